@@ -0,0 +1,319 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+// MaxMonths — наибольшая длина периода аналитики
+const MaxMonths = 120
+
+const monthLayout = "01-2006"
+
+// Filter — период аналитики и пользователь. From и To — первые дни
+// первого и последнего месяцев. Пустой UserID означает все подписки.
+type Filter struct {
+	From, To time.Time
+	UserID   *uuid.UUID
+}
+
+// Months возвращает число месяцев периода
+func (f Filter) Months() int {
+	return billing.MonthIndex(f.To) - billing.MonthIndex(f.From) + 1
+}
+
+// Выражения SQL для расчета списаний: сумма списания и длина периода
+// подписки в месяцах. Месяцы в запросах — начала месяцев как timestamp.
+const (
+	amount = "COALESCE(pc.price, subscriptions.price)"
+	cycle  = "CASE subscriptions.billing_cycle WHEN 'quarterly' THEN 3 WHEN 'yearly' THEN 12 ELSE 1 END"
+)
+
+// monthIndex возвращает выражение SQL для номера месяца, как в
+// billing.MonthIndex
+func monthIndex(expr string) string {
+	return "(EXTRACT(YEAR FROM " + expr + ") * 12 + EXTRACT(MONTH FROM " + expr + "))"
+}
+
+// shares возвращает LATERAL-подзапрос sp с долями участников совместной
+// подписки в списании price: members — сумма долей всех участников, mine —
+// доля пользователя из параметра запроса. Доли считаются как в
+// sharing.ShareOf: участники по порядку id, каждый не больше остатка цены.
+func shares(price string) string {
+	return "LEFT JOIN LATERAL (SELECT COALESCE(SUM(parts.part), 0)::bigint AS members, " +
+		"COALESCE(SUM(parts.part) FILTER (WHERE parts.user_id = ?), 0)::bigint AS mine " +
+		"FROM (SELECT raw.user_id, GREATEST(0, LEAST(raw.part, " + price + " - COALESCE(SUM(raw.part) " +
+		"OVER (ORDER BY raw.id ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING), 0))) AS part " +
+		"FROM (SELECT shares.id, shares.user_id, CASE subscriptions.split_rule " +
+		"WHEN 'equal' THEN " + price + " / (COUNT(*) OVER () + 1) " +
+		"WHEN 'percentage' THEN " + price + " * COALESCE(shares.percent, 0) / 100 " +
+		"WHEN 'fixed' THEN COALESCE(shares.amount, 0) ELSE 0 END AS part " +
+		"FROM shares WHERE shares.subscription_id = subscriptions.id) AS raw) AS parts) AS sp ON TRUE"
+}
+
+// share возвращает выражение SQL для части price, которую платит
+// пользователь f: владелец — остаток после долей участников, участник —
+// свою долю. Без пользователя — price целиком.
+func share(price string, f Filter) (string, []any) {
+	if f.UserID == nil {
+		return price, nil
+	}
+	return "CASE WHEN subscriptions.user_id = ? THEN " + price + " - sp.members ELSE sp.mine END",
+		[]any{*f.UserID}
+}
+
+// withShares добавляет к запросу подзапрос долей, если выбран пользователь
+func withShares(query *gorm.DB, price string, f Filter) *gorm.DB {
+	if f.UserID == nil {
+		return query
+	}
+	return query.Joins(shares(price), *f.UserID)
+}
+
+// charges возвращает запрос списаний подписок по месяцам периода: строка
+// на каждое списание, m.month — месяц списания, сумма — share(amount, f).
+// Считается так же, как costs.Cost: списания в месяц начала и
+// далее раз в период, кроме месяцев приостановок, по цене последнего
+// вступившего изменения; пользователю засчитывается его доля совместной
+// подписки. Подписки без end_date считаются действующими до конца периода.
+// Запрос строится от модели, поэтому ограничивается арендатором.
+func charges(tx *gorm.DB, f Filter) *gorm.DB {
+	query := tx.Model(&models.Subscription{}).
+		Joins(
+			"JOIN generate_series(?::timestamp, ?::timestamp, interval '1 month') AS m(month) "+
+				"ON m.month >= date_trunc('month', subscriptions.start_date::timestamp) "+
+				"AND m.month <= date_trunc('month', COALESCE(subscriptions.end_date, ?)::timestamp)",
+			f.From.Format(billing.DateLayout),
+			f.To.Format(billing.DateLayout),
+			f.To.Format(billing.DateLayout),
+		).
+		Joins(
+			"LEFT JOIN LATERAL (SELECT price FROM price_changes " +
+				"WHERE price_changes.subscription_id = subscriptions.id " +
+				"AND date_trunc('month', price_changes.effective_date::timestamp) <= m.month " +
+				"ORDER BY price_changes.effective_date DESC LIMIT 1) AS pc ON TRUE",
+		).
+		Where(
+			"MOD(" + monthIndex("m.month") + " - " +
+				monthIndex("subscriptions.start_date::timestamp") + ", " + cycle + ") = 0",
+		).
+		Where(
+			"NOT EXISTS (SELECT 1 FROM pauses WHERE pauses.subscription_id = subscriptions.id " +
+				"AND m.month >= date_trunc('month', pauses.start_date::timestamp) " +
+				"AND (pauses.end_date IS NULL OR m.month < date_trunc('month', pauses.end_date::timestamp)))",
+		)
+	return forUser(withShares(query, amount, f), f)
+}
+
+// forUser ограничивает запрос подписками, которыми пользователь f владеет
+// или в которых участвует, как sharing.ForUser
+func forUser(query *gorm.DB, f Filter) *gorm.DB {
+	if f.UserID != nil {
+		query = query.Where(
+			"(subscriptions.user_id = ? OR subscriptions.id IN "+
+				"(SELECT shares.subscription_id FROM shares WHERE shares.user_id = ?))",
+			*f.UserID, *f.UserID,
+		)
+	}
+	return query
+}
+
+// monthTotal — сумма за месяц из результата запроса
+type monthTotal struct {
+	Month time.Time
+	Total int
+}
+
+// Spend возвращает расходы по месяцам периода и изменение каждого месяца
+// относительно предыдущего, в том числе первого месяца периода
+func Spend(tx *gorm.DB, f Filter) (models.SpendTrend, error) {
+	withPrevious := f
+	withPrevious.From = billing.MonthStart(billing.MonthIndex(f.From) - 1)
+
+	charge, args := share(amount, f)
+	var rows []monthTotal
+	err := charges(tx, withPrevious).
+		Select("m.month AS month, SUM("+charge+") AS total", args...).
+		Group("m.month").
+		Scan(&rows).Error
+	if err != nil {
+		return models.SpendTrend{}, err
+	}
+	return trend(rows, f), nil
+}
+
+// trend строит ряд расходов периода f по суммам месяцев. Месяцы без
+// списаний дают ноль; сумма месяца перед периодом нужна для изменения
+// первого месяца.
+func trend(rows []monthTotal, f Filter) models.SpendTrend {
+	totals := make(map[int]int, len(rows))
+	for _, r := range rows {
+		totals[billing.MonthIndex(r.Month)] += r.Total
+	}
+
+	series := models.SpendTrend{
+		From:   f.From.Format(monthLayout),
+		To:     f.To.Format(monthLayout),
+		Months: make([]models.SpendMonth, 0, f.Months()),
+	}
+	first := billing.MonthIndex(f.From)
+	for i := range f.Months() {
+		total, previous := totals[first+i], totals[first+i-1]
+		month := models.SpendMonth{
+			Month:  billing.MonthStart(first + i).Format(monthLayout),
+			Total:  total,
+			Change: total - previous,
+		}
+		if previous != 0 {
+			percent := round(float64(total-previous)*100/float64(previous), 2)
+			month.ChangePercent = &percent
+		}
+		series.Total += total
+		series.Months = append(series.Months, month)
+	}
+	return series
+}
+
+// Top возвращает limit сервисов с наибольшими расходами за период
+func Top(tx *gorm.DB, f Filter, limit int) (models.TopServices, error) {
+	charge, args := share(amount, f)
+	services := []models.ServiceSpend{}
+	err := charges(tx, f).
+		Joins("LEFT JOIN services ON services.id = subscriptions.service_id").
+		Select(
+			"subscriptions.service_id, "+
+				"COALESCE(services.name, subscriptions.service_name) AS service_name, "+
+				"COUNT(DISTINCT subscriptions.id) AS subscriptions, "+
+				"SUM("+charge+") AS total",
+			args...,
+		).
+		Group("subscriptions.service_id, COALESCE(services.name, subscriptions.service_name)").
+		Order("total DESC, service_name").
+		Limit(limit).
+		Scan(&services).Error
+	return models.TopServices{
+		From:     f.From.Format(monthLayout),
+		To:       f.To.Format(monthLayout),
+		Services: services,
+	}, err
+}
+
+// Average возвращает среднюю текущую цену подписок, действовавших хотя бы
+// в одном месяце периода; для пользователя — его долю цены
+func Average(tx *gorm.DB, f Filter) (models.AveragePrice, error) {
+	const current = "subscriptions.price"
+	price, args := share(current, f)
+	var result models.AveragePrice
+	err := forUser(withShares(tx.Model(&models.Subscription{}), current, f), f).
+		Select(
+			"COUNT(*) AS subscriptions, "+
+				"COALESCE(ROUND(AVG("+price+"), 2), 0)::float8 AS average_price, "+
+				"COALESCE(ROUND(AVG(("+price+")::numeric / "+cycle+"), 2), 0)::float8 "+
+				"AS average_monthly_price",
+			append(args, args...)...,
+		).
+		Where("subscriptions.start_date < ?", nextMonth(f.To)).
+		Where("subscriptions.end_date IS NULL OR subscriptions.end_date >= ?", f.From.Format(billing.DateLayout)).
+		Scan(&result).Error
+	result.From = f.From.Format(monthLayout)
+	result.To = f.To.Format(monthLayout)
+	return result, err
+}
+
+// churnRow — число подписок за месяц из результата запроса
+type churnRow struct {
+	Month   time.Time
+	Started int
+	Ended   int
+	Active  int
+}
+
+// Churn возвращает по закончившимся месяцам периода число начавшихся и
+// закончившихся подписок и долю закончившихся среди действовавших в
+// месяце. Текущий и следующие месяцы не входят в ряд: end_date в них —
+// запланированное окончание, а не отток.
+func Churn(tx *gorm.DB, f Filter) (models.ChurnReport, error) {
+	finished := finishedMonths(f, time.Now().UTC())
+	if finished.Months() <= 0 {
+		return churnSeries(nil, f, finished.To), nil
+	}
+
+	var rows []churnRow
+	err := forUser(tx.Model(&models.Subscription{}), f).
+		Joins(
+			"JOIN generate_series(?::timestamp, ?::timestamp, interval '1 month') AS m(month) "+
+				"ON subscriptions.start_date::timestamp < m.month + interval '1 month' "+
+				"AND (subscriptions.end_date IS NULL OR subscriptions.end_date::timestamp >= m.month)",
+			finished.From.Format(billing.DateLayout),
+			finished.To.Format(billing.DateLayout),
+		).
+		Select(
+			"m.month AS month, " +
+				"COUNT(*) FILTER (WHERE date_trunc('month', subscriptions.start_date::timestamp) = m.month) AS started, " +
+				"COUNT(*) FILTER (WHERE date_trunc('month', subscriptions.end_date::timestamp) = m.month) AS ended, " +
+				"COUNT(*) AS active",
+		).
+		Group("m.month").
+		Scan(&rows).Error
+	if err != nil {
+		return models.ChurnReport{}, err
+	}
+	return churnSeries(rows, f, finished.To), nil
+}
+
+// finishedMonths возвращает часть периода f, закончившуюся к моменту now.
+// Если ни один месяц не закончился, To раньше From.
+func finishedMonths(f Filter, now time.Time) Filter {
+	if last := billing.MonthStart(billing.MonthIndex(now) - 1); f.To.After(last) {
+		f.To = last
+	}
+	return f
+}
+
+// churnSeries строит ряд оттока периода f до месяца last включительно;
+// месяцы без подписок дают нули. Закончившиеся в месяце подписки входят и
+// в действовавшие, поэтому доля оттока не больше единицы.
+func churnSeries(rows []churnRow, f Filter, last time.Time) models.ChurnReport {
+	byMonth := make(map[int]churnRow, len(rows))
+	for _, r := range rows {
+		byMonth[billing.MonthIndex(r.Month)] = r
+	}
+
+	first := billing.MonthIndex(f.From)
+	months := max(billing.MonthIndex(last)-first+1, 0)
+	report := models.ChurnReport{
+		From:   f.From.Format(monthLayout),
+		To:     f.To.Format(monthLayout),
+		Months: make([]models.ChurnMonth, 0, months),
+	}
+	for i := range months {
+		r := byMonth[first+i]
+		month := models.ChurnMonth{
+			Month:   billing.MonthStart(first + i).Format(monthLayout),
+			Started: r.Started,
+			Ended:   r.Ended,
+			Active:  r.Active,
+		}
+		if r.Active > 0 {
+			month.ChurnRate = round(float64(r.Ended)/float64(r.Active), 4)
+		}
+		report.Months = append(report.Months, month)
+	}
+	return report
+}
+
+// nextMonth возвращает первый день месяца после t в формате хранения дат
+func nextMonth(t time.Time) string {
+	return billing.MonthStart(billing.MonthIndex(t) + 1).Format(billing.DateLayout)
+}
+
+func round(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,83 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Тест для ряда расходов: пропущенные месяцы и изменение к предыдущему
+func TestTrend(t *testing.T) {
+	f := Filter{From: month(2025, time.December), To: month(2026, time.March)}
+	got := trend([]monthTotal{
+		{Month: month(2025, time.November), Total: 400},
+		{Month: month(2025, time.December), Total: 500},
+		{Month: month(2026, time.February), Total: 300},
+	}, f)
+
+	assert.Equal(t, "12-2025", got.From)
+	assert.Equal(t, 800, got.Total)
+	require.Len(t, got.Months, 4)
+
+	assert.Equal(t, "12-2025", got.Months[0].Month)
+	assert.Equal(t, 100, got.Months[0].Change)
+	require.NotNil(t, got.Months[0].ChangePercent)
+	assert.Equal(t, 25.0, *got.Months[0].ChangePercent)
+
+	assert.Equal(t, 0, got.Months[1].Total)
+	assert.Equal(t, -500, got.Months[1].Change)
+
+	// После месяца без расходов доля изменения не определена
+	assert.Equal(t, 300, got.Months[2].Change)
+	assert.Nil(t, got.Months[2].ChangePercent)
+	assert.Equal(t, "03-2026", got.Months[3].Month)
+}
+
+// Тест для ряда оттока
+func TestChurnSeries(t *testing.T) {
+	f := Filter{From: month(2026, time.January), To: month(2026, time.March)}
+	got := churnSeries([]churnRow{
+		{Month: month(2026, time.January), Started: 3, Active: 3},
+		{Month: month(2026, time.March), Started: 1, Ended: 1, Active: 3},
+	}, f, f.To)
+
+	require.Len(t, got.Months, 3)
+	assert.Equal(t, 3, got.Months[0].Started)
+	assert.Zero(t, got.Months[0].ChurnRate)
+	assert.Equal(t, "02-2026", got.Months[1].Month)
+	assert.Zero(t, got.Months[1].Active)
+	assert.Equal(t, 0.3333, got.Months[2].ChurnRate)
+}
+
+// Тест для ряда оттока только по закончившимся месяцам
+func TestChurnFinishedMonths(t *testing.T) {
+	f := Filter{From: month(2026, time.August), To: month(2026, time.December)}
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+	finished := finishedMonths(f, now)
+	assert.Equal(t, month(2026, time.September), finished.To)
+	assert.Equal(t, 2, finished.Months())
+
+	got := churnSeries([]churnRow{
+		{Month: month(2026, time.September), Started: 2, Ended: 2, Active: 2},
+	}, f, finished.To)
+	assert.Equal(t, "12-2026", got.To)
+	require.Len(t, got.Months, 2)
+	assert.Equal(t, "09-2026", got.Months[1].Month)
+	// Начавшиеся и закончившиеся в одном месяце подписки входят в Active
+	assert.Equal(t, 1.0, got.Months[1].ChurnRate)
+
+	// Период целиком в текущем месяце и позже
+	f.From = month(2026, time.October)
+	finished = finishedMonths(f, now)
+	assert.LessOrEqual(t, finished.Months(), 0)
+	got = churnSeries(nil, f, finished.To)
+	assert.NotNil(t, got.Months)
+	assert.Empty(t, got.Months)
+}
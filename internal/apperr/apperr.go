@@ -0,0 +1,193 @@
+package apperr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Kind — категория ошибки, определяющая HTTP-статус ответа
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindValidation
+	KindNotFound
+	KindConflict
+	KindUnavailable
+	KindRateLimited
+	KindUnauthorized
+)
+
+// Стабильные коды ошибок, на которые могут опираться клиенты
+const (
+	CodeInternal                = "internal_error"
+	CodeDatabaseUnavailable     = "database_unavailable"
+	CodeInvalidRequestBody      = "invalid_request_body"
+	CodeInvalidID               = "invalid_id"
+	CodeInvalidUserID           = "invalid_user_id"
+	CodeInvalidStartDate        = "invalid_start_date"
+	CodeInvalidEndDate          = "invalid_end_date"
+	CodeInvalidBillingCycle     = "invalid_billing_cycle"
+	CodeInvalidReminderSettings = "invalid_reminder_settings"
+	CodeInvalidStatus           = "invalid_status"
+	CodeInvalidStatusTransition = "invalid_status_transition"
+	CodeInvalidPause            = "invalid_pause"
+	CodeInvalidCancellation     = "invalid_cancellation"
+	CodeInvalidService          = "invalid_service"
+	CodeInvalidCategory         = "invalid_category"
+	CodeInvalidTags             = "invalid_tags"
+	CodeInvalidGroupBy          = "invalid_group_by"
+	CodeInvalidBudget           = "invalid_budget"
+	CodeInvalidPriceChange      = "invalid_price_change"
+	CodeInvalidForecast         = "invalid_forecast"
+	CodeInvalidShare            = "invalid_share"
+	CodeInvalidOrganization     = "invalid_organization"
+	CodeInvalidAnalytics        = "invalid_analytics"
+	CodeRouteNotFound           = "route_not_found"
+	CodeMethodNotAllowed        = "method_not_allowed"
+	CodeConflict                = "conflict"
+	CodeRateLimited             = "rate_limited"
+	CodeTenantRequired          = "tenant_required"
+	CodeInvalidToken            = "invalid_token"
+	CodeReferenceNotFound       = "reference_not_found"
+	CodeSubscriptionNotFound    = "subscription_not_found"
+	CodeServiceNotFound         = "service_not_found"
+	CodeCategoryNotFound        = "category_not_found"
+	CodeBudgetNotFound          = "budget_not_found"
+	CodePriceChangeNotFound     = "price_change_not_found"
+	CodeOrganizationNotFound    = "organization_not_found"
+	CodeMemberNotFound          = "member_not_found"
+	CodeInvalidWebhook          = "invalid_webhook"
+	CodeWebhookNotFound         = "webhook_not_found"
+	CodeWebhookDeliveryNotFound = "webhook_delivery_not_found"
+)
+
+// Error — доменная ошибка. Message безопасно показывать клиенту, Err —
+// исходная причина, которая попадает только в логи.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func Validation(code, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func Unavailable(code, message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: err}
+}
+
+func Internal(err error) *Error {
+	return &Error{
+		Kind:    KindInternal,
+		Code:    CodeInternal,
+		Message: "internal server error",
+		Err:     err,
+	}
+}
+
+// As возвращает доменную ошибку из цепочки err. Ошибки, не являющиеся
+// доменными, считаются внутренними.
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// FromDB переводит ошибку GORM в доменную. notFound используется, когда
+// запись не найдена; nil означает, что отсутствие записи — внутренняя
+// ошибка. Доменные ошибки, возвращенные из транзакции, не меняются.
+func FromDB(err error, notFound *Error) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound) && notFound != nil:
+		return notFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &Error{
+			Kind:    KindConflict,
+			Code:    CodeConflict,
+			Message: "resource already exists",
+			Err:     err,
+		}
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return &Error{
+			Kind:    KindValidation,
+			Code:    CodeReferenceNotFound,
+			Message: "referenced resource does not exist",
+			Err:     err,
+		}
+	case isUnavailable(err):
+		return Unavailable(
+			CodeDatabaseUnavailable,
+			"database is temporarily unavailable",
+			err,
+		)
+	}
+	return Internal(err)
+}
+
+// isUnavailable определяет ошибки, после которых запрос имеет смысл
+// повторить: нет соединения, таймаут, перегрузка или остановка сервера
+func isUnavailable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08 — ошибки соединения, 53 — нехватка ресурсов, 57P — остановка
+		// сервера
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			strings.HasPrefix(pgErr.Code, "53") ||
+			strings.HasPrefix(pgErr.Code, "57P")
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,57 @@
+package apperr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+)
+
+// Тест для перевода ошибок GORM в доменные
+func TestFromDB(t *testing.T) {
+	notFound := apperr.NotFound(apperr.CodeSubscriptionNotFound, "subscription not found")
+
+	tests := []struct {
+		name string
+		err  error
+		kind apperr.Kind
+		code string
+	}{
+		{"not found", gorm.ErrRecordNotFound, apperr.KindNotFound, apperr.CodeSubscriptionNotFound},
+		{"duplicate", gorm.ErrDuplicatedKey, apperr.KindConflict, apperr.CodeConflict},
+		{"foreign key", gorm.ErrForeignKeyViolated, apperr.KindValidation, apperr.CodeReferenceNotFound},
+		{"timeout", fmt.Errorf("query: %w", context.DeadlineExceeded), apperr.KindUnavailable, apperr.CodeDatabaseUnavailable},
+		{"connection lost", &pgconn.PgError{Code: "08006"}, apperr.KindUnavailable, apperr.CodeDatabaseUnavailable},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, apperr.KindUnavailable, apperr.CodeDatabaseUnavailable},
+		{"syntax error", &pgconn.PgError{Code: "42601"}, apperr.KindInternal, apperr.CodeInternal},
+		{"unknown", errors.New("boom"), apperr.KindInternal, apperr.CodeInternal},
+		{"domain", apperr.Conflict(apperr.CodeInvalidStatusTransition, "not allowed"), apperr.KindConflict, apperr.CodeInvalidStatusTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := apperr.As(apperr.FromDB(tt.err, notFound))
+			assert.Equal(t, tt.kind, got.Kind)
+			assert.Equal(t, tt.code, got.Code)
+		})
+	}
+}
+
+// Тест для отсутствующей записи без ошибки «не найдено»
+func TestFromDBNotFoundWithoutMapping(t *testing.T) {
+	got := apperr.As(apperr.FromDB(gorm.ErrRecordNotFound, nil))
+	assert.Equal(t, apperr.KindInternal, got.Kind)
+}
+
+// Тест для скрытия внутренних причин в сообщении
+func TestInternalHidesCause(t *testing.T) {
+	got := apperr.As(errors.New("pq: password authentication failed"))
+	assert.Equal(t, "internal server error", got.Message)
+	assert.ErrorContains(t, got, "password authentication failed")
+}
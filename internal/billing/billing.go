@@ -0,0 +1,149 @@
+package billing
+
+import (
+	"fmt"
+	"time"
+)
+
+// Cycle — периодичность списаний по подписке. Цена подписки — сумма
+// одного списания.
+type Cycle string
+
+const (
+	Monthly   Cycle = "monthly"
+	Quarterly Cycle = "quarterly"
+	Yearly    Cycle = "yearly"
+)
+
+// DateLayout — формат хранения дат подписок в базе данных
+const DateLayout = "2006-01-02"
+
+// ParseCycle проверяет периодичность. Пустая строка означает Monthly.
+func ParseCycle(s string) (Cycle, error) {
+	switch Cycle(s) {
+	case "":
+		return Monthly, nil
+	case Monthly, Quarterly, Yearly:
+		return Cycle(s), nil
+	}
+	return "", fmt.Errorf("unknown billing cycle %q", s)
+}
+
+// Months возвращает длину периода в месяцах
+func (c Cycle) Months() int {
+	switch c {
+	case Quarterly:
+		return 3
+	case Yearly:
+		return 12
+	default:
+		return 1
+	}
+}
+
+// MonthIndex возвращает порядковый номер месяца даты, удобный для
+// арифметики по месяцам
+func MonthIndex(t time.Time) int {
+	return t.Year()*12 + int(t.Month()) - 1
+}
+
+// MonthStart возвращает первый день месяца с указанным номером
+func MonthStart(index int) time.Time {
+	return time.Date(index/12, time.Month(index%12+1), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ChargesBetween считает списания подписки, начавшейся в месяце start, в
+// месяцах с from по to включительно. Списания происходят в месяц начала и
+// далее каждые cycle.Months() месяцев.
+func ChargesBetween(start time.Time, cycle Cycle, from, to time.Time) int {
+	s := MonthIndex(start)
+	a := max(MonthIndex(from), s)
+	b := MonthIndex(to)
+	if b < a {
+		return 0
+	}
+
+	n := cycle.Months()
+	first := s + ceilDiv(a-s, n)*n
+	if first > b {
+		return 0
+	}
+	return (b-first)/n + 1
+}
+
+// Interval — интервал месяцев с From по месяц перед To. Нулевой To
+// означает интервал без конца.
+type Interval struct {
+	From, To time.Time
+}
+
+// ChargesExcluding считает списания как ChargesBetween, но без списаний,
+// приходящихся на месяцы интервалов skip. Интервалы не должны
+// пересекаться.
+func ChargesExcluding(start time.Time, cycle Cycle, from, to time.Time, skip []Interval) int {
+	n := ChargesBetween(start, cycle, from, to)
+	for _, iv := range skip {
+		a, b := from, to
+		if iv.From.After(a) {
+			a = iv.From
+		}
+		if !iv.To.IsZero() {
+			if last := MonthStart(MonthIndex(iv.To) - 1); last.Before(b) {
+				b = last
+			}
+		}
+		n -= ChargesBetween(start, cycle, a, b)
+	}
+	return n
+}
+
+// PeriodEnd возвращает последний месяц оплаченного периода, в который
+// попадает at. До начала подписки это последний месяц первого периода.
+func PeriodEnd(start time.Time, cycle Cycle, at time.Time) time.Time {
+	s := MonthIndex(start)
+	n := cycle.Months()
+	k := max(MonthIndex(at)-s, 0) / n
+	return MonthStart(s + (k+1)*n - 1)
+}
+
+// NextChargeDate возвращает первую дату списания не раньше from.
+// Списания происходят в день месяца, с которого началась подписка; если в
+// месяце нет такого дня, списание переносится на последний день месяца.
+func NextChargeDate(start time.Time, cycle Cycle, from time.Time) time.Time {
+	from = truncateDay(from)
+	start = truncateDay(start)
+	if !start.Before(from) {
+		return start
+	}
+
+	n := cycle.Months()
+	k := ceilDiv(MonthIndex(from)-MonthIndex(start), n)
+	for {
+		charge := addMonths(start, k*n)
+		if !charge.Before(from) {
+			return charge
+		}
+		k++
+	}
+}
+
+// addMonths прибавляет месяцы, не перескакивая в следующий месяц для дат
+// в конце месяца (31 января + 1 месяц = 28/29 февраля)
+func addMonths(t time.Time, months int) time.Time {
+	idx := MonthIndex(t) + months
+	first := MonthStart(idx)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := min(t.Day(), lastDay)
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func ceilDiv(a, b int) int {
+	if a <= 0 {
+		return 0
+	}
+	return (a + b - 1) / b
+}
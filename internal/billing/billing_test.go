@@ -0,0 +1,134 @@
+package billing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Тест для подсчета списаний за период
+func TestChargesBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    time.Time
+		cycle    billing.Cycle
+		from, to time.Time
+		want     int
+	}{
+		{"monthly full overlap", date(2025, 6, 1), billing.Monthly, date(2025, 6, 1), date(2025, 7, 31), 2},
+		{"monthly starts inside period", date(2025, 7, 1), billing.Monthly, date(2025, 6, 1), date(2025, 7, 31), 1},
+		{"monthly starts after period", date(2025, 8, 1), billing.Monthly, date(2025, 6, 1), date(2025, 7, 31), 0},
+		{"quarterly", date(2025, 1, 1), billing.Quarterly, date(2025, 1, 1), date(2025, 12, 1), 4},
+		{"quarterly period between charges", date(2025, 1, 1), billing.Quarterly, date(2025, 2, 1), date(2025, 3, 1), 0},
+		{"yearly", date(2024, 3, 1), billing.Yearly, date(2024, 1, 1), date(2026, 2, 1), 2},
+		{"yearly includes anniversary", date(2024, 3, 1), billing.Yearly, date(2025, 3, 1), date(2025, 3, 1), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := billing.ChargesBetween(tt.start, tt.cycle, tt.from, tt.to)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Тест для подсчета списаний без месяцев приостановки
+func TestChargesExcluding(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    time.Time
+		cycle    billing.Cycle
+		from, to time.Time
+		skip     []billing.Interval
+		want     int
+	}{
+		{"no pauses", date(2025, 1, 1), billing.Monthly, date(2025, 1, 1), date(2025, 12, 1), nil, 12},
+		{"closed pause", date(2025, 1, 1), billing.Monthly, date(2025, 1, 1), date(2025, 12, 1),
+			[]billing.Interval{{From: date(2025, 3, 1), To: date(2025, 6, 1)}}, 9},
+		{"open pause", date(2025, 1, 1), billing.Monthly, date(2025, 1, 1), date(2025, 12, 1),
+			[]billing.Interval{{From: date(2025, 10, 1)}}, 9},
+		{"pause outside period", date(2025, 1, 1), billing.Monthly, date(2025, 6, 1), date(2025, 12, 1),
+			[]billing.Interval{{From: date(2025, 2, 1), To: date(2025, 4, 1)}}, 7},
+		{"pause crosses period start", date(2025, 1, 1), billing.Monthly, date(2025, 6, 1), date(2025, 12, 1),
+			[]billing.Interval{{From: date(2025, 4, 1), To: date(2025, 8, 1)}}, 5},
+		{"empty pause", date(2025, 1, 1), billing.Monthly, date(2025, 1, 1), date(2025, 12, 1),
+			[]billing.Interval{{From: date(2025, 5, 1), To: date(2025, 5, 1)}}, 12},
+		{"quarterly pause skips charge", date(2025, 1, 1), billing.Quarterly, date(2025, 1, 1), date(2025, 12, 1),
+			[]billing.Interval{{From: date(2025, 4, 1), To: date(2025, 5, 1)}}, 3},
+		{"quarterly pause between charges", date(2025, 1, 1), billing.Quarterly, date(2025, 1, 1), date(2025, 12, 1),
+			[]billing.Interval{{From: date(2025, 5, 1), To: date(2025, 7, 1)}}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := billing.ChargesExcluding(tt.start, tt.cycle, tt.from, tt.to, tt.skip)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Тест для конца оплаченного периода
+func TestPeriodEnd(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		cycle billing.Cycle
+		at    time.Time
+		want  time.Time
+	}{
+		{"monthly", date(2025, 1, 15), billing.Monthly, date(2025, 7, 20), date(2025, 7, 1)},
+		{"quarterly first month", date(2025, 1, 1), billing.Quarterly, date(2025, 4, 1), date(2025, 6, 1)},
+		{"quarterly last month", date(2025, 1, 1), billing.Quarterly, date(2025, 6, 30), date(2025, 6, 1)},
+		{"yearly", date(2024, 3, 1), billing.Yearly, date(2025, 2, 10), date(2025, 2, 1)},
+		{"not started yet", date(2025, 9, 1), billing.Quarterly, date(2025, 7, 1), date(2025, 11, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := billing.PeriodEnd(tt.start, tt.cycle, tt.at)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Тест для вычисления следующей даты списания
+func TestNextChargeDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		cycle billing.Cycle
+		from  time.Time
+		want  time.Time
+	}{
+		{"not started yet", date(2025, 9, 1), billing.Monthly, date(2025, 7, 15), date(2025, 9, 1)},
+		{"charge today", date(2025, 1, 1), billing.Monthly, date(2025, 7, 1), date(2025, 7, 1)},
+		{"next month", date(2025, 1, 1), billing.Monthly, date(2025, 7, 2), date(2025, 8, 1)},
+		{"quarterly", date(2025, 1, 1), billing.Quarterly, date(2025, 5, 10), date(2025, 7, 1)},
+		{"yearly", date(2024, 3, 1), billing.Yearly, date(2025, 3, 2), date(2026, 3, 1)},
+		{"end of month clamped", date(2025, 1, 31), billing.Monthly, date(2025, 2, 1), date(2025, 2, 28)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := billing.NextChargeDate(tt.start, tt.cycle, tt.from)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Тест для разбора периодичности
+func TestParseCycle(t *testing.T) {
+	c, err := billing.ParseCycle("")
+	assert.NoError(t, err)
+	assert.Equal(t, billing.Monthly, c)
+
+	_, err = billing.ParseCycle("weekly")
+	assert.Error(t, err)
+}
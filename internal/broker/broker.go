@@ -0,0 +1,19 @@
+package broker
+
+import "context"
+
+// Message — сообщение для брокера. Key определяет раздел (в Kafka) и
+// порядок: сообщения с одним ключом доставляются в порядке публикации.
+type Message struct {
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
+
+// Publisher публикует сообщения в брокер. Publish возвращает nil, только
+// если брокер подтвердил прием всех сообщений; сообщения публикуются в
+// переданном порядке.
+type Publisher interface {
+	Publish(ctx context.Context, msgs ...Message) error
+	Close() error
+}
@@ -0,0 +1,42 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher публикует сообщения в топик Kafka. Раздел выбирается по
+// хешу ключа, поэтому сообщения одного ключа сохраняют порядок.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// Повторы выполняет вызывающий код целой пачкой, чтобы не
+			// переставить сообщения одного ключа
+			MaxAttempts: 1,
+		},
+	}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, msgs ...Message) error {
+	out := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		out[i] = kafka.Message{Key: []byte(m.Key), Value: m.Value}
+		for k, v := range m.Headers {
+			out[i].Headers = append(out[i].Headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+	}
+	return p.writer.WriteMessages(ctx, out...)
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,52 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker хранит опубликованные сообщения в памяти процесса.
+// Используется в тестах и при локальной разработке.
+type MemoryBroker struct {
+	mu       sync.Mutex
+	messages []Message
+	failNext error
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msgs ...Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.failNext; err != nil {
+		b.failNext = nil
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.messages = append(b.messages, msgs...)
+	return nil
+}
+
+// FailNext заставляет следующий вызов Publish вернуть err, ничего не
+// опубликовав
+func (b *MemoryBroker) FailNext(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failNext = err
+}
+
+// Messages возвращает все опубликованные сообщения
+func (b *MemoryBroker) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// HeaderMessageID — заголовок с идентификатором сообщения. NATS JetStream
+// отбрасывает повторы с тем же идентификатором в окне дедупликации потока.
+const HeaderMessageID = "Message-Id"
+
+// NATSPublisher публикует сообщения в поток NATS JetStream. Поток,
+// принимающий subject, должен быть создан заранее. Сообщения публикуются
+// по одному с ожиданием подтверждения, что сохраняет их порядок.
+type NATSPublisher struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	subject string
+}
+
+func NewNATSPublisher(url, subject string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("subscription-service"))
+	if err != nil {
+		return nil, fmt.Errorf("connect to nats: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &NATSPublisher{conn: conn, js: js, subject: subject}, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, msgs ...Message) error {
+	for _, m := range msgs {
+		msg := nats.NewMsg(p.subject)
+		msg.Data = m.Value
+		for k, v := range m.Headers {
+			msg.Header.Set(k, v)
+		}
+		var opts []jetstream.PublishOpt
+		if id := m.Headers[HeaderMessageID]; id != "" {
+			opts = append(opts, jetstream.WithMsgID(id))
+		}
+		if _, err := p.js.PublishMsg(ctx, msg, opts...); err != nil {
+			return fmt.Errorf("publish to nats: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,175 @@
+package budgets
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/costs"
+	"github.com/nemopss/subscription-service/internal/events"
+	"github.com/nemopss/subscription-service/internal/metrics"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/sharing"
+	"github.com/nemopss/subscription-service/internal/taxonomy"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// Области действия бюджета
+const (
+	ScopeOverall  = "overall"
+	ScopeCategory = "category"
+	ScopeService  = "service"
+)
+
+// Validate проверяет бюджет: категория задается только для области
+// category, сервис — только для service, сумма положительна
+func Validate(b models.CreateBudget) error {
+	if b.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	switch b.Scope {
+	case ScopeOverall:
+		if b.CategoryID != nil || b.ServiceID != nil {
+			return fmt.Errorf("an overall budget cannot have category_id or service_id")
+		}
+	case ScopeCategory:
+		if b.CategoryID == nil || b.ServiceID != nil {
+			return fmt.Errorf("a category budget requires category_id only")
+		}
+	case ScopeService:
+		if b.ServiceID == nil || b.CategoryID != nil {
+			return fmt.Errorf("a service budget requires service_id only")
+		}
+	default:
+		return fmt.Errorf("unknown scope %q, expected overall, category or service", b.Scope)
+	}
+	return nil
+}
+
+// Matches сообщает, учитывается ли подписка в бюджете. Бюджет категории
+// включает ее подкатегории.
+func Matches(b models.Budget, sub models.Subscription, tree *taxonomy.Tree) bool {
+	switch b.Scope {
+	case ScopeOverall:
+		return true
+	case ScopeCategory:
+		if sub.CategoryID == nil || b.CategoryID == nil || tree == nil {
+			return false
+		}
+		for _, id := range tree.Subtree(*b.CategoryID) {
+			if id == *sub.CategoryID {
+				return true
+			}
+		}
+	case ScopeService:
+		return sub.ServiceID != nil && b.ServiceID != nil && *sub.ServiceID == *b.ServiceID
+	}
+	return false
+}
+
+// Evaluate считает расходы пользователя по бюджетам за месяц, в который
+// попадает month; в совместных подписках учитывается его доля. Подписки
+// без end_date считаются действующими до конца месяца, поэтому для
+// текущего месяца это прогноз расходов.
+func Evaluate(
+	budgets []models.Budget,
+	userID uuid.UUID,
+	subs []models.Subscription,
+	tree *taxonomy.Tree,
+	month time.Time,
+) []models.BudgetStatus {
+	from := billing.MonthStart(billing.MonthIndex(month))
+	to := costs.MonthEnd(month)
+
+	spent := make(map[int]int, len(subs))
+	for _, sub := range subs {
+		cost, err := costs.UserCost(sub, userID, from, to, to)
+		if err != nil {
+			logger.Log.WithError(err).
+				WithField("id", sub.ID).
+				Error("Invalid subscription dates")
+			continue
+		}
+		spent[sub.ID] = cost
+	}
+
+	out := make([]models.BudgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		status := models.BudgetStatus{Budget: b}
+		for _, sub := range subs {
+			if Matches(b, sub, tree) {
+				status.Spent += spent[sub.ID]
+			}
+		}
+		status.Remaining = b.Amount - status.Spent
+		status.Over = status.Spent > b.Amount
+		out = append(out, status)
+	}
+	return out
+}
+
+// Report считает расходы пользователя по всем его бюджетам за месяц
+func Report(tx *gorm.DB, userID uuid.UUID, month time.Time) ([]models.BudgetStatus, error) {
+	var budgets []models.Budget
+	if err := tx.Where("user_id = ?", userID).Order("id").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return []models.BudgetStatus{}, nil
+	}
+
+	var subs []models.Subscription
+	err := sharing.ForUser(costs.Preload(tx), userID).Find(&subs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []models.Category
+	if err := tx.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return Evaluate(budgets, userID, subs, taxonomy.NewTree(categories), month), nil
+}
+
+// Check проверяет бюджеты пользователя за текущий месяц после изменения
+// его подписок. О каждом превышенном бюджете оповещает один раз в месяц:
+// записывает превышение и публикует событие budget.exceeded в той же
+// транзакции. Возвращает новые превышения.
+func Check(tx *gorm.DB, userID uuid.UUID, now time.Time) ([]models.BudgetAlert, error) {
+	statuses, err := Report(tx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	month := billing.MonthStart(billing.MonthIndex(now)).Format(billing.DateLayout)
+	var alerts []models.BudgetAlert
+	for _, s := range statuses {
+		if !s.Over {
+			continue
+		}
+		alert := models.BudgetAlert{
+			BudgetID: s.Budget.ID,
+			UserID:   userID,
+			Month:    month,
+			Amount:   s.Budget.Amount,
+			Spent:    s.Spent,
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&alert)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		if err := events.BudgetExceeded(tx, alert); err != nil {
+			return nil, err
+		}
+		metrics.BudgetAlerts.WithLabelValues(s.Budget.Scope).Inc()
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
@@ -0,0 +1,64 @@
+package budgets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/taxonomy"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// Тест для проверки бюджета
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(models.CreateBudget{Scope: ScopeOverall, Amount: 1000}))
+	assert.NoError(t, Validate(models.CreateBudget{Scope: ScopeCategory, CategoryID: intPtr(1), Amount: 1000}))
+	assert.NoError(t, Validate(models.CreateBudget{Scope: ScopeService, ServiceID: intPtr(1), Amount: 1000}))
+
+	assert.Error(t, Validate(models.CreateBudget{Scope: ScopeOverall, Amount: 0}))
+	assert.Error(t, Validate(models.CreateBudget{Scope: ScopeOverall, CategoryID: intPtr(1), Amount: 1000}))
+	assert.Error(t, Validate(models.CreateBudget{Scope: ScopeCategory, Amount: 1000}))
+	assert.Error(t, Validate(models.CreateBudget{Scope: ScopeService, CategoryID: intPtr(1), Amount: 1000}))
+	assert.Error(t, Validate(models.CreateBudget{Scope: "weekly", Amount: 1000}))
+}
+
+// Тест для расчета расходов по бюджетам за месяц
+func TestEvaluate(t *testing.T) {
+	tree := taxonomy.NewTree([]models.Category{
+		{ID: 1, Name: "Entertainment"},
+		{ID: 2, Name: "Streaming", ParentID: intPtr(1)},
+		{ID: 3, Name: "Work"},
+	})
+	userID := uuid.New()
+	subs := []models.Subscription{
+		{ID: 1, UserID: userID, Price: 600, StartDate: "2026-01-01", BillingCycle: billing.Monthly, CategoryID: intPtr(2), ServiceID: intPtr(10)},
+		{ID: 2, UserID: userID, Price: 400, StartDate: "2026-01-01", BillingCycle: billing.Monthly, CategoryID: intPtr(3)},
+		// Годовая подписка, списание не в октябре
+		{ID: 3, UserID: userID, Price: 3000, StartDate: "2026-03-01", BillingCycle: billing.Yearly, CategoryID: intPtr(1)},
+	}
+	budgets := []models.Budget{
+		{ID: 1, Scope: ScopeOverall, Amount: 900},
+		{ID: 2, Scope: ScopeCategory, CategoryID: intPtr(1), Amount: 1000},
+		{ID: 3, Scope: ScopeService, ServiceID: intPtr(10), Amount: 500},
+	}
+
+	got := Evaluate(budgets, userID, subs, tree, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	if assert.Len(t, got, 3) {
+		assert.Equal(t, 1000, got[0].Spent)
+		assert.True(t, got[0].Over)
+		assert.Equal(t, -100, got[0].Remaining)
+
+		assert.Equal(t, 600, got[1].Spent)
+		assert.False(t, got[1].Over)
+
+		assert.Equal(t, 600, got[2].Spent)
+		assert.True(t, got[2].Over)
+	}
+}
@@ -0,0 +1,193 @@
+package catalog
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+// normalizedNameSQL — SQL-выражение, совпадающее с Normalize для
+// service_name
+const normalizedNameSQL = `lower(regexp_replace(btrim(service_name), '\s+', ' ', 'g'))`
+
+// Normalize приводит название сервиса к виду для сравнения: нижний
+// регистр, без пробелов по краям и с одиночными пробелами между словами
+func Normalize(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Names возвращает нормализованные название и псевдонимы сервиса без
+// повторов
+func Names(s models.Service) []string {
+	names := []string{Normalize(s.Name)}
+	for _, alias := range s.Aliases {
+		if n := Normalize(alias); n != "" && !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// Match ищет среди services сервис, название или псевдоним которого
+// совпадает с name
+func Match(services []models.Service, name string) (models.Service, bool) {
+	n := Normalize(name)
+	for _, s := range services {
+		if slices.Contains(Names(s), n) {
+			return s, true
+		}
+	}
+	return models.Service{}, false
+}
+
+// Conflict возвращает другой сервис каталога, с названиями которого
+// пересекаются названия s
+func Conflict(services []models.Service, s models.Service) (models.Service, bool) {
+	names := Names(s)
+	for _, other := range services {
+		if other.ID == s.ID {
+			continue
+		}
+		for _, n := range Names(other) {
+			if slices.Contains(names, n) {
+				return other, true
+			}
+		}
+	}
+	return models.Service{}, false
+}
+
+// Find ищет сервис каталога по названию. Каталог небольшой, поэтому
+// сопоставление выполняется в памяти. Возвращает nil, если сервис не
+// найден.
+func Find(tx *gorm.DB, name string) (*models.Service, error) {
+	var services []models.Service
+	if err := tx.Find(&services).Error; err != nil {
+		return nil, err
+	}
+	if s, ok := Match(services, name); ok {
+		return &s, nil
+	}
+	return nil, nil
+}
+
+// Validate проверяет сервис: название, адрес логотипа и цены
+func Validate(s models.CreateService) error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if s.LogoURL != "" {
+		u, err := url.Parse(s.LogoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("logo_url must be an absolute http(s) URL")
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, p := range s.Prices {
+		if len(p.Currency) != 3 || strings.ToUpper(p.Currency) != p.Currency {
+			return fmt.Errorf("currency %q must be a three-letter ISO 4217 code", p.Currency)
+		}
+		if _, err := billing.ParseCycle(string(p.BillingCycle)); err != nil {
+			return err
+		}
+		if p.Amount < 0 {
+			return fmt.Errorf("price amount must not be negative")
+		}
+		key := p.Currency + "/" + string(p.BillingCycle)
+		if seen[key] {
+			return fmt.Errorf("duplicate price for %s", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Link связывает с сервисом подписки без сервиса, названия которых
+// совпадают с его названием или псевдонимами, и возвращает их число
+func Link(tx *gorm.DB, s models.Service) (int64, error) {
+	result := tx.Model(&models.Subscription{}).
+		Where("service_id IS NULL AND "+normalizedNameSQL+" IN ?", Names(s)).
+		Update("service_id", s.ID)
+	return result.RowsAffected, result.Error
+}
+
+// Seed заполняет каталог по названиям существующих подписок: названия,
+// совпадающие после нормализации, становятся одним сервисом, самое
+// частое написание — его названием, остальные — псевдонимами. Затем
+// подписки связываются с сервисами.
+func Seed(tx *gorm.DB) error {
+	var rows []struct {
+		ServiceName string
+		Count       int
+	}
+	err := tx.Model(&models.Subscription{}).
+		Select("service_name, count(*) AS count").
+		Where("service_id IS NULL").
+		Group("service_name").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, r := range rows {
+		counts[r.ServiceName] = r.Count
+	}
+
+	var existing []models.Service
+	if err := tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, s := range group(counts) {
+		if other, ok := Conflict(existing, s); ok {
+			s = other
+		} else if err := tx.Create(&s).Error; err != nil {
+			return err
+		}
+		if _, err := Link(tx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// group объединяет названия, совпадающие после нормализации
+func group(counts map[string]int) []models.Service {
+	byKey := map[string][]string{}
+	for name := range counts {
+		if key := Normalize(name); key != "" {
+			byKey[key] = append(byKey[key], name)
+		}
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	services := make([]models.Service, 0, len(keys))
+	for _, key := range keys {
+		names := byKey[key]
+		sort.Slice(names, func(i, j int) bool {
+			if counts[names[i]] != counts[names[j]] {
+				return counts[names[i]] > counts[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		services = append(services, models.Service{
+			Name:    strings.Join(strings.Fields(names[0]), " "),
+			Aliases: append([]string{}, names[1:]...),
+			Prices:  []models.ServicePrice{},
+		})
+	}
+	return services
+}
@@ -0,0 +1,90 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+// Тест для сопоставления названий с каталогом
+func TestMatch(t *testing.T) {
+	services := []models.Service{
+		{ID: 1, Name: "Yandex Plus", Aliases: []string{"Яндекс Плюс"}},
+		{ID: 2, Name: "Netflix"},
+	}
+
+	for _, name := range []string{"Yandex Plus", "yandex plus", "  Yandex   PLUS ", "яндекс плюс"} {
+		s, ok := Match(services, name)
+		assert.True(t, ok, name)
+		assert.Equal(t, 1, s.ID, name)
+	}
+
+	_, ok := Match(services, "Spotify")
+	assert.False(t, ok)
+}
+
+// Тест для поиска пересекающихся названий
+func TestConflict(t *testing.T) {
+	services := []models.Service{
+		{ID: 1, Name: "Yandex Plus", Aliases: []string{"Яндекс Плюс"}},
+	}
+
+	other, ok := Conflict(services, models.Service{Name: "Kinopoisk", Aliases: []string{"яндекс  плюс"}})
+	assert.True(t, ok)
+	assert.Equal(t, 1, other.ID)
+
+	// Сервис не конфликтует сам с собой
+	_, ok = Conflict(services, models.Service{ID: 1, Name: "Yandex Plus"})
+	assert.False(t, ok)
+}
+
+// Тест для проверки сервиса
+func TestValidate(t *testing.T) {
+	valid := models.CreateService{
+		Name:    "Yandex Plus",
+		LogoURL: "https://example.com/logo.png",
+		Prices: []models.ServicePrice{
+			{Currency: "RUB", BillingCycle: billing.Monthly, Amount: 400},
+			{Currency: "RUB", BillingCycle: billing.Yearly, Amount: 3990},
+		},
+	}
+	assert.NoError(t, Validate(valid))
+
+	tests := map[string]func(s *models.CreateService){
+		"empty name":      func(s *models.CreateService) { s.Name = " " },
+		"relative logo":   func(s *models.CreateService) { s.LogoURL = "/logo.png" },
+		"bad currency":    func(s *models.CreateService) { s.Prices[0].Currency = "rub" },
+		"bad cycle":       func(s *models.CreateService) { s.Prices[0].BillingCycle = "weekly" },
+		"negative amount": func(s *models.CreateService) { s.Prices[0].Amount = -1 },
+		"duplicate price": func(s *models.CreateService) { s.Prices[1].BillingCycle = billing.Monthly },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := valid
+			s.Prices = append([]models.ServicePrice{}, valid.Prices...)
+			mutate(&s)
+			assert.Error(t, Validate(s))
+		})
+	}
+}
+
+// Тест для объединения написаний одного сервиса при заполнении каталога
+func TestGroup(t *testing.T) {
+	got := group(map[string]int{
+		"Yandex Plus": 5,
+		"yandex plus": 2,
+		"YANDEX PLUS": 2,
+		"Netflix":     1,
+		"  ":          1,
+	})
+
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "Netflix", got[0].Name)
+		assert.Empty(t, got[0].Aliases)
+		assert.Equal(t, "Yandex Plus", got[1].Name)
+		assert.Equal(t, []string{"YANDEX PLUS", "yandex plus"}, got[1].Aliases)
+	}
+}
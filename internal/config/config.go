@@ -1,14 +1,385 @@
 package config
 
 import (
-	"github.com/joho/godotenv"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
 
-	"github.com/nemopss/subscription-service/pkg/logger"
+	"github.com/joho/godotenv"
 )
 
-func LoadConfig(path string) {
-	err := godotenv.Load(path)
-	if err != nil {
-		logger.Log.Warn("Error loading .env file")
+// Config — полная конфигурация сервиса.
+//
+// Значения применяются в порядке возрастания приоритета: значения по
+// умолчанию, файл конфигурации (YAML или TOML), переменные окружения
+// (включая .env), флаги командной строки.
+type Config struct {
+	Server        ServerConfig        `config:"server"`
+	Database      DatabaseConfig      `config:"database"`
+	Log           LogConfig           `config:"log"`
+	Health        HealthConfig        `config:"health"`
+	Metrics       MetricsConfig       `config:"metrics"`
+	Tracing       TracingConfig       `config:"tracing"`
+	RateLimit     RateLimitConfig     `config:"rate_limit"`
+	Reminders     RemindersConfig     `config:"reminders"`
+	Notifications NotificationsConfig `config:"notifications"`
+	Webhooks      WebhooksConfig      `config:"webhooks"`
+	Expiry        ExpiryConfig        `config:"expiry"`
+	Outbox        OutboxConfig        `config:"outbox"`
+	Tenancy       TenancyConfig       `config:"tenancy"`
+	Features      FeaturesConfig      `config:"features"`
+}
+
+// ServerConfig содержит параметры HTTP-сервера
+type ServerConfig struct {
+	Addr              string        `config:"addr"                env:"HTTP_ADDR"                flag:"http.addr"             usage:"HTTP listen address"`
+	ReadTimeout       time.Duration `config:"read_timeout"        env:"HTTP_READ_TIMEOUT"        flag:"http.read-timeout"     usage:"maximum duration for reading the entire request"`
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" flag:"http.read-header-timeout" usage:"maximum duration for reading request headers"`
+	WriteTimeout      time.Duration `config:"write_timeout"       env:"HTTP_WRITE_TIMEOUT"       flag:"http.write-timeout"    usage:"maximum duration before timing out writes of the response"`
+	IdleTimeout       time.Duration `config:"idle_timeout"        env:"HTTP_IDLE_TIMEOUT"        flag:"http.idle-timeout"     usage:"maximum time to wait for the next request on keep-alive connections"`
+	MaxHeaderBytes    int           `config:"max_header_bytes"    env:"HTTP_MAX_HEADER_BYTES"    flag:"http.max-header-bytes" usage:"maximum size of request headers in bytes"`
+	ShutdownTimeout   time.Duration `config:"shutdown_timeout"    env:"HTTP_SHUTDOWN_TIMEOUT"    flag:"http.shutdown-timeout" usage:"time allowed for in-flight requests to finish on shutdown"`
+	// TrustedProxies — адреса и подсети прокси, которым разрешено задавать
+	// IP клиента в X-Forwarded-For. Пустой список — IP берется из
+	// соединения.
+	TrustedProxies []string `config:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" flag:"http.trusted-proxies" usage:"comma-separated IPs or CIDRs of proxies allowed to set X-Forwarded-For"`
+}
+
+// DatabaseConfig содержит параметры подключения к PostgreSQL и пула
+// соединений. Если URL не задан, строка подключения собирается из
+// отдельных параметров.
+type DatabaseConfig struct {
+	URL             string        `config:"url"                env:"POSTGRES_URL"          flag:"db.url"                usage:"PostgreSQL connection URL"`
+	Host            string        `config:"host"               env:"DB_HOST"               flag:"db.host"               usage:"database host"`
+	Port            int           `config:"port"               env:"DB_PORT"               flag:"db.port"               usage:"database port"`
+	User            string        `config:"user"               env:"DB_USER"               flag:"db.user"               usage:"database user"`
+	Password        string        `config:"password"           env:"DB_PASSWORD"           flag:"db.password"           usage:"database password"`
+	Name            string        `config:"name"               env:"DB_NAME"               flag:"db.name"               usage:"database name"`
+	TestName        string        `config:"test_name"          env:"DB_NAME_TEST"          flag:"db.test-name"          usage:"database name used by tests"`
+	SSLMode         string        `config:"sslmode"            env:"DB_SSLMODE"            flag:"db.sslmode"            usage:"PostgreSQL sslmode"`
+	MaxOpenConns    int           `config:"max_open_conns"     env:"DB_MAX_OPEN_CONNS"     flag:"db.max-open-conns"     usage:"maximum number of open connections (0 = unlimited)"`
+	MaxIdleConns    int           `config:"max_idle_conns"     env:"DB_MAX_IDLE_CONNS"     flag:"db.max-idle-conns"     usage:"maximum number of idle connections"`
+	ConnMaxLifetime time.Duration `config:"conn_max_lifetime"  env:"DB_CONN_MAX_LIFETIME"  flag:"db.conn-max-lifetime"  usage:"maximum lifetime of a connection (0 = unlimited)"`
+	ConnMaxIdleTime time.Duration `config:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" flag:"db.conn-max-idle-time" usage:"maximum idle time of a connection (0 = unlimited)"`
+	// RowLevelSecurity включает политики Postgres RLS по арендатору для
+	// всех ролей, включая сервис
+	RowLevelSecurity bool `config:"row_level_security" env:"DB_ROW_LEVEL_SECURITY" flag:"db.row-level-security" usage:"enable PostgreSQL row level security policies on tenant tables"`
+}
+
+// LogConfig содержит параметры логирования
+type LogConfig struct {
+	Level  string `config:"level"  env:"LOG_LEVEL"  flag:"log.level"  usage:"log level: trace, debug, info, warn, error"`
+	Format string `config:"format" env:"LOG_FORMAT" flag:"log.format" usage:"log format: json or text"`
+}
+
+// HealthConfig содержит параметры проверок состояния
+type HealthConfig struct {
+	Timeout time.Duration `config:"timeout" env:"HEALTH_CHECK_TIMEOUT" flag:"health.timeout" usage:"timeout of each readiness check"`
+}
+
+// MetricsConfig содержит параметры эндпоинта Prometheus
+type MetricsConfig struct {
+	Enabled bool   `config:"enabled" env:"METRICS_ENABLED" flag:"metrics.enabled" usage:"expose Prometheus metrics"`
+	Path    string `config:"path"    env:"METRICS_PATH"    flag:"metrics.path"    usage:"path of the Prometheus metrics endpoint"`
+}
+
+// TracingConfig содержит параметры трассировки OpenTelemetry
+type TracingConfig struct {
+	Enabled     bool    `config:"enabled"      env:"TRACING_ENABLED"             flag:"tracing.enabled"      usage:"enable OpenTelemetry tracing"`
+	Exporter    string  `config:"exporter"     env:"TRACING_EXPORTER"            flag:"tracing.exporter"     usage:"span exporter: otlp or stdout"`
+	Endpoint    string  `config:"endpoint"     env:"OTEL_EXPORTER_OTLP_ENDPOINT" flag:"tracing.endpoint"     usage:"OTLP/HTTP collector address (host:port)"`
+	Insecure    bool    `config:"insecure"     env:"TRACING_INSECURE"            flag:"tracing.insecure"     usage:"send spans to the collector over plain HTTP"`
+	ServiceName string  `config:"service_name" env:"OTEL_SERVICE_NAME"           flag:"tracing.service-name" usage:"service name reported in spans"`
+	SampleRatio float64 `config:"sample_ratio" env:"TRACING_SAMPLE_RATIO"        flag:"tracing.sample-ratio" usage:"fraction of new traces to sample (0..1)"`
+}
+
+// RateLimitConfig содержит параметры ограничения частоты запросов. Лимиты
+// задаются как "rate:burst" (токенов в секунду : запас), лимиты маршрутов —
+// как "METHOD /route/template=rate:burst". Отдельный лимит получают только
+// перечисленные в APIKeys ключи, остальные запросы ограничиваются по IP.
+type RateLimitConfig struct {
+	Enabled bool     `config:"enabled"  env:"RATE_LIMIT_ENABLED"  flag:"ratelimit.enabled"  usage:"enable per-client rate limiting"`
+	Store   string   `config:"store"    env:"RATE_LIMIT_STORE"    flag:"ratelimit.store"    usage:"rate limit store: memory"`
+	Default string   `config:"default"  env:"RATE_LIMIT_DEFAULT"  flag:"ratelimit.default"  usage:"default limit as rate:burst"`
+	Routes  []string `config:"routes"   env:"RATE_LIMIT_ROUTES"   flag:"ratelimit.routes"   usage:"comma-separated per-route limits as METHOD /path=rate:burst"`
+	APIKeys []string `config:"api_keys" env:"RATE_LIMIT_API_KEYS" flag:"ratelimit.api-keys" usage:"comma-separated API keys that get their own rate limit"`
+}
+
+// RemindersConfig содержит параметры напоминаний о продлении подписок.
+// DaysBefore используется для пользователей без собственных настроек.
+type RemindersConfig struct {
+	Enabled    bool          `config:"enabled"     env:"REMINDERS_ENABLED"     flag:"reminders.enabled"     usage:"send renewal reminders"`
+	Interval   time.Duration `config:"interval"    env:"REMINDERS_INTERVAL"    flag:"reminders.interval"    usage:"how often subscriptions are scanned for upcoming renewals"`
+	DaysBefore int           `config:"days_before" env:"REMINDERS_DAYS_BEFORE" flag:"reminders.days-before" usage:"default number of days before a renewal to send a reminder"`
+}
+
+// NotificationsConfig содержит параметры очереди уведомлений и каналов
+// доставки. Email и Telegram доступны, только если заданы SMTP-сервер и
+// токен бота соответственно.
+type NotificationsConfig struct {
+	Enabled       bool           `config:"enabled"        env:"NOTIFICATIONS_ENABLED"        flag:"notifications.enabled"        usage:"deliver queued notifications"`
+	PollInterval  time.Duration  `config:"poll_interval"  env:"NOTIFICATIONS_POLL_INTERVAL"  flag:"notifications.poll-interval"  usage:"how often the delivery queue is polled"`
+	MaxAttempts   int            `config:"max_attempts"   env:"NOTIFICATIONS_MAX_ATTEMPTS"   flag:"notifications.max-attempts"   usage:"delivery attempts before a notification is marked failed"`
+	BackoffBase   time.Duration  `config:"backoff_base"   env:"NOTIFICATIONS_BACKOFF_BASE"   flag:"notifications.backoff-base"   usage:"delay after the first failed attempt, doubled on each retry"`
+	BackoffMax    time.Duration  `config:"backoff_max"    env:"NOTIFICATIONS_BACKOFF_MAX"    flag:"notifications.backoff-max"    usage:"maximum delay between attempts"`
+	DefaultLocale string         `config:"default_locale" env:"NOTIFICATIONS_DEFAULT_LOCALE" flag:"notifications.default-locale" usage:"template locale used when the recipient's locale has no translation"`
+	SMTP          SMTPConfig     `config:"smtp"`
+	Webhook       WebhookConfig  `config:"webhook"`
+	Telegram      TelegramConfig `config:"telegram"`
+}
+
+// SMTPConfig содержит параметры отправки электронной почты
+type SMTPConfig struct {
+	Host     string `config:"host"     env:"SMTP_HOST"     flag:"notifications.smtp.host"     usage:"SMTP server host (empty disables email)"`
+	Port     int    `config:"port"     env:"SMTP_PORT"     flag:"notifications.smtp.port"     usage:"SMTP server port"`
+	Username string `config:"username" env:"SMTP_USERNAME" flag:"notifications.smtp.username" usage:"SMTP username (empty disables authentication)"`
+	Password string `config:"password" env:"SMTP_PASSWORD" flag:"notifications.smtp.password" usage:"SMTP password"`
+	From     string `config:"from"     env:"SMTP_FROM"     flag:"notifications.smtp.from"     usage:"sender address"`
+}
+
+// WebhookConfig содержит параметры уведомлений через HTTP
+type WebhookConfig struct {
+	Timeout time.Duration `config:"timeout" env:"NOTIFICATIONS_WEBHOOK_TIMEOUT" flag:"notifications.webhook.timeout" usage:"timeout of a notification webhook request"`
+}
+
+// TelegramConfig содержит параметры Telegram-бота
+type TelegramConfig struct {
+	BotToken string `config:"bot_token" env:"TELEGRAM_BOT_TOKEN" flag:"notifications.telegram.bot-token" usage:"Telegram bot token (empty disables Telegram)"`
+	APIURL   string `config:"api_url"   env:"TELEGRAM_API_URL"   flag:"notifications.telegram.api-url"   usage:"Telegram Bot API base URL"`
+}
+
+// WebhooksConfig содержит параметры доставки событий подписок на
+// зарегистрированные вебхуки
+type WebhooksConfig struct {
+	Enabled      bool          `config:"enabled"       env:"WEBHOOKS_ENABLED"       flag:"webhooks.enabled"       usage:"deliver subscription events to registered webhooks"`
+	PollInterval time.Duration `config:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL" flag:"webhooks.poll-interval" usage:"how often pending webhook deliveries are polled"`
+	MaxAttempts  int           `config:"max_attempts"  env:"WEBHOOKS_MAX_ATTEMPTS"  flag:"webhooks.max-attempts"  usage:"delivery attempts before an event is moved to the dead letter state"`
+	BackoffBase  time.Duration `config:"backoff_base"  env:"WEBHOOKS_BACKOFF_BASE"  flag:"webhooks.backoff-base"  usage:"delay after the first failed attempt, doubled on each retry"`
+	BackoffMax   time.Duration `config:"backoff_max"   env:"WEBHOOKS_BACKOFF_MAX"   flag:"webhooks.backoff-max"   usage:"maximum delay between attempts"`
+	Timeout      time.Duration `config:"timeout"       env:"WEBHOOKS_TIMEOUT"       flag:"webhooks.timeout"       usage:"timeout of a webhook request"`
+}
+
+// ExpiryConfig содержит параметры задачи, отмечающей истекшие подписки
+type ExpiryConfig struct {
+	Enabled  bool          `config:"enabled"  env:"EXPIRY_ENABLED"  flag:"expiry.enabled"  usage:"mark subscriptions past their end_date as expired"`
+	Interval time.Duration `config:"interval" env:"EXPIRY_INTERVAL" flag:"expiry.interval" usage:"how often subscriptions are checked for expiry"`
+}
+
+// OutboxConfig содержит параметры публикации событий подписок в брокер
+// сообщений через таблицу outbox
+type OutboxConfig struct {
+	Enabled      bool          `config:"enabled"       env:"OUTBOX_ENABLED"       flag:"outbox.enabled"       usage:"write subscription events to the outbox and relay them to the broker"`
+	Broker       string        `config:"broker"        env:"OUTBOX_BROKER"        flag:"outbox.broker"        usage:"message broker: kafka, nats or memory"`
+	Topic        string        `config:"topic"         env:"OUTBOX_TOPIC"         flag:"outbox.topic"         usage:"Kafka topic or NATS subject for subscription events"`
+	PollInterval time.Duration `config:"poll_interval" env:"OUTBOX_POLL_INTERVAL" flag:"outbox.poll-interval" usage:"how often the outbox is polled"`
+	BatchSize    int           `config:"batch_size"    env:"OUTBOX_BATCH_SIZE"    flag:"outbox.batch-size"    usage:"maximum number of events published at once"`
+	Retention    time.Duration `config:"retention"     env:"OUTBOX_RETENTION"     flag:"outbox.retention"     usage:"how long published events are kept (0 = forever)"`
+	Kafka        KafkaConfig   `config:"kafka"`
+	NATS         NATSConfig    `config:"nats"`
+}
+
+// KafkaConfig содержит адреса брокеров Kafka
+type KafkaConfig struct {
+	Brokers []string `config:"brokers" env:"KAFKA_BROKERS" flag:"outbox.kafka.brokers" usage:"comma-separated Kafka broker addresses"`
+}
+
+// NATSConfig содержит адрес сервера NATS
+type NATSConfig struct {
+	URL string `config:"url" env:"NATS_URL" flag:"outbox.nats.url" usage:"NATS server URL"`
+}
+
+// TenancyConfig содержит параметры разделения данных между арендаторами.
+// Арендатор запроса определяется по claim JWT (HS256) из заголовка
+// Authorization или по поддомену в Host. Если разделение выключено, все
+// данные принадлежат арендатору по умолчанию.
+type TenancyConfig struct {
+	Enabled    bool   `config:"enabled"     env:"TENANCY_ENABLED"     flag:"tenancy.enabled"     usage:"isolate data between tenants"`
+	HostSuffix string `config:"host_suffix" env:"TENANCY_HOST_SUFFIX" flag:"tenancy.host-suffix" usage:"domain suffix of tenant hosts, e.g. .example.com for acme.example.com (empty disables host resolution)"`
+	JWTSecret  string `config:"jwt_secret"  env:"TENANCY_JWT_SECRET"  flag:"tenancy.jwt-secret"  usage:"HS256 key of bearer tokens carrying the tenant (empty disables token resolution)"`
+	JWTClaim   string `config:"jwt_claim"   env:"TENANCY_JWT_CLAIM"   flag:"tenancy.jwt-claim"   usage:"token claim holding the tenant ID"`
+}
+
+// FeaturesConfig включает и отключает части API
+type FeaturesConfig struct {
+	Swagger      bool `config:"swagger"       env:"FEATURE_SWAGGER"       flag:"features.swagger"       usage:"serve Swagger UI at /swagger"`
+	LegacyRoutes bool `config:"legacy_routes" env:"FEATURE_LEGACY_ROUTES" flag:"features.legacy-routes" usage:"serve deprecated routes without the /api/v1 prefix"`
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Health: HealthConfig{
+			Timeout: 2 * time.Second,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "otlp",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "subscription-service",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   "memory",
+			Default: "10:20",
+			Routes: []string{
+				"GET /api/v1/subscriptions/total=1:5",
+				"GET /subscriptions/total=1:5",
+			},
+		},
+		Reminders: RemindersConfig{
+			Enabled:    true,
+			Interval:   24 * time.Hour,
+			DaysBefore: 3,
+		},
+		Notifications: NotificationsConfig{
+			Enabled:       true,
+			PollInterval:  5 * time.Second,
+			MaxAttempts:   8,
+			BackoffBase:   30 * time.Second,
+			BackoffMax:    time.Hour,
+			DefaultLocale: "en",
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+			Webhook: WebhookConfig{
+				Timeout: 10 * time.Second,
+			},
+			Telegram: TelegramConfig{
+				APIURL: "https://api.telegram.org",
+			},
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:      true,
+			PollInterval: 5 * time.Second,
+			MaxAttempts:  10,
+			BackoffBase:  time.Minute,
+			BackoffMax:   6 * time.Hour,
+			Timeout:      10 * time.Second,
+		},
+		Expiry: ExpiryConfig{
+			Enabled:  true,
+			Interval: time.Hour,
+		},
+		Outbox: OutboxConfig{
+			Broker:       "nats",
+			Topic:        "subscriptions.events",
+			PollInterval: time.Second,
+			BatchSize:    100,
+			Retention:    7 * 24 * time.Hour,
+			Kafka: KafkaConfig{
+				Brokers: []string{"localhost:9092"},
+			},
+			NATS: NATSConfig{
+				URL: "nats://localhost:4222",
+			},
+		},
+		Tenancy: TenancyConfig{
+			JWTClaim: "tenant_id",
+		},
+		Features: FeaturesConfig{
+			Swagger:      true,
+			LegacyRoutes: true,
+		},
+	}
+}
+
+// Load собирает конфигурацию из всех источников и проверяет ее.
+// args — аргументы командной строки без имени программы. Кроме флагов
+// отдельных параметров поддерживаются -config (путь к YAML/TOML-файлу,
+// также CONFIG_FILE) и -env-file (путь к .env).
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("subscription-service", flag.ContinueOnError)
+	configFile := fs.String(
+		"config",
+		os.Getenv("CONFIG_FILE"),
+		"path to a YAML or TOML configuration file",
+	)
+	envFile := fs.String("env-file", ".env", "path to a .env file")
+	overrides := registerFlags(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// .env не перезаписывает уже заданные переменные окружения
+	if err := godotenv.Load(*envFile); err != nil &&
+		!errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("load %s: %w", *envFile, err)
+	}
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+
+	// Ошибки разбора всех источников и проверки значений возвращаются
+	// вместе. Значение, которое не удалось разобрать, остается прежним.
+	var problems []string
+	if *configFile != "" {
+		problems = append(problems, loadFile(*configFile, cfg)...)
+	}
+	problems = append(problems, loadEnv(cfg)...)
+	problems = append(problems, overrides.apply(cfg)...)
+	problems = append(problems, cfg.problems()...)
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// DSN возвращает строку подключения к основной базе данных
+func (d DatabaseConfig) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return d.dsn(d.Name)
+}
+
+// TestDSN возвращает строку подключения к тестовой базе данных
+func (d DatabaseConfig) TestDSN() string {
+	return d.dsn(d.TestName)
+}
+
+func (d DatabaseConfig) dsn(name string) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, d.Password),
+		Host:   fmt.Sprintf("%s:%d", d.Host, d.Port),
+		Path:   "/" + name,
+	}
+	q := url.Values{}
+	if d.SSLMode != "" {
+		q.Set("sslmode", d.SSLMode)
 	}
+	u.RawQuery = q.Encode()
+	return u.String()
 }
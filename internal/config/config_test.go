@@ -0,0 +1,121 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nemopss/subscription-service/internal/config"
+)
+
+// writeFile создает временный файл конфигурации
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// noEnvFile указывает на несуществующий .env, чтобы тесты не зависели
+// от локального окружения
+func noEnvFile(t *testing.T) []string {
+	return []string{"-env-file", filepath.Join(t.TempDir(), "missing.env")}
+}
+
+// Тест для приоритета источников: файл < окружение < флаги
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+  read_timeout: 3s
+database:
+  url: postgres://file@localhost/file
+  max_open_conns: 10
+log:
+  level: debug
+`)
+	t.Setenv("HTTP_ADDR", ":9100")
+	t.Setenv("DB_MAX_OPEN_CONNS", "20")
+
+	args := append(noEnvFile(t),
+		"-config", path,
+		"-http.addr", ":9200",
+		"-features.swagger=false",
+	)
+	cfg, err := config.Load(args)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9200", cfg.Server.Addr)
+	assert.Equal(t, 3*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 20, cfg.Database.MaxOpenConns)
+	assert.Equal(t, "postgres://file@localhost/file", cfg.Database.DSN())
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.False(t, cfg.Features.Swagger)
+	assert.Equal(t, 60*time.Second, cfg.Server.IdleTimeout)
+}
+
+// Тест для загрузки TOML-файла
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[server]
+write_timeout = "45s"
+
+[database]
+host = "db"
+name = "subs"
+user = "app"
+password = "p@ss"
+`)
+
+	cfg, err := config.Load(append(noEnvFile(t), "-config", path))
+	require.NoError(t, err)
+
+	assert.Equal(t, 45*time.Second, cfg.Server.WriteTimeout)
+	assert.Equal(
+		t,
+		"postgres://app:p%40ss@db:5432/subs?sslmode=disable",
+		cfg.Database.DSN(),
+	)
+}
+
+// Тест для вывода всех ошибок валидации сразу
+func TestLoadValidationListsAllProblems(t *testing.T) {
+	args := append(noEnvFile(t),
+		"-http.addr", "",
+		"-log.format", "xml",
+		"-db.max-open-conns", "2",
+		"-db.max-idle-conns", "5",
+	)
+
+	_, err := config.Load(args)
+
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Len(t, verr.Problems, 6)
+	assert.Contains(t, err.Error(), "server.addr")
+	assert.Contains(t, err.Error(), "database.host")
+	assert.Contains(t, err.Error(), "log.format")
+	assert.Contains(t, err.Error(), "max_idle_conns")
+}
+
+// Тест для некорректных значений в переменных окружения: перечисляются
+// все ошибки разбора вместе с ошибками проверки
+func TestLoadInvalidEnv(t *testing.T) {
+	t.Setenv("POSTGRES_URL", "postgres://localhost/db")
+	t.Setenv("HTTP_READ_TIMEOUT", "soon")
+	t.Setenv("DB_PORT", "db")
+	t.Setenv("LOG_FORMAT", "xml")
+
+	_, err := config.Load(noEnvFile(t))
+
+	var verr *config.ValidationError
+	require.True(t, errors.As(err, &verr))
+	assert.Len(t, verr.Problems, 3)
+	assert.ErrorContains(t, err, "HTTP_READ_TIMEOUT")
+	assert.ErrorContains(t, err, "DB_PORT")
+	assert.ErrorContains(t, err, "log.format")
+}
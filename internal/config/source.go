@@ -0,0 +1,249 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// field — лист структуры конфигурации вместе с его тегами
+type field struct {
+	path  []string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// fields обходит структуру конфигурации и возвращает все листовые поля
+func fields(v reflect.Value, prefix []string) []field {
+	var out []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("config")
+		if key == "" {
+			continue
+		}
+		path := append(append([]string{}, prefix...), key)
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			out = append(out, fields(fv, path)...)
+			continue
+		}
+		out = append(out, field{path: path, value: fv, tag: sf.Tag})
+	}
+	return out
+}
+
+// setValue присваивает полю значение, заданное строкой
+func setValue(v reflect.Value, raw string) error {
+	switch v.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// loadFile читает YAML- или TOML-файл, выбирая формат по расширению, и
+// возвращает ошибки разбора всех значений
+func loadFile(path string, cfg *Config) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("read config file: %v", err)}
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return []string{fmt.Sprintf(
+			"unsupported config file extension %q, expected .yaml, .yml or .toml",
+			filepath.Ext(path),
+		)}
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("parse config file %s: %v", path, err)}
+	}
+
+	var problems []string
+	for _, f := range fields(reflect.ValueOf(cfg).Elem(), nil) {
+		v, ok := lookup(raw, f.path)
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, stringify(v)); err != nil {
+			problems = append(problems, fmt.Sprintf(
+				"config file %s: %s: %v",
+				path,
+				strings.Join(f.path, "."),
+				err,
+			))
+		}
+	}
+	return problems
+}
+
+func lookup(raw map[string]any, path []string) (any, bool) {
+	var cur any = raw
+	for _, key := range path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func stringify(v any) string {
+	if items, ok := v.([]any); ok {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// loadEnv применяет переменные окружения, указанные в тегах env, и
+// возвращает ошибки разбора всех значений
+func loadEnv(cfg *Config) []string {
+	var problems []string
+	for _, f := range fields(reflect.ValueOf(cfg).Elem(), nil) {
+		key := f.tag.Get("env")
+		if key == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(key)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("environment variable %s: %v", key, err))
+		}
+	}
+	return problems
+}
+
+// flagOverrides хранит значения флагов, явно заданных в командной строке
+type flagOverrides map[string]string
+
+func (o flagOverrides) apply(cfg *Config) []string {
+	var problems []string
+	for _, f := range fields(reflect.ValueOf(cfg).Elem(), nil) {
+		name := f.tag.Get("flag")
+		raw, ok := o[name]
+		if name == "" || !ok {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			problems = append(problems, fmt.Sprintf("flag -%s: %v", name, err))
+		}
+	}
+	return problems
+}
+
+type flagValue struct {
+	name      string
+	def       string
+	isBool    bool
+	overrides flagOverrides
+}
+
+// IsBoolFlag позволяет задавать булевы флаги без значения (-features.swagger)
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	if raw, ok := v.overrides[v.name]; ok {
+		return raw
+	}
+	return v.def
+}
+
+func (v *flagValue) Set(raw string) error {
+	v.overrides[v.name] = raw
+	return nil
+}
+
+// registerFlags регистрирует флаг для каждого поля с тегом flag. Значения
+// применяются после файла и окружения, поэтому имеют наивысший приоритет.
+func registerFlags(fs *flag.FlagSet, defaults *Config) flagOverrides {
+	overrides := flagOverrides{}
+	for _, f := range fields(reflect.ValueOf(defaults).Elem(), nil) {
+		name := f.tag.Get("flag")
+		if name == "" {
+			continue
+		}
+		usage := f.tag.Get("usage")
+		if env := f.tag.Get("env"); env != "" {
+			usage += " (env " + env + ")"
+		}
+		fs.Var(&flagValue{
+			name:      name,
+			def:       fmt.Sprint(f.value.Interface()),
+			isBool:    f.value.Kind() == reflect.Bool,
+			overrides: overrides,
+		}, name, usage)
+	}
+	return overrides
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nemopss/subscription-service/internal/limits"
+)
+
+// ValidationError перечисляет все найденные ошибки конфигурации
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate проверяет конфигурацию целиком и возвращает *ValidationError
+// со списком всех проблем, а не только первой
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// problems возвращает все проблемы значений конфигурации
+func (c *Config) problems() []string {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		add("server.addr (HTTP_ADDR) must not be empty")
+	}
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout (HTTP_READ_TIMEOUT)", c.Server.ReadTimeout},
+		{"server.read_header_timeout (HTTP_READ_HEADER_TIMEOUT)", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout (HTTP_WRITE_TIMEOUT)", c.Server.WriteTimeout},
+		{"server.idle_timeout (HTTP_IDLE_TIMEOUT)", c.Server.IdleTimeout},
+		{"server.shutdown_timeout (HTTP_SHUTDOWN_TIMEOUT)", c.Server.ShutdownTimeout},
+	}
+	for _, t := range timeouts {
+		if t.value <= 0 {
+			add("%s must be positive", t.name)
+		}
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		add("server.max_header_bytes (HTTP_MAX_HEADER_BYTES) must be positive")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				add("server.trusted_proxies (HTTP_TRUSTED_PROXIES) must contain IPs or CIDRs, got %q", proxy)
+			}
+		}
+	}
+
+	db := c.Database
+	if db.URL == "" {
+		if db.Host == "" {
+			add("database.host (DB_HOST) is required when database.url (POSTGRES_URL) is not set")
+		}
+		if db.Name == "" {
+			add("database.name (DB_NAME) is required when database.url (POSTGRES_URL) is not set")
+		}
+		if db.User == "" {
+			add("database.user (DB_USER) is required when database.url (POSTGRES_URL) is not set")
+		}
+	}
+	if db.Port <= 0 || db.Port > 65535 {
+		add("database.port (DB_PORT) must be between 1 and 65535, got %d", db.Port)
+	}
+	if db.MaxOpenConns < 0 {
+		add("database.max_open_conns (DB_MAX_OPEN_CONNS) must not be negative")
+	}
+	if db.MaxIdleConns < 0 {
+		add("database.max_idle_conns (DB_MAX_IDLE_CONNS) must not be negative")
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		add(
+			"database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)",
+			db.MaxIdleConns,
+			db.MaxOpenConns,
+		)
+	}
+	if db.ConnMaxLifetime < 0 {
+		add("database.conn_max_lifetime (DB_CONN_MAX_LIFETIME) must not be negative")
+	}
+	if db.ConnMaxIdleTime < 0 {
+		add("database.conn_max_idle_time (DB_CONN_MAX_IDLE_TIME) must not be negative")
+	}
+
+	if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+		add("log.level (LOG_LEVEL): %v", err)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		add("log.format (LOG_FORMAT) must be json or text, got %q", c.Log.Format)
+	}
+
+	if c.Health.Timeout <= 0 {
+		add("health.timeout (HEALTH_CHECK_TIMEOUT) must be positive")
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		add("metrics.path (METRICS_PATH) must start with /, got %q", c.Metrics.Path)
+	}
+
+	if c.Tracing.Enabled {
+		switch c.Tracing.Exporter {
+		case "otlp":
+			if c.Tracing.Endpoint == "" {
+				add("tracing.endpoint (OTEL_EXPORTER_OTLP_ENDPOINT) is required for the otlp exporter")
+			}
+		case "stdout":
+		default:
+			add("tracing.exporter (TRACING_EXPORTER) must be otlp or stdout, got %q", c.Tracing.Exporter)
+		}
+		if c.Tracing.ServiceName == "" {
+			add("tracing.service_name (OTEL_SERVICE_NAME) must not be empty")
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			add("tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Store != "memory" {
+			add("rate_limit.store (RATE_LIMIT_STORE) must be memory, got %q", c.RateLimit.Store)
+		}
+		if _, err := limits.ParsePolicy(c.RateLimit.Default, c.RateLimit.Routes); err != nil {
+			add("rate_limit (RATE_LIMIT_DEFAULT, RATE_LIMIT_ROUTES): %v", err)
+		}
+	}
+
+	if c.Reminders.Enabled {
+		if c.Reminders.Interval <= 0 {
+			add("reminders.interval (REMINDERS_INTERVAL) must be positive")
+		}
+		if c.Reminders.DaysBefore < 0 || c.Reminders.DaysBefore > limits.ReminderDaysBefore {
+			add(
+				"reminders.days_before (REMINDERS_DAYS_BEFORE) must be between 0 and %d, got %d",
+				limits.ReminderDaysBefore,
+				c.Reminders.DaysBefore,
+			)
+		}
+	}
+
+	if n := c.Notifications; n.Enabled {
+		if n.PollInterval <= 0 {
+			add("notifications.poll_interval (NOTIFICATIONS_POLL_INTERVAL) must be positive")
+		}
+		if n.MaxAttempts <= 0 {
+			add("notifications.max_attempts (NOTIFICATIONS_MAX_ATTEMPTS) must be positive")
+		}
+		if n.BackoffBase <= 0 {
+			add("notifications.backoff_base (NOTIFICATIONS_BACKOFF_BASE) must be positive")
+		}
+		if n.BackoffMax < n.BackoffBase {
+			add("notifications.backoff_max (NOTIFICATIONS_BACKOFF_MAX) must not be less than notifications.backoff_base")
+		}
+		if n.DefaultLocale == "" {
+			add("notifications.default_locale (NOTIFICATIONS_DEFAULT_LOCALE) must not be empty")
+		}
+		if n.SMTP.Host != "" {
+			if n.SMTP.Port <= 0 || n.SMTP.Port > 65535 {
+				add("notifications.smtp.port (SMTP_PORT) must be between 1 and 65535, got %d", n.SMTP.Port)
+			}
+			if n.SMTP.From == "" {
+				add("notifications.smtp.from (SMTP_FROM) is required when notifications.smtp.host (SMTP_HOST) is set")
+			}
+		}
+		if n.Webhook.Timeout <= 0 {
+			add("notifications.webhook.timeout (NOTIFICATIONS_WEBHOOK_TIMEOUT) must be positive")
+		}
+		if n.Telegram.BotToken != "" && n.Telegram.APIURL == "" {
+			add("notifications.telegram.api_url (TELEGRAM_API_URL) must not be empty")
+		}
+	}
+
+	if w := c.Webhooks; w.Enabled {
+		if w.PollInterval <= 0 {
+			add("webhooks.poll_interval (WEBHOOKS_POLL_INTERVAL) must be positive")
+		}
+		if w.MaxAttempts <= 0 {
+			add("webhooks.max_attempts (WEBHOOKS_MAX_ATTEMPTS) must be positive")
+		}
+		if w.BackoffBase <= 0 {
+			add("webhooks.backoff_base (WEBHOOKS_BACKOFF_BASE) must be positive")
+		}
+		if w.BackoffMax < w.BackoffBase {
+			add("webhooks.backoff_max (WEBHOOKS_BACKOFF_MAX) must not be less than webhooks.backoff_base")
+		}
+		if w.Timeout <= 0 {
+			add("webhooks.timeout (WEBHOOKS_TIMEOUT) must be positive")
+		}
+	}
+
+	if c.Expiry.Enabled && c.Expiry.Interval <= 0 {
+		add("expiry.interval (EXPIRY_INTERVAL) must be positive")
+	}
+
+	if o := c.Outbox; o.Enabled {
+		switch o.Broker {
+		case "kafka":
+			if len(o.Kafka.Brokers) == 0 {
+				add("outbox.kafka.brokers (KAFKA_BROKERS) is required for the kafka broker")
+			}
+		case "nats":
+			if o.NATS.URL == "" {
+				add("outbox.nats.url (NATS_URL) is required for the nats broker")
+			}
+		case "memory":
+		default:
+			add("outbox.broker (OUTBOX_BROKER) must be kafka, nats or memory, got %q", o.Broker)
+		}
+		if o.Topic == "" {
+			add("outbox.topic (OUTBOX_TOPIC) must not be empty")
+		}
+		if o.PollInterval <= 0 {
+			add("outbox.poll_interval (OUTBOX_POLL_INTERVAL) must be positive")
+		}
+		if o.BatchSize <= 0 {
+			add("outbox.batch_size (OUTBOX_BATCH_SIZE) must be positive")
+		}
+		if o.Retention < 0 {
+			add("outbox.retention (OUTBOX_RETENTION) must not be negative")
+		}
+	}
+
+	if t := c.Tenancy; t.Enabled {
+		if t.HostSuffix == "" && t.JWTSecret == "" {
+			add("tenancy.host_suffix (TENANCY_HOST_SUFFIX) or tenancy.jwt_secret (TENANCY_JWT_SECRET) is required when tenancy is enabled")
+		}
+		if t.JWTSecret != "" && t.JWTClaim == "" {
+			add("tenancy.jwt_claim (TENANCY_JWT_CLAIM) must not be empty")
+		}
+	}
+
+	return problems
+}
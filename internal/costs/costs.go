@@ -0,0 +1,161 @@
+package costs
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/sharing"
+)
+
+// Preload загружает то, что нужно для расчета стоимости подписок:
+// приостановки в порядке начала, изменения цены в порядке вступления и
+// участников совместной подписки
+func Preload(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Pauses", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("start_date")
+		}).
+		Preload("PriceChanges", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("effective_date")
+		}).
+		Preload("Shares", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("id")
+		})
+}
+
+// Cost возвращает стоимость подписки в месяцах с from по to включительно
+// с учетом периодичности списаний, приостановок и изменений цены.
+// Подписка без end_date считается действующей по openEnd.
+func Cost(sub models.Subscription, from, to, openEnd time.Time) (int, error) {
+	return cost(sub, from, to, openEnd, func(price int) int { return price })
+}
+
+// UserCost возвращает долю пользователя в стоимости подписки, считаемой
+// как в Cost: долю каждого списания по правилу разделения подписки
+func UserCost(sub models.Subscription, userID uuid.UUID, from, to, openEnd time.Time) (int, error) {
+	return cost(sub, from, to, openEnd, func(price int) int {
+		return sharing.ShareOf(sub, userID, price)
+	})
+}
+
+func cost(sub models.Subscription, from, to, openEnd time.Time, share func(price int) int) (int, error) {
+	start, err := time.Parse(billing.DateLayout, sub.StartDate)
+	if err != nil {
+		return 0, err
+	}
+	end := openEnd
+	if sub.EndDate != nil {
+		if end, err = time.Parse(billing.DateLayout, *sub.EndDate); err != nil {
+			return 0, err
+		}
+	}
+
+	// Пересечение периода подписки с запрошенным периодом
+	from = maxTime(start, from)
+	to = minTime(end, to)
+	skip := PauseIntervals(sub.Pauses)
+
+	total := 0
+	for _, p := range prices(sub) {
+		segTo := to
+		if !p.To.IsZero() {
+			segTo = minTime(to, billing.MonthStart(billing.MonthIndex(p.To)-1))
+		}
+		charges := billing.ChargesExcluding(
+			start,
+			sub.BillingCycle,
+			maxTime(from, p.From),
+			segTo,
+			skip,
+		)
+		total += share(p.price) * charges
+	}
+	return total, nil
+}
+
+// priced — интервал месяцев с одной ценой
+type priced struct {
+	billing.Interval
+	price int
+}
+
+// prices делит время на интервалы с одной ценой: до первого изменения
+// действует цена подписки, затем цена каждого изменения до следующего.
+// Изменения должны идти по возрастанию EffectiveDate.
+func prices(sub models.Subscription) []priced {
+	out := []priced{{price: sub.Price}}
+	for _, change := range sub.PriceChanges {
+		at, err := time.Parse(billing.DateLayout, change.EffectiveDate)
+		if err != nil {
+			continue
+		}
+		out[len(out)-1].To = at
+		out = append(out, priced{
+			Interval: billing.Interval{From: at},
+			price:    change.Price,
+		})
+	}
+	return out
+}
+
+// Forecast возвращает ожидаемые списания пользователя по подпискам за
+// months месяцев начиная с месяца from. Подписки без end_date считаются
+// действующими до конца прогноза, открытые приостановки — не
+// закончившимися.
+func Forecast(subs []models.Subscription, userID uuid.UUID, from time.Time, months int) ([]models.ForecastMonth, int) {
+	first := billing.MonthIndex(from)
+	openEnd := MonthEnd(billing.MonthStart(first + months - 1))
+
+	series := make([]models.ForecastMonth, months)
+	total := 0
+	for i := range series {
+		monthStart := billing.MonthStart(first + i)
+		series[i].Month = monthStart.Format("01-2006")
+		for _, sub := range subs {
+			cost, err := UserCost(sub, userID, monthStart, MonthEnd(monthStart), openEnd)
+			if err != nil {
+				continue
+			}
+			series[i].Total += cost
+		}
+		total += series[i].Total
+	}
+	return series, total
+}
+
+// PauseIntervals переводит приостановки в интервалы для расчета списаний
+func PauseIntervals(pauses []models.Pause) []billing.Interval {
+	out := make([]billing.Interval, 0, len(pauses))
+	for _, p := range pauses {
+		var iv billing.Interval
+		iv.From, _ = time.Parse(billing.DateLayout, p.StartDate)
+		if p.EndDate != nil {
+			iv.To, _ = time.Parse(billing.DateLayout, *p.EndDate)
+		}
+		out = append(out, iv)
+	}
+	return out
+}
+
+// MonthEnd возвращает последний день месяца, в который попадает t
+func MonthEnd(t time.Time) time.Time {
+	return billing.MonthStart(billing.MonthIndex(t)+1).AddDate(0, 0, -1)
+}
+
+func maxTime(t1, t2 time.Time) time.Time {
+	if t1.After(t2) {
+		return t1
+	}
+	return t2
+}
+
+func minTime(t1, t2 time.Time) time.Time {
+	if t1.Before(t2) {
+		return t1
+	}
+	return t2
+}
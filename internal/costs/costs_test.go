@@ -0,0 +1,159 @@
+package costs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/costs"
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Тест для расчета стоимости подписки за период
+func TestCost(t *testing.T) {
+	end := "2025-06-01"
+	resumed := "2025-03-01"
+	tests := []struct {
+		name    string
+		sub     models.Subscription
+		from    time.Time
+		to      time.Time
+		openEnd time.Time
+		want    int
+	}{
+		{
+			name:    "open-ended until openEnd",
+			sub:     models.Subscription{Price: 100, StartDate: "2025-01-01", BillingCycle: billing.Monthly},
+			from:    date(2025, 1, 1),
+			to:      date(2025, 12, 31),
+			openEnd: date(2025, 4, 15),
+			want:    400,
+		},
+		{
+			name:    "end date limits period",
+			sub:     models.Subscription{Price: 100, StartDate: "2025-01-01", EndDate: &end, BillingCycle: billing.Monthly},
+			from:    date(2025, 1, 1),
+			to:      date(2025, 12, 31),
+			openEnd: date(2025, 12, 31),
+			want:    600,
+		},
+		{
+			name: "paused months excluded",
+			sub: models.Subscription{
+				Price:        100,
+				StartDate:    "2025-01-01",
+				BillingCycle: billing.Monthly,
+				Pauses:       []models.Pause{{StartDate: "2025-02-01", EndDate: &resumed}},
+			},
+			from:    date(2025, 1, 1),
+			to:      date(2025, 4, 30),
+			openEnd: date(2025, 4, 30),
+			want:    300,
+		},
+		{
+			name: "scheduled price change",
+			sub: models.Subscription{
+				Price:        100,
+				StartDate:    "2025-01-01",
+				BillingCycle: billing.Monthly,
+				PriceChanges: []models.PriceChange{
+					{EffectiveDate: "2025-03-01", Price: 150},
+					{EffectiveDate: "2025-05-01", Price: 200},
+				},
+			},
+			from:    date(2025, 2, 1),
+			to:      date(2025, 5, 31),
+			openEnd: date(2025, 12, 31),
+			want:    100 + 150 + 150 + 200,
+		},
+		{
+			name:    "yearly charge outside period",
+			sub:     models.Subscription{Price: 1200, StartDate: "2025-01-01", BillingCycle: billing.Yearly},
+			from:    date(2025, 2, 1),
+			to:      date(2025, 12, 31),
+			openEnd: date(2025, 12, 31),
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := costs.Cost(tt.sub, tt.from, tt.to, tt.openEnd)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Тест для прогноза списаний по месяцам
+func TestForecast(t *testing.T) {
+	end := "2026-12-01"
+	userID := uuid.New()
+	subs := []models.Subscription{
+		{
+			UserID:       userID,
+			Price:        300,
+			StartDate:    "2026-01-01",
+			BillingCycle: billing.Monthly,
+			EndDate:      &end,
+		},
+		{
+			UserID:       userID,
+			Price:        1200,
+			StartDate:    "2026-02-01",
+			BillingCycle: billing.Quarterly,
+			PriceChanges: []models.PriceChange{{EffectiveDate: "2027-01-01", Price: 1500}},
+		},
+	}
+
+	series, total := costs.Forecast(subs, userID, date(2026, 10, 19), 5)
+	require.Len(t, series, 5)
+	assert.Equal(t, []models.ForecastMonth{
+		{Month: "10-2026", Total: 300},
+		{Month: "11-2026", Total: 300 + 1200},
+		{Month: "12-2026", Total: 300},
+		{Month: "01-2027", Total: 0},
+		{Month: "02-2027", Total: 1500},
+	}, series)
+	assert.Equal(t, 3600, total)
+}
+
+// Тест для доли участника совместной подписки
+func TestUserCost(t *testing.T) {
+	owner, member := uuid.New(), uuid.New()
+	sub := models.Subscription{
+		UserID:       owner,
+		Price:        1000,
+		StartDate:    "2025-01-01",
+		BillingCycle: billing.Monthly,
+		SplitRule:    "equal",
+		Shares:       []models.Share{{UserID: member}},
+		PriceChanges: []models.PriceChange{{EffectiveDate: "2025-03-01", Price: 1201}},
+	}
+
+	got, err := costs.UserCost(sub, member, date(2025, 1, 1), date(2025, 3, 31), date(2025, 3, 31))
+	require.NoError(t, err)
+	assert.Equal(t, 500+500+600, got)
+
+	got, err = costs.UserCost(sub, owner, date(2025, 1, 1), date(2025, 3, 31), date(2025, 3, 31))
+	require.NoError(t, err)
+	assert.Equal(t, 500+500+601, got)
+
+	got, err = costs.UserCost(sub, uuid.New(), date(2025, 1, 1), date(2025, 3, 31), date(2025, 3, 31))
+	require.NoError(t, err)
+	assert.Zero(t, got)
+}
+
+// Тест для конца месяца
+func TestMonthEnd(t *testing.T) {
+	assert.Equal(t, date(2024, 2, 29), costs.MonthEnd(date(2024, 2, 10)))
+	assert.Equal(t, date(2025, 12, 31), costs.MonthEnd(date(2025, 12, 1)))
+}
@@ -1,44 +1,169 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/config"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
+	"github.com/nemopss/subscription-service/internal/metrics"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+	"github.com/nemopss/subscription-service/internal/tracing"
 	"github.com/nemopss/subscription-service/migrations"
 	"github.com/nemopss/subscription-service/pkg/logger"
 )
 
 var DB *gorm.DB
 
-func InitDB() error {
+func InitDB(cfg config.DatabaseConfig) error {
 	logger.Log.Info("Initialising database")
-	dsn := os.Getenv("POSTGRES_URL")
-	if dsn == "" {
-		return fmt.Errorf("переменная окружения POSTGRES_URL не установлена")
-	}
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(
+		postgres.Open(cfg.DSN()),
+		&gorm.Config{TranslateError: true},
+	)
 	if err != nil {
 		logger.Log.WithError(err).Error("Error opening database")
 		return err
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return err
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return err
+	}
+	if err := db.Use(tenancy.Plugin{RowLevelSecurity: cfg.RowLevelSecurity}); err != nil {
+		return err
+	}
+	registerMetrics(sqlDB)
+
 	DB = db
 
+	// Миграции работают с данными всех арендаторов
+	migrationDB := DB.WithContext(tenancy.System(context.Background()))
 	m := gormigrate.New(
-		DB,
+		migrationDB,
 		gormigrate.DefaultOptions,
-		[]*gormigrate.Migration{migrations.Migrate20250721(DB)},
+		migrations.All(migrationDB),
 	)
 
 	if err := m.Migrate(); err != nil {
 		logger.Log.WithError(err).Error("Migration failed")
 		return err
 	}
+	if cfg.RowLevelSecurity {
+		rls := gormigrate.New(
+			migrationDB,
+			gormigrate.DefaultOptions,
+			[]*gormigrate.Migration{
+				migrations.RowLevelSecurity(migrationDB),
+				migrations.ForceRowLevelSecurity(migrationDB),
+			},
+		)
+		if err := rls.Migrate(); err != nil {
+			logger.Log.WithError(err).Error("Row level security migration failed")
+			return err
+		}
+	}
 
 	logger.Log.Info("Database initialised")
 	return nil
 }
+
+// Close закрывает пул соединений с базой данных
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	logger.Log.Info("Closing database connections")
+	return sqlDB.Close()
+}
+
+// Ping проверяет доступность базы данных
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database is not initialised")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// MigrationsApplied проверяет, что последняя миграция применена
+func MigrationsApplied(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database is not initialised")
+	}
+	latest := migrations.LatestID(DB)
+	var count int64
+	err := DB.WithContext(ctx).
+		Table(gormigrate.DefaultOptions.TableName).
+		Where(gormigrate.DefaultOptions.IDColumnName+" = ?", latest).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("migration %s is not applied", latest)
+	}
+	return nil
+}
+
+// registerMetrics регистрирует метрики пула соединений и количества
+// активных подписок
+func registerMetrics(sqlDB *sql.DB) {
+	for _, c := range []prometheus.Collector{
+		collectors.NewDBStatsCollector(sqlDB, "subscriptions"),
+		metrics.NewActiveSubscriptions(countActiveSubscriptions),
+	} {
+		err := metrics.Registry.Register(c)
+		var already prometheus.AlreadyRegisteredError
+		if err != nil && !errors.As(err, &already) {
+			logger.Log.WithError(err).Warn("Failed to register database metrics")
+		}
+	}
+}
+
+// countActiveSubscriptions считает подписки всех арендаторов в состояниях
+// active и trialing, которые уже начались и еще не закончились.
+// Приостановленные, отмененные и истекшие подписки не учитываются.
+func countActiveSubscriptions(ctx context.Context) (int64, error) {
+	now := time.Now().UTC()
+	today := now.Format(billing.DateLayout)
+	// end_date — первый день последнего месяца подписки включительно
+	monthStart := billing.MonthStart(billing.MonthIndex(now)).Format(billing.DateLayout)
+	var count int64
+	err := DB.WithContext(tenancy.System(ctx)).
+		Model(&models.Subscription{}).
+		Where("status IN ?", []lifecycle.Status{lifecycle.Active, lifecycle.Trialing}).
+		Where("start_date <= ?", today).
+		Where("end_date IS NULL OR end_date >= ?", monthStart).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,126 @@
+package duplicates
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/nemopss/subscription-service/internal/catalog"
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+// key определяет сервис подписки: по связи с каталогом, по совпадению
+// названия с названием или псевдонимом сервиса каталога, иначе по
+// нормализованному названию
+func key(sub models.Subscription, services []models.Service) (string, *models.Service) {
+	for i := range services {
+		if sub.ServiceID != nil && services[i].ID == *sub.ServiceID {
+			return "service:" + strconv.Itoa(services[i].ID), &services[i]
+		}
+	}
+	if s, ok := catalog.Match(services, sub.ServiceName); ok {
+		return "service:" + strconv.Itoa(s.ID), &s
+	}
+	return "name:" + catalog.Normalize(sub.ServiceName), nil
+}
+
+// Overlap сообщает, пересекаются ли периоды подписок. Подписка действует
+// по месяц end_date включительно, без end_date — бессрочно.
+func Overlap(a, b models.Subscription) bool {
+	return before(a.StartDate, b.EndDate) && before(b.StartDate, a.EndDate)
+}
+
+// before сообщает, начинается ли подписка с start не позже end
+func before(start string, end *string) bool {
+	return end == nil || start <= *end
+}
+
+// Of возвращает подписки из others, дублирующие sub: того же
+// пользователя, на тот же сервис и с пересекающимся периодом
+func Of(sub models.Subscription, others []models.Subscription, services []models.Service) []models.Subscription {
+	k, _ := key(sub, services)
+	var out []models.Subscription
+	for _, other := range others {
+		if other.ID == sub.ID || other.UserID != sub.UserID {
+			continue
+		}
+		if ok, _ := key(other, services); ok == k && Overlap(sub, other) {
+			out = append(out, other)
+		}
+	}
+	return out
+}
+
+// Detect находит группы дублирующихся подписок. Подписки одного
+// пользователя на один сервис объединяются в группу, если их периоды
+// пересекаются напрямую или через другие подписки группы.
+func Detect(subs []models.Subscription, services []models.Service) []models.DuplicateGroup {
+	type bucket struct {
+		userID  uuid.UUID
+		key     string
+		service *models.Service
+		subs    []models.Subscription
+	}
+	var buckets []*bucket
+	index := map[string]*bucket{}
+	for _, sub := range subs {
+		k, service := key(sub, services)
+		id := sub.UserID.String() + "/" + k
+		b, ok := index[id]
+		if !ok {
+			b = &bucket{userID: sub.UserID, key: k, service: service}
+			index[id] = b
+			buckets = append(buckets, b)
+		}
+		b.subs = append(b.subs, sub)
+	}
+
+	var out []models.DuplicateGroup
+	for _, b := range buckets {
+		for _, group := range overlapping(b.subs) {
+			dg := models.DuplicateGroup{
+				UserID:        b.userID,
+				ServiceName:   group[0].ServiceName,
+				Subscriptions: group,
+			}
+			if b.service != nil {
+				dg.ServiceName = b.service.Name
+				dg.ServiceID = &b.service.ID
+			}
+			out = append(out, dg)
+		}
+	}
+	return out
+}
+
+// overlapping делит подписки одного сервиса на цепочки пересекающихся
+// периодов и возвращает цепочки из двух и более подписок
+func overlapping(subs []models.Subscription) [][]models.Subscription {
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].StartDate < subs[j].StartDate
+	})
+
+	var out [][]models.Subscription
+	var group []models.Subscription
+	// end — конец самой поздней подписки группы, nil — бессрочно
+	var end *string
+	for _, sub := range subs {
+		if len(group) > 0 && before(sub.StartDate, end) {
+			group = append(group, sub)
+			if end != nil && (sub.EndDate == nil || *sub.EndDate > *end) {
+				end = sub.EndDate
+			}
+			continue
+		}
+		if len(group) > 1 {
+			out = append(out, group)
+		}
+		group = []models.Subscription{sub}
+		end = sub.EndDate
+	}
+	if len(group) > 1 {
+		out = append(out, group)
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package duplicates
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// Тест для пересечения периодов подписок
+func TestOverlap(t *testing.T) {
+	a := models.Subscription{StartDate: "2025-01-01", EndDate: strPtr("2025-06-01")}
+	assert.True(t, Overlap(a, models.Subscription{StartDate: "2025-06-01"}))
+	assert.True(t, Overlap(a, models.Subscription{StartDate: "2024-01-01", EndDate: strPtr("2025-01-01")}))
+	assert.False(t, Overlap(a, models.Subscription{StartDate: "2025-07-01"}))
+	assert.False(t, Overlap(a, models.Subscription{StartDate: "2024-01-01", EndDate: strPtr("2024-12-01")}))
+	assert.True(t, Overlap(models.Subscription{StartDate: "2025-01-01"}, models.Subscription{StartDate: "2030-01-01"}))
+}
+
+// Тест для поиска дублей с учетом псевдонимов каталога
+func TestDetect(t *testing.T) {
+	services := []models.Service{{ID: 1, Name: "Yandex Plus", Aliases: []string{"Яндекс Плюс"}}}
+	user, other := uuid.New(), uuid.New()
+	subs := []models.Subscription{
+		{ID: 1, UserID: user, ServiceName: "Yandex Plus", ServiceID: intPtr(1), StartDate: "2025-01-01"},
+		{ID: 2, UserID: user, ServiceName: "яндекс плюс", StartDate: "2025-03-01", EndDate: strPtr("2025-05-01")},
+		// Другой пользователь
+		{ID: 3, UserID: other, ServiceName: "Yandex Plus", StartDate: "2025-01-01"},
+		// Периоды не пересекаются
+		{ID: 4, UserID: user, ServiceName: "Netflix", StartDate: "2025-01-01", EndDate: strPtr("2025-02-01")},
+		{ID: 5, UserID: user, ServiceName: " NETFLIX ", StartDate: "2025-03-01"},
+	}
+
+	groups := Detect(subs, services)
+	if assert.Len(t, groups, 1) {
+		assert.Equal(t, "Yandex Plus", groups[0].ServiceName)
+		assert.Equal(t, 1, *groups[0].ServiceID)
+		assert.Len(t, groups[0].Subscriptions, 2)
+	}
+
+	dups := Of(models.Subscription{ID: 6, UserID: user, ServiceName: "netflix", StartDate: "2025-02-01"}, subs, services)
+	if assert.Len(t, dups, 2) {
+		assert.Equal(t, 4, dups[0].ID)
+		assert.Equal(t, 5, dups[1].ID)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/outbox"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+	"github.com/nemopss/subscription-service/internal/webhooks"
+)
+
+// Типы агрегатов для событий в outbox
+const (
+	AggregateSubscription = "subscription"
+	AggregateBudget       = "budget"
+)
+
+var outboxEnabled atomic.Bool
+
+// EnableOutbox включает запись событий в outbox. Вызывается при запуске,
+// если настроен relay: без него события копились бы в таблице.
+func EnableOutbox(enabled bool) {
+	outboxEnabled.Store(enabled)
+}
+
+// SubscriptionChanged записывает событие изменения подписки для всех
+// потребителей: в outbox для брокера сообщений и в очередь вебхуков
+// арендатора подписки. Вызывается в транзакции изменения.
+func SubscriptionChanged(tx *gorm.DB, eventType string, sub models.Subscription) error {
+	tx = tenancy.Scope(tx, sub.TenantID)
+	if outboxEnabled.Load() {
+		err := outbox.Write(
+			tx,
+			AggregateSubscription,
+			strconv.Itoa(sub.ID),
+			eventType,
+			sub,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return webhooks.Publish(tx, eventType, sub)
+}
+
+// BudgetExceeded записывает событие превышения бюджета для всех
+// потребителей арендатора бюджета. Вызывается в транзакции изменения
+// подписки.
+func BudgetExceeded(tx *gorm.DB, alert models.BudgetAlert) error {
+	tx = tenancy.Scope(tx, alert.TenantID)
+	if outboxEnabled.Load() {
+		err := outbox.Write(
+			tx,
+			AggregateBudget,
+			strconv.Itoa(alert.BudgetID),
+			webhooks.EventBudgetExceeded,
+			alert,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return webhooks.Publish(tx, webhooks.EventBudgetExceeded, alert)
+}
@@ -0,0 +1,93 @@
+package expiry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/events"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/webhooks"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// Job переводит в состояние expired подписки, срок действия которых
+// закончился, и публикует для них событие subscription.expired. Подписка
+// действует по месяц end_date включительно, поэтому истекает с первого
+// числа следующего месяца.
+type Job struct {
+	db       *gorm.DB
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewJob(db *gorm.DB, interval time.Duration) *Job {
+	return &Job{db: db, interval: interval, now: time.Now}
+}
+
+func (j *Job) Name() string {
+	return "subscription-expiry"
+}
+
+// Run выполняет проход сразу после запуска и затем раз в interval
+func (j *Job) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			logger.Log.WithError(err).Error("Subscription expiry pass failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce переводит истекшие подписки в expired и возвращает их число.
+// Переход выполняется одним запросом, поэтому правила жизненного цикла
+// проверяются в условии: обновляются только подписки в состояниях, из
+// которых lifecycle разрешает истечение. Переход и события записываются в
+// одной транзакции; строки, уже переведенные другой репликой, повторно не
+// обновляются.
+func (j *Job) RunOnce(ctx context.Context) (int, error) {
+	now := j.now().UTC()
+	monthStart := billing.MonthStart(billing.MonthIndex(now)).Format(billing.DateLayout)
+
+	var expired []models.Subscription
+	err := j.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&expired).
+			Clauses(clause.Returning{}).
+			Where("status IN ?", lifecycle.Sources(lifecycle.Expired)).
+			Where("end_date IS NOT NULL AND end_date < ?", monthStart).
+			Updates(map[string]any{
+				"status":     lifecycle.Expired,
+				"expired_at": now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		for _, sub := range expired {
+			if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionExpired, sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("expire subscriptions: %w", err)
+	}
+
+	if len(expired) > 0 {
+		logger.Log.WithField("count", len(expired)).Info("Subscriptions expired")
+	}
+	return len(expired), nil
+}
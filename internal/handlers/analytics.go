@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/nemopss/subscription-service/internal/analytics"
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// maxTopServices — наибольшее число сервисов в рейтинге расходов
+const maxTopServices = 50
+
+var (
+	errInvalidAnalyticsPeriod = apperr.Validation(
+		apperr.CodeInvalidAnalytics,
+		"start_date must not be after end_date and the period must not exceed 120 months",
+	)
+	errInvalidTopLimit = apperr.Validation(
+		apperr.CodeInvalidAnalytics,
+		"limit must be between 1 and 50",
+	)
+)
+
+// parseAnalyticsFilter разбирает период и пользователя запроса аналитики.
+// По умолчанию период — двенадцать месяцев по текущий включительно.
+func parseAnalyticsFilter(c *gin.Context) (analytics.Filter, error) {
+	var f analytics.Filter
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			return f, errInvalidUserID
+		}
+		f.UserID = &userID
+	}
+
+	var err error
+	if f.To, err = parseMonth(c.Query("end_date")); err != nil {
+		return f, errInvalidEndDate
+	}
+	f.From = billing.MonthStart(billing.MonthIndex(f.To) - 11)
+	if raw := c.Query("start_date"); raw != "" {
+		if f.From, err = parseMonth(raw); err != nil {
+			return f, errInvalidStartDate
+		}
+	}
+	if f.From.After(f.To) || f.Months() > analytics.MaxMonths {
+		return f, errInvalidAnalyticsPeriod
+	}
+	return f, nil
+}
+
+// @Summary      Spend trend
+// @Description  Returns spending per month over the period and the change against the previous month. Charges follow billing cycles, scheduled price changes and pauses; subscriptions without an end date run to the end of the period. For a user, subscriptions they own or share are counted with the user's share of each charge, as in /subscriptions/total.
+// @Tags         analytics
+// @Produce      json
+// @Param        user_id     query     string  false  "User ID (UUID), all users by default"
+// @Param        start_date  query     string  false  "First month (MM-YYYY), 11 months before end_date by default"
+// @Param        end_date    query     string  false  "Last month (MM-YYYY), the current month by default"
+// @Success      200         {object}  models.SpendTrend
+// @Failure      400         {object}  models.Problem
+// @Failure      500         {object}  models.Problem
+// @Failure      503         {object}  models.Problem
+// @Router       /analytics/spend [get]
+func GetSpendTrend(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Getting spend trend")
+	f, err := parseAnalyticsFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	trend, err := analytics.Spend(db.DB.WithContext(c.Request.Context()), f)
+	if err != nil {
+		log.WithError(err).Error("Failed to calculate spend trend")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, trend)
+}
+
+// @Summary      Top services by spend
+// @Description  Returns the services with the highest spending over the period. Subscriptions linked to the catalog are grouped by catalog service, the rest by service name. For a user, only the user's share of shared subscriptions is counted.
+// @Tags         analytics
+// @Produce      json
+// @Param        user_id     query     string  false  "User ID (UUID), all users by default"
+// @Param        start_date  query     string  false  "First month (MM-YYYY), 11 months before end_date by default"
+// @Param        end_date    query     string  false  "Last month (MM-YYYY), the current month by default"
+// @Param        limit       query     int     false  "Number of services, 5 by default, at most 50"
+// @Success      200         {object}  models.TopServices
+// @Failure      400         {object}  models.Problem
+// @Failure      500         {object}  models.Problem
+// @Failure      503         {object}  models.Problem
+// @Router       /analytics/top-services [get]
+func GetTopServices(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Getting top services")
+	f, err := parseAnalyticsFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	limit := 5
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxTopServices {
+			c.Error(errInvalidTopLimit)
+			return
+		}
+	}
+
+	top, err := analytics.Top(db.DB.WithContext(c.Request.Context()), f, limit)
+	if err != nil {
+		log.WithError(err).Error("Failed to calculate top services")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, top)
+}
+
+// @Summary      Average subscription price
+// @Description  Returns the average current price of subscriptions active in at least one month of the period, as charged and converted to a monthly price by billing cycle. For a user, shared subscriptions count with the user's share of the price.
+// @Tags         analytics
+// @Produce      json
+// @Param        user_id     query     string  false  "User ID (UUID), all users by default"
+// @Param        start_date  query     string  false  "First month (MM-YYYY), 11 months before end_date by default"
+// @Param        end_date    query     string  false  "Last month (MM-YYYY), the current month by default"
+// @Success      200         {object}  models.AveragePrice
+// @Failure      400         {object}  models.Problem
+// @Failure      500         {object}  models.Problem
+// @Failure      503         {object}  models.Problem
+// @Router       /analytics/average-price [get]
+func GetAveragePrice(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Getting average price")
+	f, err := parseAnalyticsFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	avg, err := analytics.Average(db.DB.WithContext(c.Request.Context()), f)
+	if err != nil {
+		log.WithError(err).Error("Failed to calculate average price")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, avg)
+}
+
+// @Summary      Churn
+// @Description  Returns per finished month of the period the number of subscriptions started and ended, the number active in the month, including those started or ended in it, and the share of those that ended. The current month and later months are not included, since end dates in them are scheduled ends rather than churn.
+// @Tags         analytics
+// @Produce      json
+// @Param        user_id     query     string  false  "User ID (UUID), all users by default"
+// @Param        start_date  query     string  false  "First month (MM-YYYY), 11 months before end_date by default"
+// @Param        end_date    query     string  false  "Last month (MM-YYYY), the current month by default"
+// @Success      200         {object}  models.ChurnReport
+// @Failure      400         {object}  models.Problem
+// @Failure      500         {object}  models.Problem
+// @Failure      503         {object}  models.Problem
+// @Router       /analytics/churn [get]
+func GetChurn(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Getting churn")
+	f, err := parseAnalyticsFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	report, err := analytics.Churn(db.DB.WithContext(c.Request.Context()), f)
+	if err != nil {
+		log.WithError(err).Error("Failed to calculate churn")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/budgets"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/sharing"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+var (
+	errBudgetNotFound = apperr.NotFound(
+		apperr.CodeBudgetNotFound,
+		"budget not found",
+	)
+	errInvalidBudgetID = apperr.Validation(
+		apperr.CodeInvalidBudget,
+		"invalid budget id",
+	)
+	errInvalidBudgetMonth = apperr.Validation(
+		apperr.CodeInvalidBudget,
+		"month must be in MM-YYYY format",
+	)
+	errDuplicateBudget = apperr.Conflict(
+		apperr.CodeConflict,
+		"the user already has a budget with this scope and target",
+	)
+)
+
+// checkBudgets проверяет бюджеты владельца и участников подписки после
+// ее изменения и оповещает о новых превышениях
+func checkBudgets(tx *gorm.DB, sub models.Subscription) error {
+	now := time.Now().UTC()
+	for _, userID := range sharing.Participants(sub) {
+		if _, err := budgets.Check(tx, userID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameTarget сообщает, относятся ли бюджеты к одной области и цели
+func sameTarget(a, b models.Budget) bool {
+	return a.Scope == b.Scope &&
+		equalIntPtr(a.CategoryID, b.CategoryID) &&
+		equalIntPtr(a.ServiceID, b.ServiceID)
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// saveBudget сохраняет бюджет, если у пользователя нет другого бюджета с
+// той же областью и целью
+func saveBudget(tx *gorm.DB, budget *models.Budget) error {
+	var existing []models.Budget
+	if err := tx.Where("user_id = ?", budget.UserID).Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.ID != budget.ID && sameTarget(other, *budget) {
+			return errDuplicateBudget
+		}
+	}
+	return tx.Save(budget).Error
+}
+
+// bindBudget разбирает адрес бюджета и проверяет бюджет из тела запроса
+func bindBudget(c *gin.Context) (uuid.UUID, models.CreateBudget, error) {
+	var req models.CreateBudget
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return userID, req, errInvalidUserID
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return userID, req, invalidBody(err)
+	}
+	if err := budgets.Validate(req); err != nil {
+		return userID, req, apperr.Validation(apperr.CodeInvalidBudget, err.Error())
+	}
+	return userID, req, nil
+}
+
+// budgetPath разбирает user_id и id бюджета из адреса запроса
+func budgetPath(c *gin.Context) (uuid.UUID, int, error) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		return userID, 0, errInvalidUserID
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return userID, 0, errInvalidBudgetID
+	}
+	return userID, id, nil
+}
+
+// @Summary      Create a budget
+// @Description  Sets a monthly spending limit for the user: overall, for a category with its subcategories, or for a catalog service. When a subscription change pushes the month's spend over a budget, a budget.exceeded event is published once per budget and month.
+// @Tags         budgets
+// @Accept       json
+// @Produce      json
+// @Param        user_id  path      string               true  "User ID (UUID)"
+// @Param        budget   body      models.CreateBudget  true  "Budget"
+// @Success      201      {object}  models.Budget
+// @Failure      400      {object}  models.Problem  "Invalid budget or unknown category or service"
+// @Failure      409      {object}  models.Problem  "Budget with this scope and target already exists"
+// @Failure      503      {object}  models.Problem
+// @Router       /users/{user_id}/budgets [post]
+func CreateBudget(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	userID, req, err := bindBudget(c)
+	if err != nil {
+		log.WithError(err).Error("Invalid budget")
+		c.Error(err)
+		return
+	}
+
+	budget := models.Budget{
+		UserID:     userID,
+		Scope:      req.Scope,
+		CategoryID: req.CategoryID,
+		ServiceID:  req.ServiceID,
+		Amount:     req.Amount,
+	}
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		return saveBudget(tx, &budget)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to create budget")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	log.WithField("budget_id", budget.ID).Info("Budget created")
+	c.JSON(http.StatusCreated, budget)
+}
+
+// @Summary      List budgets
+// @Tags         budgets
+// @Produce      json
+// @Param        user_id  path      string  true  "User ID (UUID)"
+// @Success      200      {array}   models.Budget
+// @Failure      400      {object}  models.Problem
+// @Failure      503      {object}  models.Problem
+// @Router       /users/{user_id}/budgets [get]
+func ListBudgets(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.Error(errInvalidUserID)
+		return
+	}
+	var list []models.Budget
+	err = db.DB.WithContext(c.Request.Context()).
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&list).Error
+	if err != nil {
+		log.WithError(err).Error("Failed to list budgets")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary      Update a budget
+// @Tags         budgets
+// @Accept       json
+// @Produce      json
+// @Param        user_id  path      string               true  "User ID (UUID)"
+// @Param        id       path      int                  true  "Budget ID"
+// @Param        budget   body      models.CreateBudget  true  "Budget"
+// @Success      200      {object}  models.Budget
+// @Failure      400      {object}  models.Problem
+// @Failure      404      {object}  models.Problem
+// @Failure      409      {object}  models.Problem  "Budget with this scope and target already exists"
+// @Failure      503      {object}  models.Problem
+// @Router       /users/{user_id}/budgets/{id} [put]
+func UpdateBudget(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	_, id, err := budgetPath(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userID, req, err := bindBudget(c)
+	if err != nil {
+		log.WithError(err).Error("Invalid budget")
+		c.Error(err)
+		return
+	}
+
+	var budget models.Budget
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).First(&budget, id).Error; err != nil {
+			return err
+		}
+		budget.Scope = req.Scope
+		budget.CategoryID = req.CategoryID
+		budget.ServiceID = req.ServiceID
+		budget.Amount = req.Amount
+		return saveBudget(tx, &budget)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to update budget")
+		c.Error(apperr.FromDB(err, errBudgetNotFound))
+		return
+	}
+
+	log.WithField("budget_id", budget.ID).Info("Budget updated")
+	c.JSON(http.StatusOK, budget)
+}
+
+// @Summary      Delete a budget
+// @Tags         budgets
+// @Param        user_id  path  string  true  "User ID (UUID)"
+// @Param        id       path  int     true  "Budget ID"
+// @Success      204
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /users/{user_id}/budgets/{id} [delete]
+func DeleteBudget(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	userID, id, err := budgetPath(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	result := db.DB.WithContext(c.Request.Context()).
+		Where("user_id = ?", userID).
+		Delete(&models.Budget{}, id)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Failed to delete budget")
+		c.Error(apperr.FromDB(result.Error, nil))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.Error(errBudgetNotFound)
+		return
+	}
+	log.WithField("budget_id", id).Info("Budget deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Budget vs. actual spend
+// @Description  Returns the user's spend for the month against each budget. Spend is computed like /subscriptions/total for that month; subscriptions without an end date are counted to the end of the month, so for the current month it is the projected spend.
+// @Tags         budgets
+// @Produce      json
+// @Param        user_id  path      string  true   "User ID (UUID)"
+// @Param        month    query     string  false  "Month in MM-YYYY format, the current month by default"
+// @Success      200      {object}  models.BudgetReport
+// @Failure      400      {object}  models.Problem
+// @Failure      503      {object}  models.Problem
+// @Router       /users/{user_id}/budgets/report [get]
+func GetBudgetReport(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.Error(errInvalidUserID)
+		return
+	}
+	month, err := parseMonth(c.Query("month"))
+	if err != nil {
+		c.Error(errInvalidBudgetMonth)
+		return
+	}
+
+	statuses, err := budgets.Report(db.DB.WithContext(c.Request.Context()), userID, month)
+	if err != nil {
+		log.WithError(err).Error("Failed to build budget report")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, models.BudgetReport{
+		Month:   month.Format("01-2006"),
+		Budgets: statuses,
+	})
+}
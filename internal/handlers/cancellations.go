@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/events"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
+	"github.com/nemopss/subscription-service/internal/metrics"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/webhooks"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// maxCancelReason — максимальная длина причины отмены в символах
+const maxCancelReason = 500
+
+var (
+	errCancelReasonTooLong = apperr.Validation(
+		apperr.CodeInvalidCancellation,
+		"cancellation reason must be at most 500 characters",
+	)
+	errAlreadyCancelled = apperr.Conflict(
+		apperr.CodeInvalidStatusTransition,
+		"subscription is already cancelled or expired",
+	)
+	errNotCancelled = apperr.Conflict(
+		apperr.CodeInvalidStatusTransition,
+		"subscription is not cancelled",
+	)
+	errCancellationOver = apperr.Conflict(
+		apperr.CodeInvalidStatusTransition,
+		"the cancelled subscription has already ended",
+	)
+)
+
+// clearCancellation снимает отметку об отмене
+func clearCancellation(sub *models.Subscription) {
+	sub.CancelledAt = nil
+	sub.CancelReason = ""
+	sub.CancelAtPeriodEnd = false
+	sub.EndDateBeforeCancel = nil
+}
+
+// immediateEnd возвращает последний месяц подписки, которая завершается
+// сейчас: текущий месяц, а для еще не начавшейся — месяц начала, чтобы
+// end_date не оказался раньше start_date
+func immediateEnd(start time.Time) time.Time {
+	end := currentMonth()
+	if start.After(end) {
+		end = billing.MonthStart(billing.MonthIndex(start))
+	}
+	return end
+}
+
+// endNow завершает подписку месяцем immediateEnd, если она не
+// заканчивается раньше. Вызывается при переходе в expired, чтобы
+// истекшая подписка не учитывалась в стоимости дальше.
+func endNow(sub *models.Subscription) error {
+	start, err := time.Parse(billing.DateLayout, sub.StartDate)
+	if err != nil {
+		return err
+	}
+	end := immediateEnd(start).Format(billing.DateLayout)
+	if sub.EndDate == nil || end < *sub.EndDate {
+		sub.EndDate = &end
+	}
+	return nil
+}
+
+// cancel отменяет подписку. Немедленная отмена завершает подписку сразу
+// (см. immediateEnd) и переводит ее в expired; отмена в конце периода
+// оставляет ее в cancelled до последнего оплаченного месяца. Более ранний
+// end_date сохраняется.
+func cancel(tx *gorm.DB, sub *models.Subscription, mode lifecycle.CancelMode, reason string, now time.Time) error {
+	start, err := time.Parse(billing.DateLayout, sub.StartDate)
+	if err != nil {
+		return err
+	}
+
+	end := immediateEnd(start)
+	to := lifecycle.Expired
+	if mode == lifecycle.CancelAtPeriodEnd {
+		end = billing.PeriodEnd(start, sub.BillingCycle, now)
+		to = lifecycle.Cancelled
+	}
+
+	prevEnd := sub.EndDate
+	if err := changeStatus(tx, sub, to); err != nil {
+		return err
+	}
+	endStr := end.Format(billing.DateLayout)
+	if prevEnd == nil || endStr < *prevEnd {
+		sub.EndDate = &endStr
+	}
+	sub.EndDateBeforeCancel = prevEnd
+	sub.CancelledAt = &now
+	sub.CancelReason = reason
+	sub.CancelAtPeriodEnd = mode == lifecycle.CancelAtPeriodEnd
+	return nil
+}
+
+// @Summary      Cancel a subscription
+// @Description  Cancels a subscription immediately (it becomes expired) or at the end of the current billing period (it stays cancelled until then and can be uncancelled). The reason and time of cancellation are stored.
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                         true   "Subscription ID"
+// @Param        cancel  body      models.CancelSubscription  false  "Cancellation mode and reason"
+// @Success      200     {object}  models.Subscription
+// @Failure      400     {object}  models.Problem  "Invalid ID, mode or reason"
+// @Failure      404     {object}  models.Problem  "Subscription not found"
+// @Failure      409     {object}  models.Problem  "Subscription is already cancelled or expired"
+// @Failure      500     {object}  models.Problem
+// @Failure      503     {object}  models.Problem
+// @Router       /subscriptions/{id}/cancel [post]
+func CancelSubscription(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Cancelling subscription")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
+		return
+	}
+
+	var req models.CancelSubscription
+	if err := bindOptionalJSON(c, &req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+	mode, err := lifecycle.ParseCancelMode(req.Mode)
+	if err != nil {
+		log.WithError(err).Error("Invalid cancellation mode")
+		c.Error(apperr.Validation(apperr.CodeInvalidCancellation, err.Error()))
+		return
+	}
+	if utf8.RuneCountInString(req.Reason) > maxCancelReason {
+		c.Error(errCancelReasonTooLong)
+		return
+	}
+
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		if sub.Status == lifecycle.Cancelled || sub.Status == lifecycle.Expired {
+			return errAlreadyCancelled
+		}
+		if err := cancel(tx, &sub, mode, req.Reason, time.Now().UTC()); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(&sub).Error; err != nil {
+			return err
+		}
+		return events.SubscriptionChanged(tx, webhooks.EventSubscriptionCancelled, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to cancel subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
+		return
+	}
+
+	metrics.SubscriptionsCancelled.WithLabelValues(string(mode)).Inc()
+	log.WithField("id", sub.ID).WithField("mode", mode).Info("Subscription cancelled")
+	c.JSON(http.StatusOK, sub)
+}
+
+// @Summary      Uncancel a subscription
+// @Description  Reverts a cancellation while the cancelled subscription is still running: the subscription becomes active and gets its previous end date back.
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id   path      int  true  "Subscription ID"
+// @Success      200  {object}  models.Subscription
+// @Failure      400  {object}  models.Problem  "Invalid ID"
+// @Failure      404  {object}  models.Problem  "Subscription not found"
+// @Failure      409  {object}  models.Problem  "Subscription is not cancelled or has already ended"
+// @Failure      500  {object}  models.Problem
+// @Failure      503  {object}  models.Problem
+// @Router       /subscriptions/{id}/uncancel [post]
+func UncancelSubscription(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Uncancelling subscription")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
+		return
+	}
+
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		if sub.Status != lifecycle.Cancelled {
+			return errNotCancelled
+		}
+		// Подписка действует по месяц end_date включительно; задача
+		// истечения могла еще не перевести ее в expired
+		if sub.EndDate != nil && *sub.EndDate < currentMonth().Format(billing.DateLayout) {
+			return errCancellationOver
+		}
+		endDate := sub.EndDateBeforeCancel
+		if err := changeStatus(tx, &sub, lifecycle.Active); err != nil {
+			return err
+		}
+		sub.EndDate = endDate
+		if err := tx.Omit(clause.Associations).Save(&sub).Error; err != nil {
+			return err
+		}
+		if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionUncancelled, sub); err != nil {
+			return err
+		}
+		return checkBudgets(tx, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to uncancel subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
+		return
+	}
+
+	log.WithField("id", sub.ID).Info("Subscription uncancelled")
+	c.JSON(http.StatusOK, sub)
+}
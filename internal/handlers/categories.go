@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/taxonomy"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+var (
+	errCategoryNotFound = apperr.NotFound(
+		apperr.CodeCategoryNotFound,
+		"category not found",
+	)
+	errInvalidCategoryID = apperr.Validation(
+		apperr.CodeInvalidCategory,
+		"invalid category_id",
+	)
+	errInvalidCategoryParent = apperr.Validation(
+		apperr.CodeInvalidCategory,
+		"parent_id must be an existing category outside the category's subtree",
+	)
+	errInvalidCategoryName = apperr.Validation(
+		apperr.CodeInvalidCategory,
+		"name is required",
+	)
+)
+
+// loadCategoryTree загружает все категории
+func loadCategoryTree(tx *gorm.DB) (*taxonomy.Tree, error) {
+	var categories []models.Category
+	if err := tx.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return taxonomy.NewTree(categories), nil
+}
+
+// normalizeTags проверяет теги подписки
+func normalizeTags(tags []string) ([]string, error) {
+	tags, err := taxonomy.NormalizeTags(tags)
+	if err != nil {
+		return nil, apperr.Validation(apperr.CodeInvalidTags, err.Error())
+	}
+	return tags, nil
+}
+
+// filterByTaxonomy применяет фильтры category_id (с подкатегориями) и tag
+// (подписки со всеми перечисленными через запятую тегами)
+func filterByTaxonomy(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if raw := c.Query("category_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, errInvalidCategoryID
+		}
+		tree, err := loadCategoryTree(db.DB.WithContext(c.Request.Context()))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tree.Get(id); !ok {
+			return nil, errCategoryNotFound
+		}
+		query = query.Where("category_id IN ?", tree.Subtree(id))
+	}
+
+	if raw := c.Query("tag"); raw != "" {
+		tags, err := normalizeTags(strings.Split(raw, ","))
+		if err != nil {
+			return nil, err
+		}
+		if len(tags) > 0 {
+			data, _ := json.Marshal(tags)
+			query = query.Where("tags @> ?::jsonb", string(data))
+		}
+	}
+	return query, nil
+}
+
+// bindCategory разбирает категорию из тела запроса
+func bindCategory(c *gin.Context) (models.CreateCategory, error) {
+	var req models.CreateCategory
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return req, invalidBody(err)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return req, errInvalidCategoryName
+	}
+	return req, nil
+}
+
+// @Summary      Create a category
+// @Description  Creates a category, optionally nested under a parent category
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        category  body      models.CreateCategory  true  "Category"
+// @Success      201       {object}  models.Category
+// @Failure      400       {object}  models.Problem
+// @Failure      503       {object}  models.Problem
+// @Router       /categories [post]
+func CreateCategory(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	req, err := bindCategory(c)
+	if err != nil {
+		log.WithError(err).Error("Invalid category")
+		c.Error(err)
+		return
+	}
+
+	category := models.Category{Name: req.Name, ParentID: req.ParentID}
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		tree, err := loadCategoryTree(tx)
+		if err != nil {
+			return err
+		}
+		if !tree.CanMove(0, req.ParentID) {
+			return errInvalidCategoryParent
+		}
+		return tx.Create(&category).Error
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to create category")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	log.WithField("category_id", category.ID).Info("Category created")
+	c.JSON(http.StatusCreated, category)
+}
+
+// @Summary      List categories
+// @Description  Returns all categories as a flat list; the hierarchy is defined by parent_id
+// @Tags         categories
+// @Produce      json
+// @Success      200  {array}   models.Category
+// @Failure      503  {object}  models.Problem
+// @Router       /categories [get]
+func ListCategories(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	var categories []models.Category
+	if err := db.DB.WithContext(c.Request.Context()).Order("id").Find(&categories).Error; err != nil {
+		log.WithError(err).Error("Failed to list categories")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, categories)
+}
+
+// @Summary      Get a category
+// @Tags         categories
+// @Produce      json
+// @Param        id   path      int  true  "Category ID"
+// @Success      200  {object}  models.Category
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /categories/{id} [get]
+func GetCategory(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidCategoryID)
+		return
+	}
+	var category models.Category
+	if err := db.DB.WithContext(c.Request.Context()).First(&category, id).Error; err != nil {
+		log.WithError(err).Error("Failed to get category")
+		c.Error(apperr.FromDB(err, errCategoryNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+// @Summary      Update a category
+// @Description  Renames a category or moves it under another parent. A category cannot be moved into its own subtree.
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int                    true  "Category ID"
+// @Param        category  body      models.CreateCategory  true  "Category"
+// @Success      200       {object}  models.Category
+// @Failure      400       {object}  models.Problem
+// @Failure      404       {object}  models.Problem
+// @Failure      503       {object}  models.Problem
+// @Router       /categories/{id} [put]
+func UpdateCategory(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidCategoryID)
+		return
+	}
+	req, err := bindCategory(c)
+	if err != nil {
+		log.WithError(err).Error("Invalid category")
+		c.Error(err)
+		return
+	}
+
+	var category models.Category
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&category, id).Error; err != nil {
+			return err
+		}
+		tree, err := loadCategoryTree(tx)
+		if err != nil {
+			return err
+		}
+		if !tree.CanMove(id, req.ParentID) {
+			return errInvalidCategoryParent
+		}
+		category.Name = req.Name
+		category.ParentID = req.ParentID
+		return tx.Save(&category).Error
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to update category")
+		c.Error(apperr.FromDB(err, errCategoryNotFound))
+		return
+	}
+
+	log.WithField("category_id", category.ID).Info("Category updated")
+	c.JSON(http.StatusOK, category)
+}
+
+// @Summary      Delete a category
+// @Description  Deletes a category. Its subcategories move to its parent; its subscriptions become uncategorized.
+// @Tags         categories
+// @Param        id   path  int  true  "Category ID"
+// @Success      204
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /categories/{id} [delete]
+func DeleteCategory(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidCategoryID)
+		return
+	}
+
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.First(&category, id).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Category{}).
+			Where("parent_id = ?", id).
+			Update("parent_id", category.ParentID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to delete category")
+		c.Error(apperr.FromDB(err, errCategoryNotFound))
+		return
+	}
+	log.WithField("category_id", id).Info("Category deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// Способы группировки стоимости
+const (
+	groupByCategory = "category"
+	groupByTag      = "tag"
+)
+
+// costGroups накапливает стоимость подписок по категориям верхнего уровня
+// или по тегам. Подписка с несколькими тегами учитывается в каждом из них.
+type costGroups struct {
+	by     string
+	tree   *taxonomy.Tree
+	groups map[string]*models.CostGroup
+}
+
+func newCostGroups(by string, tree *taxonomy.Tree) *costGroups {
+	return &costGroups{by: by, tree: tree, groups: map[string]*models.CostGroup{}}
+}
+
+func (g *costGroups) add(sub models.Subscription, cost int) {
+	if g == nil {
+		return
+	}
+	switch g.by {
+	case groupByCategory:
+		if sub.CategoryID != nil {
+			if root, ok := g.tree.Root(*sub.CategoryID); ok {
+				g.group("category:"+strconv.Itoa(root.ID), root.Name, &root.ID).Total += cost
+				return
+			}
+		}
+		g.group("", "", nil).Total += cost
+	case groupByTag:
+		if len(sub.Tags) == 0 {
+			g.group("", "", nil).Total += cost
+		}
+		for _, tag := range sub.Tags {
+			g.group("tag:"+tag, tag, nil).Total += cost
+		}
+	}
+}
+
+func (g *costGroups) group(id, key string, categoryID *int) *models.CostGroup {
+	group, ok := g.groups[id]
+	if !ok {
+		group = &models.CostGroup{Key: key, CategoryID: categoryID}
+		g.groups[id] = group
+	}
+	return group
+}
+
+// list возвращает группы по убыванию стоимости
+func (g *costGroups) list() []models.CostGroup {
+	if g == nil {
+		return nil
+	}
+	out := make([]models.CostGroup, 0, len(g.groups))
+	for _, group := range g.groups {
+		out = append(out, *group)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
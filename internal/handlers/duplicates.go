@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/duplicates"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// warnPossibleDuplicate — код предупреждения о возможном дубле
+const warnPossibleDuplicate = "possible_duplicate"
+
+var errDuplicatesUserRequired = apperr.Validation(
+	apperr.CodeInvalidUserID,
+	"user_id is required",
+)
+
+// duplicateWarnings ищет подписки пользователя, дублирующие sub, и
+// возвращает предупреждение о них
+func duplicateWarnings(tx *gorm.DB, sub models.Subscription) ([]models.Warning, error) {
+	var others []models.Subscription
+	err := tx.Where("user_id = ? AND id <> ?", sub.UserID, sub.ID).Find(&others).Error
+	if err != nil {
+		return nil, err
+	}
+	var services []models.Service
+	if err := tx.Find(&services).Error; err != nil {
+		return nil, err
+	}
+
+	dups := duplicates.Of(sub, others, services)
+	if len(dups) == 0 {
+		return nil, nil
+	}
+	ids := make([]int, 0, len(dups))
+	for _, d := range dups {
+		ids = append(ids, d.ID)
+	}
+	return []models.Warning{{
+		Code:            warnPossibleDuplicate,
+		Message:         "the user already has a subscription to this service for an overlapping period",
+		SubscriptionIDs: ids,
+	}}, nil
+}
+
+// @Summary      Find duplicate subscriptions
+// @Description  Groups subscriptions of the user to the same service (by catalog link, name or alias) whose periods overlap
+// @Tags         subscriptions
+// @Produce      json
+// @Param        user_id  query     string  true   "User ID (UUID)"
+// @Success      200      {array}   models.DuplicateGroup
+// @Failure      400      {object}  models.Problem
+// @Failure      500      {object}  models.Problem
+// @Failure      503      {object}  models.Problem
+// @Router       /subscriptions/duplicates [get]
+func ListDuplicates(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Listing duplicate subscriptions")
+
+	// Подписки загружаются и группируются в памяти, поэтому только для
+	// одного пользователя, а не для всего арендатора
+	raw := c.Query("user_id")
+	if raw == "" {
+		c.Error(errDuplicatesUserRequired)
+		return
+	}
+	userID, err := uuid.Parse(raw)
+	if err != nil {
+		c.Error(errInvalidUserID)
+		return
+	}
+
+	tx := db.DB.WithContext(c.Request.Context())
+	var subs []models.Subscription
+	if err := tx.Where("user_id = ?", userID).Order("id").Find(&subs).Error; err != nil {
+		log.WithError(err).Error("Failed to fetch subscriptions")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	var services []models.Service
+	if err := tx.Find(&services).Error; err != nil {
+		log.WithError(err).Error("Failed to fetch catalog")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	groups := duplicates.Detect(subs, services)
+	if groups == nil {
+		groups = []models.DuplicateGroup{}
+	}
+	c.JSON(http.StatusOK, groups)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/costs"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/sharing"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// maxForecastMonths — наибольшая длина прогноза
+const maxForecastMonths = 60
+
+var errInvalidForecastMonths = apperr.Validation(
+	apperr.CodeInvalidForecast,
+	"months must be between 1 and 60",
+)
+
+// @Summary      Forecast spending
+// @Description  Projects the user's charges month by month, starting with the current month by default. Subscriptions without an end date run to the end of the forecast; billing cycles, scheduled price changes, end dates and ongoing pauses are taken into account. Expired subscriptions are excluded. Shared subscriptions count with the user's share.
+// @Tags         subscriptions
+// @Produce      json
+// @Param        user_id       query     string  true   "User ID (UUID)"
+// @Param        months        query     int     false  "Number of months, 12 by default, at most 60"
+// @Param        from          query     string  false  "First month (MM-YYYY), the current month by default"
+// @Param        service_name  query     string  false  "Service name filter, matched against catalog names and aliases"
+// @Param        service_id    query     int     false  "Catalog service ID filter"
+// @Param        category_id   query     int     false  "Category ID filter, subcategories included"
+// @Param        tag           query     string  false  "Comma-separated tags, all must be present"
+// @Success      200           {object}  models.ForecastResponse
+// @Failure      400           {object}  models.Problem
+// @Failure      500           {object}  models.Problem
+// @Failure      503           {object}  models.Problem
+// @Router       /subscriptions/forecast [get]
+func GetForecast(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	userID, err := uuid.Parse(c.Query("user_id"))
+	if err != nil {
+		c.Error(errInvalidUserID)
+		return
+	}
+
+	months := 12
+	if raw := c.Query("months"); raw != "" {
+		months, err = strconv.Atoi(raw)
+		if err != nil || months < 1 || months > maxForecastMonths {
+			c.Error(errInvalidForecastMonths)
+			return
+		}
+	}
+	from, err := parseMonth(c.Query("from"))
+	if err != nil {
+		c.Error(errInvalidStartDate)
+		return
+	}
+
+	query := sharing.ForUser(costs.Preload(db.DB.WithContext(c.Request.Context())), userID).
+		Where("status <> ?", lifecycle.Expired)
+	if query, err = filterByService(c, query); err == nil {
+		query, err = filterByTaxonomy(c, query)
+	}
+	if err != nil {
+		log.WithError(err).Error("Invalid forecast filter")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	var subs []models.Subscription
+	if err := query.Find(&subs).Error; err != nil {
+		log.WithError(err).Error("Failed to fetch subscriptions")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	series, total := costs.Forecast(subs, userID, from, months)
+	c.JSON(http.StatusOK, models.ForecastResponse{
+		From:   from.Format("01-2006"),
+		Total:  total,
+		Months: series,
+	})
+}
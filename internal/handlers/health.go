@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nemopss/subscription-service/internal/health"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// Liveness сообщает, что процесс жив и обрабатывает запросы
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// Readiness выполняет проверки готовности и возвращает 503, если хотя бы
+// одна зависимость недоступна
+func Readiness(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checker.Run(c.Request.Context())
+		if report.Status != health.StatusOK {
+			logger.FromContext(c.Request.Context()).
+				WithField("checks", report.Checks).
+				Warn("Readiness check failed")
+			c.JSON(http.StatusServiceUnavailable, report)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/costs"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/orgs"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+var (
+	errOrganizationNotFound = apperr.NotFound(
+		apperr.CodeOrganizationNotFound,
+		"organization not found",
+	)
+	errMemberNotFound = apperr.NotFound(
+		apperr.CodeMemberNotFound,
+		"member not found",
+	)
+	errInvalidOrganizationID = apperr.Validation(
+		apperr.CodeInvalidOrganization,
+		"invalid organization id",
+	)
+	errInvalidOrganizationName = apperr.Validation(
+		apperr.CodeInvalidOrganization,
+		"name is required",
+	)
+	errLastOwner = apperr.Conflict(
+		apperr.CodeConflict,
+		"an organization must keep at least one owner",
+	)
+)
+
+// organizationID разбирает идентификатор организации из адреса запроса
+func organizationID(c *gin.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, errInvalidOrganizationID
+	}
+	return id, nil
+}
+
+// loadOrganization загружает организацию с участниками
+func loadOrganization(tx *gorm.DB, id int) (models.Organization, error) {
+	var org models.Organization
+	err := tx.Preload("Members", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("created_at, user_id")
+	}).First(&org, id).Error
+	return org, err
+}
+
+// memberSubscriptions ограничивает запрос подписками, которыми участники
+// организации владеют или в которых участвуют
+func memberSubscriptions(tx *gorm.DB, org models.Organization) *gorm.DB {
+	userIDs := orgs.UserIDs(org.Members)
+	shared := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&models.Share{}).
+		Select("subscription_id").
+		Where("user_id IN ?", userIDs)
+	return tx.Where("(user_id IN ? OR id IN (?))", userIDs, shared)
+}
+
+// @Summary      Create an organization
+// @Description  Creates an organization or a household; the owner_id user becomes its first owner
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization  body      models.CreateOrganization  true  "Organization"
+// @Success      201           {object}  models.Organization
+// @Failure      400           {object}  models.Problem
+// @Failure      503           {object}  models.Problem
+// @Router       /organizations [post]
+func CreateOrganization(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	var req models.CreateOrganization
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.Error(errInvalidOrganizationName)
+		return
+	}
+	kind, err := orgs.ParseKind(req.Kind)
+	if err != nil {
+		c.Error(apperr.Validation(apperr.CodeInvalidOrganization, err.Error()))
+		return
+	}
+	if req.OwnerID == uuid.Nil {
+		c.Error(errInvalidUserID)
+		return
+	}
+
+	org := models.Organization{
+		Name:    req.Name,
+		Kind:    kind,
+		Members: []models.Member{{UserID: req.OwnerID, Role: orgs.RoleOwner}},
+	}
+	if err := db.DB.WithContext(c.Request.Context()).Create(&org).Error; err != nil {
+		log.WithError(err).Error("Failed to create organization")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	log.WithField("organization_id", org.ID).Info("Organization created")
+	c.JSON(http.StatusCreated, org)
+}
+
+// @Summary      List organizations
+// @Description  Returns organizations and households, optionally only those the user belongs to
+// @Tags         organizations
+// @Produce      json
+// @Param        user_id  query     string  false  "Member user ID (UUID)"
+// @Success      200      {array}   models.Organization
+// @Failure      400      {object}  models.Problem
+// @Failure      503      {object}  models.Problem
+// @Router       /organizations [get]
+func ListOrganizations(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	tx := db.DB.WithContext(c.Request.Context())
+	query := tx.Order("id")
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			c.Error(errInvalidUserID)
+			return
+		}
+		members := tx.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Member{}).
+			Select("organization_id").
+			Where("user_id = ?", userID)
+		query = query.Where("id IN (?)", members)
+	}
+
+	var list []models.Organization
+	if err := query.Find(&list).Error; err != nil {
+		log.WithError(err).Error("Failed to list organizations")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary      Get an organization
+// @Description  Returns the organization with its members
+// @Tags         organizations
+// @Produce      json
+// @Param        id   path      int  true  "Organization ID"
+// @Success      200  {object}  models.Organization
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /organizations/{id} [get]
+func GetOrganization(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := organizationID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	org, err := loadOrganization(db.DB.WithContext(c.Request.Context()), id)
+	if err != nil {
+		log.WithError(err).Error("Failed to get organization")
+		c.Error(apperr.FromDB(err, errOrganizationNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// @Summary      Update an organization
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        id            path      int                        true  "Organization ID"
+// @Param        organization  body      models.UpdateOrganization  true  "Organization"
+// @Success      200           {object}  models.Organization
+// @Failure      400           {object}  models.Problem
+// @Failure      404           {object}  models.Problem
+// @Failure      503           {object}  models.Problem
+// @Router       /organizations/{id} [put]
+func UpdateOrganization(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := organizationID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var req models.UpdateOrganization
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.Error(errInvalidOrganizationName)
+		return
+	}
+	kind, err := orgs.ParseKind(req.Kind)
+	if err != nil {
+		c.Error(apperr.Validation(apperr.CodeInvalidOrganization, err.Error()))
+		return
+	}
+
+	var org models.Organization
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if org, err = loadOrganization(tx, id); err != nil {
+			return err
+		}
+		org.Name = req.Name
+		org.Kind = kind
+		return tx.Omit("Members").Save(&org).Error
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to update organization")
+		c.Error(apperr.FromDB(err, errOrganizationNotFound))
+		return
+	}
+
+	log.WithField("organization_id", org.ID).Info("Organization updated")
+	c.JSON(http.StatusOK, org)
+}
+
+// @Summary      Delete an organization
+// @Description  Deletes the organization and its memberships; the members' subscriptions are kept
+// @Tags         organizations
+// @Param        id   path  int  true  "Organization ID"
+// @Success      204
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /organizations/{id} [delete]
+func DeleteOrganization(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := organizationID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	result := db.DB.WithContext(c.Request.Context()).Delete(&models.Organization{}, id)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Failed to delete organization")
+		c.Error(apperr.FromDB(result.Error, nil))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.Error(errOrganizationNotFound)
+		return
+	}
+	log.WithField("organization_id", id).Info("Organization deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Add a member or change a role
+// @Description  Adds the user to the organization or changes their role (member by default). The last owner cannot be demoted.
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                  true   "Organization ID"
+// @Param        user_id  path      string               true   "User ID (UUID)"
+// @Param        member   body      models.UpdateMember  false  "Role"
+// @Success      200      {object}  models.Organization
+// @Failure      400      {object}  models.Problem
+// @Failure      404      {object}  models.Problem
+// @Failure      409      {object}  models.Problem  "The organization would have no owner"
+// @Failure      503      {object}  models.Problem
+// @Router       /organizations/{id}/members/{user_id} [put]
+func PutMember(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := organizationID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.Error(errInvalidUserID)
+		return
+	}
+	var req models.UpdateMember
+	if err := bindOptionalJSON(c, &req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+	role, err := orgs.ParseRole(req.Role)
+	if err != nil {
+		c.Error(apperr.Validation(apperr.CodeInvalidOrganization, err.Error()))
+		return
+	}
+
+	var org models.Organization
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if org, err = loadOrganization(tx, id); err != nil {
+			return err
+		}
+		if !orgs.KeepsOwner(org.Members, userID, role) {
+			return errLastOwner
+		}
+		member := models.Member{OrganizationID: id, UserID: userID, Role: role}
+		err := tx.Where(models.Member{OrganizationID: id, UserID: userID}).
+			Assign(models.Member{Role: role}).
+			FirstOrCreate(&member).Error
+		if err != nil {
+			return err
+		}
+		org, err = loadOrganization(tx, id)
+		return err
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to update member")
+		c.Error(apperr.FromDB(err, errOrganizationNotFound))
+		return
+	}
+
+	log.WithField("organization_id", id).WithField("user_id", userID).Info("Member updated")
+	c.JSON(http.StatusOK, org)
+}
+
+// @Summary      Remove a member
+// @Description  Removes the user from the organization. The last owner cannot be removed.
+// @Tags         organizations
+// @Param        id       path  int     true  "Organization ID"
+// @Param        user_id  path  string  true  "User ID (UUID)"
+// @Success      204
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Failure      409  {object}  models.Problem  "The organization would have no owner"
+// @Failure      503  {object}  models.Problem
+// @Router       /organizations/{id}/members/{user_id} [delete]
+func DeleteMember(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := organizationID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.Error(errInvalidUserID)
+		return
+	}
+
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		org, err := loadOrganization(tx, id)
+		if err != nil {
+			return err
+		}
+		if !orgs.KeepsOwner(org.Members, userID, "") {
+			return errLastOwner
+		}
+		result := tx.Where("organization_id = ? AND user_id = ?", id, userID).Delete(&models.Member{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errMemberNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to remove member")
+		c.Error(apperr.FromDB(err, errOrganizationNotFound))
+		return
+	}
+
+	log.WithField("organization_id", id).WithField("user_id", userID).Info("Member removed")
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      List the organization's subscriptions
+// @Description  Returns subscriptions owned by or shared with the organization's members
+// @Tags         organizations
+// @Produce      json
+// @Param        id           path      int     true   "Organization ID"
+// @Param        status       query     string  false  "Comma-separated statuses"
+// @Param        category_id  query     int     false  "Category ID filter, subcategories included"
+// @Param        tag          query     string  false  "Comma-separated tags, all must be present"
+// @Success      200          {array}   models.Subscription
+// @Failure      400          {object}  models.Problem
+// @Failure      404          {object}  models.Problem
+// @Failure      503          {object}  models.Problem
+// @Router       /organizations/{id}/subscriptions [get]
+func ListOrganizationSubscriptions(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := organizationID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	tx := db.DB.WithContext(c.Request.Context())
+	org, err := loadOrganization(tx, id)
+	if err != nil {
+		log.WithError(err).Error("Failed to get organization")
+		c.Error(apperr.FromDB(err, errOrganizationNotFound))
+		return
+	}
+
+	query := memberSubscriptions(costs.Preload(tx), org).Order("id")
+	if raw := c.Query("status"); raw != "" {
+		statuses, err := lifecycle.ParseStatuses(raw)
+		if err != nil {
+			c.Error(invalidStatus(err))
+			return
+		}
+		query = query.Where("status IN ?", statuses)
+	}
+	if query, err = filterByTaxonomy(c, query); err != nil {
+		log.WithError(err).Error("Invalid category or tag filter")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	var subs []models.Subscription
+	if err := query.Find(&subs).Error; err != nil {
+		log.WithError(err).Error("Failed to list subscriptions")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, subs)
+}
+
+// @Summary      Organization total cost
+// @Description  Calculates the members' spend over the period like /subscriptions/total, in total, by member and by service. Each member counts with their share of shared subscriptions, so a subscription shared inside the organization is counted once.
+// @Tags         organizations
+// @Produce      json
+// @Param        id            path      int     true   "Organization ID"
+// @Param        start_date    query     string  false  "Start date (MM-YYYY)"
+// @Param        end_date      query     string  false  "End date (MM-YYYY)"
+// @Param        service_name  query     string  false  "Service name filter, matched against catalog names and aliases"
+// @Param        service_id    query     int     false  "Catalog service ID filter"
+// @Param        category_id   query     int     false  "Category ID filter, subcategories included"
+// @Param        tag           query     string  false  "Comma-separated tags, all must be present"
+// @Success      200           {object}  models.OrganizationTotal
+// @Failure      400           {object}  models.Problem
+// @Failure      404           {object}  models.Problem
+// @Failure      503           {object}  models.Problem
+// @Router       /organizations/{id}/total [get]
+func GetOrganizationTotal(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := organizationID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	tx := db.DB.WithContext(c.Request.Context())
+	org, err := loadOrganization(tx, id)
+	if err != nil {
+		log.WithError(err).Error("Failed to get organization")
+		c.Error(apperr.FromDB(err, errOrganizationNotFound))
+		return
+	}
+
+	query := memberSubscriptions(costs.Preload(tx).Preload("Service"), org)
+	if query, err = filterByService(c, query); err == nil {
+		query, err = filterByTaxonomy(c, query)
+	}
+	if err != nil {
+		log.WithError(err).Error("Invalid service, category or tag filter")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	var subs []models.Subscription
+	if err := query.Find(&subs).Error; err != nil {
+		log.WithError(err).Error("Failed to fetch subscriptions")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, orgs.Total(org.Members, subs, from, to, time.Now()))
+}
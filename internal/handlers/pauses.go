@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/costs"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/events"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/webhooks"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+var (
+	errInvalidResumeDate = apperr.Validation(
+		apperr.CodeInvalidPause,
+		"invalid resume_date format, expected MM-YYYY",
+	)
+	errAlreadyPaused = apperr.Conflict(
+		apperr.CodeInvalidStatusTransition,
+		"subscription is already paused",
+	)
+	errNotPaused = apperr.Conflict(
+		apperr.CodeInvalidStatusTransition,
+		"subscription is not paused",
+	)
+	errFuturePause = apperr.Validation(
+		apperr.CodeInvalidPause,
+		"pause cannot start after the current month",
+	)
+)
+
+// currentMonth возвращает первый день текущего месяца
+func currentMonth() time.Time {
+	return billing.MonthStart(billing.MonthIndex(time.Now().UTC()))
+}
+
+// parseMonth разбирает месяц в формате MM-YYYY; пустая строка означает
+// текущий месяц
+func parseMonth(raw string) (time.Time, error) {
+	if raw == "" {
+		return currentMonth(), nil
+	}
+	return time.Parse("01-2006", raw)
+}
+
+// openPause приостанавливает подписку с месяца from. Приостановка не может
+// начинаться раньше подписки и раньше конца предыдущей приостановки.
+// Подписка должна быть загружена вместе с приостановками.
+func openPause(tx *gorm.DB, sub *models.Subscription, from time.Time) error {
+	start := from.Format(billing.DateLayout)
+	if start < sub.StartDate {
+		return apperr.Validation(
+			apperr.CodeInvalidPause,
+			"pause cannot start before the subscription",
+		)
+	}
+	if n := len(sub.Pauses); n > 0 {
+		last := sub.Pauses[n-1]
+		if last.EndDate == nil || start < *last.EndDate {
+			return apperr.Validation(
+				apperr.CodeInvalidPause,
+				"pause cannot overlap the previous pause",
+			)
+		}
+	}
+
+	pause := models.Pause{SubscriptionID: sub.ID, StartDate: start}
+	if err := tx.Create(&pause).Error; err != nil {
+		return err
+	}
+	sub.Pauses = append(sub.Pauses, pause)
+	return nil
+}
+
+// closePause завершает открытую приостановку: месяц to снова
+// оплачивается. Если to не позже начала приостановки, она не
+// действовала ни месяца и удаляется.
+func closePause(tx *gorm.DB, sub *models.Subscription, to time.Time) error {
+	n := len(sub.Pauses)
+	if n == 0 || sub.Pauses[n-1].EndDate != nil {
+		return nil
+	}
+	pause := &sub.Pauses[n-1]
+	end := to.Format(billing.DateLayout)
+	if end <= pause.StartDate {
+		sub.Pauses = sub.Pauses[:n-1]
+		return tx.Delete(&models.Pause{}, pause.ID).Error
+	}
+	pause.EndDate = &end
+	return tx.Model(pause).Update("end_date", end).Error
+}
+
+// changeStatus переводит подписку в новое состояние и закрывает
+// приостановку текущим месяцем, если подписка выходит из нее. Возврат
+// отмененной подписки в active снимает отметку об отмене. Приостановка
+// открывается только через PauseSubscription.
+func changeStatus(tx *gorm.DB, sub *models.Subscription, to lifecycle.Status) error {
+	from := sub.Status
+	if err := setStatus(sub, to); err != nil {
+		return err
+	}
+	switch {
+	case from == lifecycle.Paused && to != lifecycle.Paused:
+		return closePause(tx, sub, currentMonth())
+	case from == lifecycle.Cancelled && to == lifecycle.Active:
+		clearCancellation(sub)
+	}
+	return nil
+}
+
+// loadForUpdate загружает подписку с приостановками и изменениями цены и
+// блокирует ее до конца транзакции
+func loadForUpdate(tx *gorm.DB, id int) (models.Subscription, error) {
+	var sub models.Subscription
+	err := costs.Preload(tx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&sub, id).Error
+	return sub, err
+}
+
+// bindOptionalJSON разбирает тело запроса, если оно передано
+func bindOptionalJSON(c *gin.Context, obj any) error {
+	err := c.ShouldBindJSON(obj)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// @Summary      Pause a subscription
+// @Description  Pauses billing from the given month (the current month by default, not later than the current month). Paused months are excluded from the total cost.
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                        true   "Subscription ID"
+// @Param        pause  body      models.PauseSubscription  false  "Pause start"
+// @Success      200    {object}  models.Subscription
+// @Failure      400    {object}  models.Problem  "Invalid ID, future or overlapping pause"
+// @Failure      404    {object}  models.Problem  "Subscription not found"
+// @Failure      409    {object}  models.Problem  "Subscription cannot be paused"
+// @Failure      500    {object}  models.Problem
+// @Failure      503    {object}  models.Problem
+// @Router       /subscriptions/{id}/pause [post]
+func PauseSubscription(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Pausing subscription")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
+		return
+	}
+
+	var req models.PauseSubscription
+	if err := bindOptionalJSON(c, &req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+	from, err := parseMonth(req.StartDate)
+	if err != nil {
+		log.WithError(err).Error("Invalid start_date format")
+		c.Error(errInvalidStartDate)
+		return
+	}
+	// Состояние paused действует сразу: приостановка с будущего месяца
+	// исключила бы подписку из напоминаний и метрик, пока она оплачивается
+	if from.After(currentMonth()) {
+		c.Error(errFuturePause)
+		return
+	}
+
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		if sub.Status == lifecycle.Paused {
+			return errAlreadyPaused
+		}
+		if err := setStatus(&sub, lifecycle.Paused); err != nil {
+			return err
+		}
+		if err := openPause(tx, &sub, from); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(&sub).Error; err != nil {
+			return err
+		}
+		return events.SubscriptionChanged(tx, webhooks.EventSubscriptionPaused, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to pause subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
+		return
+	}
+
+	log.WithField("id", sub.ID).Info("Subscription paused")
+	c.JSON(http.StatusOK, sub)
+}
+
+// @Summary      Resume a subscription
+// @Description  Resumes billing from the given month (the current month by default). Resuming before the pause has started cancels the pause.
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                         true   "Subscription ID"
+// @Param        resume  body      models.ResumeSubscription  false  "Resume month"
+// @Success      200     {object}  models.Subscription
+// @Failure      400     {object}  models.Problem  "Invalid ID or date"
+// @Failure      404     {object}  models.Problem  "Subscription not found"
+// @Failure      409     {object}  models.Problem  "Subscription is not paused"
+// @Failure      500     {object}  models.Problem
+// @Failure      503     {object}  models.Problem
+// @Router       /subscriptions/{id}/resume [post]
+func ResumeSubscription(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Resuming subscription")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
+		return
+	}
+
+	var req models.ResumeSubscription
+	if err := bindOptionalJSON(c, &req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+	to, err := parseMonth(req.ResumeDate)
+	if err != nil {
+		log.WithError(err).Error("Invalid resume_date format")
+		c.Error(errInvalidResumeDate)
+		return
+	}
+
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		if sub.Status != lifecycle.Paused {
+			return errNotPaused
+		}
+		if err := setStatus(&sub, lifecycle.Active); err != nil {
+			return err
+		}
+		if err := closePause(tx, &sub, to); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(&sub).Error; err != nil {
+			return err
+		}
+		if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionResumed, sub); err != nil {
+			return err
+		}
+		return checkBudgets(tx, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to resume subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
+		return
+	}
+
+	log.WithField("id", sub.ID).Info("Subscription resumed")
+	c.JSON(http.StatusOK, sub)
+}
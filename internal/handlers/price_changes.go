@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/events"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/webhooks"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+var (
+	errPriceChangeNotFound = apperr.NotFound(
+		apperr.CodePriceChangeNotFound,
+		"price change not found",
+	)
+	errInvalidPriceChangeID = apperr.Validation(
+		apperr.CodeInvalidPriceChange,
+		"invalid price change id",
+	)
+	errInvalidEffectiveDate = apperr.Validation(
+		apperr.CodeInvalidPriceChange,
+		"effective_date must be in MM-YYYY format, not earlier than the current month and after start_date",
+	)
+	errNegativePrice = apperr.Validation(
+		apperr.CodeInvalidPriceChange,
+		"price must not be negative",
+	)
+)
+
+// @Summary      Schedule a price change
+// @Description  Schedules a new price for the subscription's charges from the given month on. The change is used by the total cost, budgets and the forecast. One change per month is allowed.
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                       true  "Subscription ID"
+// @Param        change  body      models.CreatePriceChange  true  "Price change"
+// @Success      201     {object}  models.Subscription
+// @Failure      400     {object}  models.Problem  "Invalid ID, date or price"
+// @Failure      404     {object}  models.Problem  "Subscription not found"
+// @Failure      409     {object}  models.Problem  "A price change for this month already exists"
+// @Failure      500     {object}  models.Problem
+// @Failure      503     {object}  models.Problem
+// @Router       /subscriptions/{id}/price-changes [post]
+func CreatePriceChange(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
+		return
+	}
+
+	var req models.CreatePriceChange
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+	effective, err := time.Parse("01-2006", req.EffectiveDate)
+	if err != nil || effective.Before(currentMonth()) {
+		c.Error(errInvalidEffectiveDate)
+		return
+	}
+	if req.Price < 0 {
+		c.Error(errNegativePrice)
+		return
+	}
+
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		change := models.PriceChange{
+			SubscriptionID: sub.ID,
+			EffectiveDate:  effective.Format(billing.DateLayout),
+			Price:          req.Price,
+		}
+		if change.EffectiveDate <= sub.StartDate {
+			return errInvalidEffectiveDate
+		}
+		if err := tx.Create(&change).Error; err != nil {
+			return err
+		}
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionUpdated, sub); err != nil {
+			return err
+		}
+		return checkBudgets(tx, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to schedule price change")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
+		return
+	}
+
+	log.WithField("id", sub.ID).Info("Price change scheduled")
+	c.JSON(http.StatusCreated, sub)
+}
+
+// @Summary      Cancel a scheduled price change
+// @Tags         subscriptions
+// @Param        id         path  int  true  "Subscription ID"
+// @Param        change_id  path  int  true  "Price change ID"
+// @Success      204
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Failure      503  {object}  models.Problem
+// @Router       /subscriptions/{id}/price-changes/{change_id} [delete]
+func DeletePriceChange(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidID)
+		return
+	}
+	changeID, err := strconv.Atoi(c.Param("change_id"))
+	if err != nil {
+		c.Error(errInvalidPriceChangeID)
+		return
+	}
+
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		sub, err := loadForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		result := tx.Where("subscription_id = ?", sub.ID).Delete(&models.PriceChange{}, changeID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errPriceChangeNotFound
+		}
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionUpdated, sub); err != nil {
+			return err
+		}
+		// Отмена запланированного снижения цены увеличивает расходы
+		return checkBudgets(tx, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to delete price change")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
+		return
+	}
+
+	log.WithField("id", id).WithField("price_change_id", changeID).Info("Price change deleted")
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/notify"
+	"github.com/nemopss/subscription-service/internal/reminders"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+var errInvalidReminderDays = apperr.Validation(
+	apperr.CodeInvalidReminderSettings,
+	"days_before must be between 0 and 365",
+)
+
+// GetReminderSettings возвращает настройки напоминаний пользователя.
+// Пока пользователь не задал собственных, действуют настройки по
+// умолчанию с defaultDays.
+// @Summary      Get renewal reminder settings
+// @Description  Returns the user's renewal reminder settings, or the defaults if none were saved
+// @Tags         reminders
+// @Produce      json
+// @Param        user_id  path      string  true  "User ID (UUID)"
+// @Success      200      {object}  models.ReminderSettings
+// @Failure      400      {object}  models.Problem
+// @Failure      503      {object}  models.Problem
+// @Router       /users/{user_id}/reminder-settings [get]
+func GetReminderSettings(defaultDays int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.FromContext(c.Request.Context())
+		userID, err := uuid.Parse(c.Param("user_id"))
+		if err != nil {
+			log.WithError(err).Error("Invalid user id")
+			c.Error(errInvalidUserID)
+			return
+		}
+
+		settings, err := loadReminderSettings(c, userID, defaultDays)
+		if err != nil {
+			log.WithError(err).Error("Failed to get reminder settings")
+			c.Error(apperr.FromDB(err, nil))
+			return
+		}
+		c.JSON(http.StatusOK, settings)
+	}
+}
+
+// UpdateReminderSettings сохраняет настройки напоминаний пользователя.
+// Не переданные поля сохраняют текущие значения. Канал доставки
+// проверяется вместе с адресом получателя.
+// @Summary      Update renewal reminder settings
+// @Description  Sets how many days before a renewal the user is reminded, whether reminders are enabled and the delivery channel (email, webhook or telegram)
+// @Tags         reminders
+// @Accept       json
+// @Produce      json
+// @Param        user_id   path      string                         true  "User ID (UUID)"
+// @Param        settings  body      models.UpdateReminderSettings  true  "Reminder settings"
+// @Success      200       {object}  models.ReminderSettings
+// @Failure      400       {object}  models.Problem
+// @Failure      503       {object}  models.Problem
+// @Router       /users/{user_id}/reminder-settings [put]
+func UpdateReminderSettings(defaultDays int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.FromContext(c.Request.Context())
+		userID, err := uuid.Parse(c.Param("user_id"))
+		if err != nil {
+			log.WithError(err).Error("Invalid user id")
+			c.Error(errInvalidUserID)
+			return
+		}
+
+		var req models.UpdateReminderSettings
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.WithError(err).Error("Invalid request body")
+			c.Error(invalidBody(err))
+			return
+		}
+		if req.DaysBefore != nil &&
+			(*req.DaysBefore < 0 || *req.DaysBefore > reminders.MaxDaysBefore) {
+			c.Error(errInvalidReminderDays)
+			return
+		}
+
+		settings, err := loadReminderSettings(c, userID, defaultDays)
+		if err != nil {
+			log.WithError(err).Error("Failed to get reminder settings")
+			c.Error(apperr.FromDB(err, nil))
+			return
+		}
+		if req.DaysBefore != nil {
+			settings.DaysBefore = *req.DaysBefore
+		}
+		if req.Enabled != nil {
+			settings.Enabled = *req.Enabled
+		}
+		if req.Channel != nil {
+			settings.Channel = *req.Channel
+		}
+		if req.Recipient != nil {
+			settings.Recipient = *req.Recipient
+		}
+		if req.Locale != nil {
+			settings.Locale = *req.Locale
+		}
+		// Пустой канал отключает доставку, напоминания только пишутся в журнал
+		if settings.Channel != "" {
+			err := notify.ValidateRecipient(settings.Channel, settings.Recipient)
+			if err != nil {
+				log.WithError(err).Error("Invalid reminder channel")
+				c.Error(apperr.Validation(
+					apperr.CodeInvalidReminderSettings,
+					err.Error(),
+				))
+				return
+			}
+		}
+
+		err = db.DB.WithContext(c.Request.Context()).
+			Clauses(clause.OnConflict{UpdateAll: true}).
+			Create(&settings).Error
+		if err != nil {
+			log.WithError(err).Error("Failed to save reminder settings")
+			c.Error(apperr.FromDB(err, nil))
+			return
+		}
+
+		log.WithField("user_id", userID).Info("Reminder settings updated")
+		c.JSON(http.StatusOK, settings)
+	}
+}
+
+func loadReminderSettings(
+	c *gin.Context,
+	userID uuid.UUID,
+	defaultDays int,
+) (models.ReminderSettings, error) {
+	var settings models.ReminderSettings
+	err := db.DB.WithContext(c.Request.Context()).
+		First(&settings, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return reminders.Defaults(userID, defaultDays), nil
+	}
+	return settings, err
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/catalog"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+var (
+	errServiceNotFound = apperr.NotFound(
+		apperr.CodeServiceNotFound,
+		"service not found",
+	)
+	errInvalidServiceID = apperr.Validation(
+		apperr.CodeInvalidService,
+		"invalid service_id",
+	)
+)
+
+// linkService связывает подписку с сервисом каталога: по serviceID, если он
+// задан, иначе по названию подписки. Несуществующий serviceID отклоняется
+// внешним ключом.
+func linkService(tx *gorm.DB, sub *models.Subscription, serviceID *int) error {
+	if serviceID != nil {
+		sub.ServiceID = serviceID
+		return nil
+	}
+	service, err := catalog.Find(tx, sub.ServiceName)
+	if err != nil {
+		return err
+	}
+	sub.ServiceID = nil
+	if service != nil {
+		sub.ServiceID = &service.ID
+	}
+	return nil
+}
+
+// filterByService применяет фильтры service_id и service_name. Название
+// сопоставляется с каталогом, чтобы учесть все написания сервиса;
+// подписки вне каталога ищутся по точному названию.
+func filterByService(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if raw := c.Query("service_id"); raw != "" {
+		serviceID, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, errInvalidServiceID
+		}
+		query = query.Where("service_id = ?", serviceID)
+	}
+
+	if name := c.Query("service_name"); name != "" {
+		service, err := catalog.Find(db.DB.WithContext(c.Request.Context()), name)
+		if err != nil {
+			return nil, err
+		}
+		if service != nil {
+			query = query.Where("(service_id = ? OR service_name = ?)", service.ID, name)
+		} else {
+			query = query.Where("service_name = ?", name)
+		}
+	}
+	return query, nil
+}
+
+// saveService проверяет, что названия сервиса не заняты другими
+// сервисами, сохраняет его и связывает с ним подходящие подписки
+func saveService(tx *gorm.DB, service *models.Service) error {
+	var services []models.Service
+	if err := tx.Find(&services).Error; err != nil {
+		return err
+	}
+	if other, ok := catalog.Conflict(services, *service); ok {
+		return apperr.Conflict(
+			apperr.CodeConflict,
+			"service name or alias is already used by "+other.Name,
+		)
+	}
+	if err := tx.Save(service).Error; err != nil {
+		return err
+	}
+	_, err := catalog.Link(tx, *service)
+	return err
+}
+
+// bindService разбирает и проверяет сервис из тела запроса
+func bindService(c *gin.Context) (models.CreateService, error) {
+	var req models.CreateService
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return req, invalidBody(err)
+	}
+	if err := catalog.Validate(req); err != nil {
+		return req, apperr.Validation(apperr.CodeInvalidService, err.Error())
+	}
+	if req.Aliases == nil {
+		req.Aliases = []string{}
+	}
+	if req.Prices == nil {
+		req.Prices = []models.ServicePrice{}
+	}
+	for i := range req.Prices {
+		if req.Prices[i].BillingCycle == "" {
+			req.Prices[i].BillingCycle = billing.Monthly
+		}
+	}
+	return req, nil
+}
+
+// @Summary      Add a service to the catalog
+// @Description  Adds a service with its canonical name, aliases and default prices. Subscriptions without a service whose name matches the name or an alias are linked to it.
+// @Tags         services
+// @Accept       json
+// @Produce      json
+// @Param        service  body      models.CreateService  true  "Service"
+// @Success      201      {object}  models.Service
+// @Failure      400      {object}  models.Problem
+// @Failure      409      {object}  models.Problem  "Name or alias already used"
+// @Failure      503      {object}  models.Problem
+// @Router       /services [post]
+func CreateService(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	req, err := bindService(c)
+	if err != nil {
+		log.WithError(err).Error("Invalid service")
+		c.Error(err)
+		return
+	}
+
+	service := models.Service{
+		Name:     req.Name,
+		Aliases:  req.Aliases,
+		Category: req.Category,
+		LogoURL:  req.LogoURL,
+		Prices:   req.Prices,
+	}
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		return saveService(tx, &service)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to create service")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	log.WithField("service_id", service.ID).Info("Service created")
+	c.JSON(http.StatusCreated, service)
+}
+
+// @Summary      List catalog services
+// @Description  Returns the catalog ordered by name, optionally only the service matching a name or alias
+// @Tags         services
+// @Produce      json
+// @Param        name  query     string  false  "Service name or alias"
+// @Success      200   {array}   models.Service
+// @Failure      503   {object}  models.Problem
+// @Router       /services [get]
+func ListServices(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	var services []models.Service
+	if err := db.DB.WithContext(c.Request.Context()).Order("name").Find(&services).Error; err != nil {
+		log.WithError(err).Error("Failed to list services")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	if name := c.Query("name"); name != "" {
+		matched := []models.Service{}
+		if s, ok := catalog.Match(services, name); ok {
+			matched = append(matched, s)
+		}
+		services = matched
+	}
+	c.JSON(http.StatusOK, services)
+}
+
+// @Summary      Get a catalog service
+// @Tags         services
+// @Produce      json
+// @Param        id   path      int  true  "Service ID"
+// @Success      200  {object}  models.Service
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /services/{id} [get]
+func GetService(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidServiceID)
+		return
+	}
+	var service models.Service
+	if err := db.DB.WithContext(c.Request.Context()).First(&service, id).Error; err != nil {
+		log.WithError(err).Error("Failed to get service")
+		c.Error(apperr.FromDB(err, errServiceNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, service)
+}
+
+// @Summary      Update a catalog service
+// @Description  Replaces the service. Subscriptions without a service that match the new name or aliases are linked to it; already linked subscriptions are kept.
+// @Tags         services
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                   true  "Service ID"
+// @Param        service  body      models.CreateService  true  "Service"
+// @Success      200      {object}  models.Service
+// @Failure      400      {object}  models.Problem
+// @Failure      404      {object}  models.Problem
+// @Failure      409      {object}  models.Problem  "Name or alias already used"
+// @Failure      503      {object}  models.Problem
+// @Router       /services/{id} [put]
+func UpdateService(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidServiceID)
+		return
+	}
+	req, err := bindService(c)
+	if err != nil {
+		log.WithError(err).Error("Invalid service")
+		c.Error(err)
+		return
+	}
+
+	var service models.Service
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&service, id).Error; err != nil {
+			return err
+		}
+		service.Name = req.Name
+		service.Aliases = req.Aliases
+		service.Category = req.Category
+		service.LogoURL = req.LogoURL
+		service.Prices = req.Prices
+		return saveService(tx, &service)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to update service")
+		c.Error(apperr.FromDB(err, errServiceNotFound))
+		return
+	}
+
+	log.WithField("service_id", service.ID).Info("Service updated")
+	c.JSON(http.StatusOK, service)
+}
+
+// @Summary      Delete a catalog service
+// @Description  Deletes the service; linked subscriptions keep their names and lose the link
+// @Tags         services
+// @Param        id   path  int  true  "Service ID"
+// @Success      204
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /services/{id} [delete]
+func DeleteService(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidServiceID)
+		return
+	}
+	result := db.DB.WithContext(c.Request.Context()).Delete(&models.Service{}, id)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Failed to delete service")
+		c.Error(apperr.FromDB(result.Error, nil))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.Error(errServiceNotFound)
+		return
+	}
+	log.WithField("service_id", id).Info("Service deleted")
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/events"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/sharing"
+	"github.com/nemopss/subscription-service/internal/webhooks"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// @Summary      Share a subscription
+// @Description  Replaces the subscription's members and split rule. With equal the price is split evenly between the owner and the members; with percentage each member pays a percent of every charge; with fixed each member pays a fixed amount per charge. The owner pays the rest. The user's total cost, budgets and forecast count only the user's share.
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                       true  "Subscription ID"
+// @Param        share  body      models.ShareSubscription  true  "Split rule and members"
+// @Success      200    {object}  models.Subscription
+// @Failure      400    {object}  models.Problem  "Invalid ID, split rule or members"
+// @Failure      404    {object}  models.Problem  "Subscription not found"
+// @Failure      500    {object}  models.Problem
+// @Failure      503    {object}  models.Problem
+// @Router       /subscriptions/{id}/shares [put]
+func ShareSubscription(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
+		return
+	}
+
+	var req models.ShareSubscription
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		if err := sharing.Validate(sub, req); err != nil {
+			return apperr.Validation(apperr.CodeInvalidShare, err.Error())
+		}
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&models.Share{}).Error; err != nil {
+			return err
+		}
+		for i := range req.Members {
+			req.Members[i].ID = 0
+			req.Members[i].SubscriptionID = sub.ID
+		}
+		if err := tx.Create(&req.Members).Error; err != nil {
+			return err
+		}
+		sub.SplitRule = req.SplitRule
+		sub.Shares = req.Members
+		if err := tx.Omit(clause.Associations).Save(&sub).Error; err != nil {
+			return err
+		}
+		if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionUpdated, sub); err != nil {
+			return err
+		}
+		return checkBudgets(tx, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to share subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
+		return
+	}
+
+	log.WithField("id", sub.ID).WithField("members", len(sub.Shares)).Info("Subscription shared")
+	c.JSON(http.StatusOK, sub)
+}
+
+// @Summary      Stop sharing a subscription
+// @Description  Removes all members; the owner pays the full price again
+// @Tags         subscriptions
+// @Produce      json
+// @Param        id   path      int  true  "Subscription ID"
+// @Success      200  {object}  models.Subscription
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Failure      503  {object}  models.Problem
+// @Router       /subscriptions/{id}/shares [delete]
+func UnshareSubscription(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
+		return
+	}
+
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", sub.ID).Delete(&models.Share{}).Error; err != nil {
+			return err
+		}
+		sub.SplitRule = ""
+		sub.Shares = nil
+		if err := tx.Omit(clause.Associations).Save(&sub).Error; err != nil {
+			return err
+		}
+		if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionUpdated, sub); err != nil {
+			return err
+		}
+		return checkBudgets(tx, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to unshare subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
+		return
+	}
+
+	log.WithField("id", sub.ID).Info("Subscription unshared")
+	c.JSON(http.StatusOK, sub)
+}
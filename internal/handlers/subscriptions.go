@@ -2,44 +2,126 @@ package handlers
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/costs"
 	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/events"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
+	"github.com/nemopss/subscription-service/internal/metrics"
 	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/sharing"
+	"github.com/nemopss/subscription-service/internal/tracing"
+	"github.com/nemopss/subscription-service/internal/webhooks"
 	"github.com/nemopss/subscription-service/pkg/logger"
 )
 
+// Ошибки, общие для обработчиков подписок
+var (
+	errInvalidID = apperr.Validation(
+		apperr.CodeInvalidID,
+		"invalid subscription id",
+	)
+	errSubscriptionNotFound = apperr.NotFound(
+		apperr.CodeSubscriptionNotFound,
+		"subscription not found",
+	)
+	errInvalidStartDate = apperr.Validation(
+		apperr.CodeInvalidStartDate,
+		"invalid start_date format, expected MM-YYYY",
+	)
+	errInvalidEndDate = apperr.Validation(
+		apperr.CodeInvalidEndDate,
+		"invalid end_date format, expected MM-YYYY",
+	)
+	errInvalidUserID = apperr.Validation(
+		apperr.CodeInvalidUserID,
+		"invalid user id",
+	)
+	errInvalidBillingCycle = apperr.Validation(
+		apperr.CodeInvalidBillingCycle,
+		"invalid billing_cycle, expected monthly, quarterly or yearly",
+	)
+	errInvalidInitialStatus = apperr.Validation(
+		apperr.CodeInvalidStatus,
+		"a new subscription must be active or trialing",
+	)
+	errStatusEndpoint = apperr.Conflict(
+		apperr.CodeInvalidStatusTransition,
+		"use POST /subscriptions/{id}/cancel or /subscriptions/{id}/pause to cancel or pause a subscription",
+	)
+)
+
+// invalidStatus оборачивает ошибку разбора состояния
+func invalidStatus(err error) error {
+	return apperr.Validation(apperr.CodeInvalidStatus, err.Error())
+}
+
+// setStatus переводит подписку в новое состояние по правилам жизненного
+// цикла
+func setStatus(sub *models.Subscription, to lifecycle.Status) error {
+	if err := lifecycle.Transition(sub.Status, to); err != nil {
+		return apperr.Conflict(apperr.CodeInvalidStatusTransition, err.Error())
+	}
+	switch {
+	case to == lifecycle.Expired && sub.ExpiredAt == nil:
+		now := time.Now().UTC()
+		sub.ExpiredAt = &now
+	case to != lifecycle.Expired:
+		sub.ExpiredAt = nil
+	}
+	sub.Status = to
+	return nil
+}
+
+// invalidBody оборачивает ошибку разбора тела запроса. Текст ошибки JSON
+// или валидатора раскрывает внутренние типы и поля, поэтому клиент
+// получает постоянное сообщение, а причина попадает только в логи.
+func invalidBody(err error) error {
+	return &apperr.Error{
+		Kind:    apperr.KindValidation,
+		Code:    apperr.CodeInvalidRequestBody,
+		Message: "request body is malformed",
+		Err:     err,
+	}
+}
+
 // @Summary      Create a new subscription
-// @Description  Create a new subscription with the provided details
+// @Description  Create a new subscription with the provided details. If the user already has a subscription to the same service for an overlapping period, the subscription is still created and the response contains a possible_duplicate warning.
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Param        subscription  body      models.CreateSubscription  true  "Subscription data"
-// @Success      201           {object}  models.Subscription "CreatedSubscription"
-// @Failure      400           {object}  models.ErrorResponse "Invalid request"
-// @Failure      500           {object}  models.ErrorResponse "Internal error"
+// @Success      201           {object}  models.CreatedSubscription "CreatedSubscription"
+// @Failure      400           {object}  models.Problem "Invalid request"
+// @Failure      500           {object}  models.Problem "Internal error"
+// @Failure      503           {object}  models.Problem "Database unavailable"
 // @Router       /subscriptions [post]
 func CreateSubscription(c *gin.Context) {
-	logger.Log.Info("Creating new subscription")
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Creating new subscription")
 	var sub models.Subscription
 	if err := c.ShouldBindJSON(&sub); err != nil {
-		logger.Log.WithError(err).Error("Invalid request body")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
 		return
 	}
 
 	startDate, err := time.Parse("01-2006", sub.StartDate)
 	if err != nil {
-		logger.Log.WithError(err).Error("Invalid start_date format")
-		c.JSON(
-			http.StatusBadRequest,
-			gin.H{"error": "invalid start_date format, expected MM-YYYY"},
-		)
+		log.WithError(err).Error("Invalid start_date format")
+		c.Error(errInvalidStartDate)
 		return
 	}
 	sub.StartDate = startDate.Format("2006-01-02")
@@ -47,25 +129,75 @@ func CreateSubscription(c *gin.Context) {
 	if sub.EndDate != nil {
 		endDate, err := time.Parse("01-2006", *sub.EndDate)
 		if err != nil {
-			logger.Log.WithError(err).Error("Invalid end_date format")
-			c.JSON(
-				http.StatusBadRequest,
-				gin.H{"error": "invalid end_date format, expected MM-YYYY"},
-			)
+			log.WithError(err).Error("Invalid end_date format")
+			c.Error(errInvalidEndDate)
 			return
 		}
 		endDateStr := endDate.Format("2006-01-02")
 		sub.EndDate = &endDateStr
 	}
 
-	if err := db.DB.Create(&sub).Error; err != nil {
-		logger.Log.WithError(err).Error("Failed to create subscription")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	sub.BillingCycle, err = billing.ParseCycle(string(sub.BillingCycle))
+	if err != nil {
+		log.WithError(err).Error("Invalid billing_cycle")
+		c.Error(errInvalidBillingCycle)
+		return
+	}
+
+	if sub.Status == "" {
+		sub.Status = lifecycle.Active
+	}
+	if !slices.Contains(lifecycle.InitialStatuses, sub.Status) {
+		log.WithField("status", sub.Status).Error("Invalid initial status")
+		c.Error(errInvalidInitialStatus)
+		return
+	}
+	// Приостановки, разделение стоимости и изменения цены задаются только
+	// отдельными эндпоинтами, которые проверяют их правила
+	sub.ExpiredAt = nil
+	sub.Pauses = nil
+	sub.SplitRule = ""
+	sub.Shares = nil
+	sub.PriceChanges = nil
+	clearCancellation(&sub)
+
+	if sub.Tags, err = normalizeTags(sub.Tags); err != nil {
+		log.WithError(err).Error("Invalid tags")
+		c.Error(err)
+		return
+	}
+
+	var warnings []models.Warning
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := linkService(tx, &sub, sub.ServiceID); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(&sub).Error; err != nil {
+			return err
+		}
+		if warnings, err = duplicateWarnings(tx, sub); err != nil {
+			return err
+		}
+		if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionCreated, sub); err != nil {
+			return err
+		}
+		return checkBudgets(tx, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to create subscription")
+		c.Error(apperr.FromDB(err, nil))
 		return
 	}
 
-	logger.Log.WithField("id", sub.ID).Info("Subscription created")
-	c.JSON(http.StatusCreated, sub)
+	metrics.SubscriptionsCreated.Inc()
+	log.WithField("id", sub.ID).Info("Subscription created")
+	if len(warnings) > 0 {
+		log.WithField("id", sub.ID).Warn("Possible duplicate subscription")
+	}
+	c.JSON(http.StatusCreated, models.CreatedSubscription{
+		Subscription: sub,
+		Warnings:     warnings,
+	})
 }
 
 // @Summary      Get a subscription by ID
@@ -74,25 +206,27 @@ func CreateSubscription(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      int  true  "Subscription ID"
 // @Success      200  {object}  models.Subscription
-// @Failure      400  {object}  models.ErrorResponse
-// @Failure      404  {object}  models.ErrorResponse
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Failure      503  {object}  models.Problem
 // @Router       /subscriptions/{id} [get]
 func GetSubscription(c *gin.Context) {
-	logger.Log.Info("Getting subscription")
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Getting subscription")
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		logger.Log.WithError(err).Error("Invalid subscription id")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
 		return
 	}
 
 	var sub models.Subscription
-	if err := db.DB.First(&sub, id).Error; err != nil {
-		logger.Log.WithError(err).Error("Subscription not found")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+	if err := costs.Preload(db.DB.WithContext(c.Request.Context())).First(&sub, id).Error; err != nil {
+		log.WithError(err).Error("Failed to get subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
 		return
 	}
-	logger.Log.WithField("id", sub.ID).Info("Subscription found")
+	log.WithField("id", sub.ID).Info("Subscription found")
 	c.JSON(http.StatusOK, sub)
 }
 
@@ -104,77 +238,149 @@ func GetSubscription(c *gin.Context) {
 // @Param        id           path      int                      true  "Subscription ID"
 // @Param        subscription body      models.CreateSubscription  true  "Updated subscription data"
 // @Success      200          {object}  models.Subscription      "Updated subscription"
-// @Failure      400          {object}  models.ErrorResponse       "Invalid subscription ID or request body"
-// @Failure      404          {object}  models.ErrorResponse       "Subscription not found"
-// @Failure      500          {object}  models.ErrorResponse       "Internal server error"
+// @Failure      400          {object}  models.Problem       "Invalid subscription ID or request body"
+// @Failure      404          {object}  models.Problem       "Subscription not found"
+// @Failure      409          {object}  models.Problem       "Status transition not allowed"
+// @Failure      500          {object}  models.Problem       "Internal server error"
+// @Failure      503          {object}  models.Problem       "Database unavailable"
 // @Router       /subscriptions/{id} [put]
 func UpdateSubscription(c *gin.Context) {
-	logger.Log.Info("Updating subscription")
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Updating subscription")
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		logger.Log.WithError(err).Error("Invalid subscription id")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
-		return
-	}
-
-	var sub models.Subscription
-	if err := db.DB.First(&sub, id).Error; err != nil {
-		logger.Log.WithError(err).Error("Subscription not found")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
 		return
 	}
 
 	var updatedSub models.Subscription
 	if err := c.ShouldBindJSON(&updatedSub); err != nil {
-		logger.Log.WithError(err).Error("Invalid request body")
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
 		return
 	}
 
 	// Парсим start_date, если обновлено
+	var startDate string
 	if updatedSub.StartDate != "" {
-		startDate, err := time.Parse("01-2006", updatedSub.StartDate)
+		start, err := time.Parse("01-2006", updatedSub.StartDate)
 		if err != nil {
-			logger.Log.WithError(err).Error("Invalid start_date format")
-			c.JSON(
-				http.StatusBadRequest,
-				gin.H{"error": "invalid start_date format, expected MM-YYYY"},
-			)
+			log.WithError(err).Error("Invalid start_date format")
+			c.Error(errInvalidStartDate)
 			return
 		}
-		sub.StartDate = startDate.Format("2006-01-02")
+		startDate = start.Format("2006-01-02")
 	}
 
 	// Парсим end_date, если обновлено
+	var endDate *string
 	if updatedSub.EndDate != nil {
-		endDate, err := time.Parse("01-2006", *updatedSub.EndDate)
+		end, err := time.Parse("01-2006", *updatedSub.EndDate)
 		if err != nil {
-			logger.Log.WithError(err).Error("Invalid end_date format")
-			c.JSON(
-				http.StatusBadRequest,
-				gin.H{"error": "invalid end_date format, expected MM-YYYY"},
-			)
+			log.WithError(err).Error("Invalid end_date format")
+			c.Error(errInvalidEndDate)
+			return
+		}
+		endDateStr := end.Format("2006-01-02")
+		endDate = &endDateStr
+	}
+
+	// Периодичность не меняется, если не передана
+	var cycle billing.Cycle
+	if updatedSub.BillingCycle != "" {
+		cycle, err = billing.ParseCycle(string(updatedSub.BillingCycle))
+		if err != nil {
+			log.WithError(err).Error("Invalid billing_cycle")
+			c.Error(errInvalidBillingCycle)
 			return
 		}
-		endDateStr := endDate.Format("2006-01-02")
-		sub.EndDate = &endDateStr
-	} else {
-		sub.EndDate = nil
 	}
 
-	// Обновляем остальные поля
-	sub.ServiceName = updatedSub.ServiceName
-	sub.Price = updatedSub.Price
-	sub.UserID = updatedSub.UserID
+	// Состояние меняется только по разрешенным переходам
+	var status lifecycle.Status
+	if updatedSub.Status != "" {
+		status, err = lifecycle.ParseStatus(string(updatedSub.Status))
+		if err != nil {
+			log.WithError(err).Error("Invalid status")
+			c.Error(invalidStatus(err))
+			return
+		}
+	}
 
-	// Сохраняем изменения
-	if err := db.DB.Save(&sub).Error; err != nil {
-		logger.Log.WithError(err).Error("Failed to update subscription")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	tags, err := normalizeTags(updatedSub.Tags)
+	if err != nil {
+		log.WithError(err).Error("Invalid tags")
+		c.Error(err)
+		return
+	}
+
+	// Подписка загружается с блокировкой в транзакции изменения, чтобы
+	// переход состояния проверялся по ее текущему состоянию и не затирал
+	// параллельные приостановку, отмену или истечение
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if sub, err = loadForUpdate(tx, id); err != nil {
+			return err
+		}
+		// Отмена и приостановка задают даты, поэтому выполняются только
+		// своими эндпоинтами
+		if status != sub.Status && (status == lifecycle.Cancelled || status == lifecycle.Paused) {
+			return errStatusEndpoint
+		}
+		if startDate != "" {
+			sub.StartDate = startDate
+		}
+		switch {
+		case endDate != nil:
+			sub.EndDate = endDate
+		case sub.Status == lifecycle.Cancelled && status == lifecycle.Active:
+			// Как при uncancel: возвращается end_date до отмены
+			sub.EndDate = sub.EndDateBeforeCancel
+		case sub.Status == lifecycle.Cancelled:
+			// end_date отмены сохраняется, пока его не изменят явно
+		default:
+			sub.EndDate = nil
+		}
+		// Истекшая подписка без end_date учитывалась бы в стоимости и
+		// бюджетах бессрочно
+		if status == lifecycle.Expired && sub.Status != lifecycle.Expired {
+			if err := endNow(&sub); err != nil {
+				return err
+			}
+		}
+		if cycle != "" {
+			sub.BillingCycle = cycle
+		}
+		sub.Tags = tags
+		sub.ServiceName = updatedSub.ServiceName
+		sub.Price = updatedSub.Price
+		sub.UserID = updatedSub.UserID
+		sub.CategoryID = updatedSub.CategoryID
+
+		if status != "" {
+			if err := changeStatus(tx, &sub, status); err != nil {
+				return err
+			}
+		}
+		if err := linkService(tx, &sub, updatedSub.ServiceID); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(&sub).Error; err != nil {
+			return err
+		}
+		if err := events.SubscriptionChanged(tx, webhooks.EventSubscriptionUpdated, sub); err != nil {
+			return err
+		}
+		return checkBudgets(tx, sub)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to update subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
 		return
 	}
 
-	logger.Log.WithField("id", sub.ID).Info("Subscription updated")
+	log.WithField("id", sub.ID).Info("Subscription updated")
 	c.JSON(http.StatusOK, sub)
 }
 
@@ -184,207 +390,238 @@ func UpdateSubscription(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      int  true  "Subscription ID"
 // @Success      204
-// @Failure      401  {object}  models.ErrorResponse
-// @Failure      404  {object}  models.ErrorResponse
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Failure      503  {object}  models.Problem
 // @Router       /subscriptions/{id} [delete]
 func DeleteSubscription(c *gin.Context) {
-	logger.Log.Info("Deleting subscription")
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Deleting subscription")
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		logger.Log.WithError(err).Error("Invalid subscription id")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
+		log.WithError(err).Error("Invalid subscription id")
+		c.Error(errInvalidID)
 		return
 	}
-	if rows := db.DB.Delete(models.Subscription{}, id).RowsAffected; rows == 0 {
-		logger.Log.WithError(err).Error("Subscription not found")
-		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+	// Удаленная запись возвращается через RETURNING и попадает в событие
+	var sub models.Subscription
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Returning{}).Delete(&sub, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return events.SubscriptionChanged(tx, webhooks.EventSubscriptionDeleted, sub)
+	})
+	if err != nil {
+		log.WithError(err).WithField("id", id).Error("Failed to delete subscription")
+		c.Error(apperr.FromDB(err, errSubscriptionNotFound))
 		return
 	}
 
-	logger.Log.WithField("id", id).Info("Subscription deleted")
+	metrics.SubscriptionsDeleted.Inc()
+	log.WithField("id", id).Info("Subscription deleted")
 	c.Status(http.StatusNoContent)
 }
 
 // @Summary      Get all subscriptions
-// @Description  Retrieve all of the subscriptions
+// @Description  Retrieve all of the subscriptions, optionally filtered by status, category and tags
 // @Tags         subscriptions
 // @Produce      json
-// @Success      200  {array}  models.Subscription
-// @Failure      400  {object}  models.ErrorResponse
+// @Param        status       query     string  false  "Comma-separated statuses (trialing, active, paused, cancelled, expired)"
+// @Param        category_id  query     int     false  "Category ID, subcategories included"
+// @Param        tag          query     string  false  "Comma-separated tags, all must be present"
+// @Success      200     {array}   models.Subscription
+// @Failure      400     {object}  models.Problem
+// @Failure      500     {object}  models.Problem
+// @Failure      503     {object}  models.Problem
 // @Router       /subscriptions [get]
 func ListSubscriptions(c *gin.Context) {
-	logger.Log.Info("Listing subscriptions")
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Listing subscriptions")
 	var subs []models.Subscription
 
-	if err := db.DB.Find(&subs).Error; err != nil {
-		logger.Log.WithError(err).Error("Failed to list subscriptions")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	query := costs.Preload(db.DB.WithContext(c.Request.Context()))
+	if raw := c.Query("status"); raw != "" {
+		statuses, err := lifecycle.ParseStatuses(raw)
+		if err != nil {
+			log.WithError(err).Error("Invalid status filter")
+			c.Error(invalidStatus(err))
+			return
+		}
+		query = query.Where("status IN ?", statuses)
+	}
+	query, err := filterByTaxonomy(c, query)
+	if err != nil {
+		log.WithError(err).Error("Invalid category or tag filter")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	if err := query.Find(&subs).Error; err != nil {
+		log.WithError(err).Error("Failed to list subscriptions")
+		c.Error(apperr.FromDB(err, nil))
 		return
 	}
 
-	logger.Log.WithField("count", len(subs)).Info("Subscriptions listed")
+	log.WithField("count", len(subs)).Info("Subscriptions listed")
 	c.JSON(http.StatusOK, subs)
 }
 
+// parsePeriod разбирает период из параметров start_date и end_date в
+// формате MM-YYYY. По умолчанию период начинается в 1970 году и
+// заканчивается текущим моментом; end_date включает весь месяц.
+func parsePeriod(c *gin.Context) (time.Time, time.Time, error) {
+	periodStart := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Now()
+	var err error
+
+	if raw := c.Query("start_date"); raw != "" {
+		if periodStart, err = time.Parse("01-2006", raw); err != nil {
+			return periodStart, periodEnd, errInvalidStartDate
+		}
+	}
+	if raw := c.Query("end_date"); raw != "" {
+		if periodEnd, err = time.Parse("01-2006", raw); err != nil {
+			return periodStart, periodEnd, errInvalidEndDate
+		}
+		// Устанавливаем конец месяца
+		periodEnd = periodEnd.AddDate(0, 1, -1)
+	}
+	return periodStart, periodEnd, nil
+}
+
 // GetTotalCostByPeriod вычисляет общую стоимость подписок за период
 // @Summary      Get total cost of subscriptions
-// @Description  Calculates the total cost of subscriptions for a user over a specified period, optionally filtered by service name and date range
+// @Description  Calculates the total cost of subscriptions for a user over a specified period, optionally filtered by service name and date range. Paused months are not charged. Subscriptions shared with the user count with the user's share, the owner's with the rest.
 // @Tags         subscriptions
 // @Produce      json
 // @Param        user_id       query     string  true   "User ID (UUID)"
-// @Param        service_name  query     string  false  "Service name filter"
+// @Param        service_name  query     string  false  "Service name filter, matched against catalog names and aliases"
+// @Param        service_id    query     int     false  "Catalog service ID filter"
+// @Param        category_id   query     int     false  "Category ID filter, subcategories included"
+// @Param        tag           query     string  false  "Comma-separated tags, all must be present"
+// @Param        group_by      query     string  false  "Group the total by top-level category or by tag"  Enums(category, tag)
 // @Param        start_date    query     string  false  "Start date (MM-YYYY)"
 // @Param        end_date      query     string  false  "End date (MM-YYYY)"
 // @Success      200           {object}  models.TotalCostResponse  "Total cost"
-// @Failure      400           {object}  models.ErrorResponse      "Invalid user ID or date format"
-// @Failure      500           {object}  models.ErrorResponse      "Internal server error"
+// @Failure      400           {object}  models.Problem      "Invalid user ID or date format"
+// @Failure      500           {object}  models.Problem      "Internal server error"
+// @Failure      503           {object}  models.Problem      "Database unavailable"
 // @Router       /subscriptions/total [get]
 func GetTotalCostByPeriod(c *gin.Context) {
-	logger.Log.Info("Getting total cost by period")
+	log := logger.FromContext(c.Request.Context())
+	log.Info("Getting total cost by period")
 	userIDStr := c.Query("user_id")
-	serviceName := c.Query("service_name")
-	startDateStr := c.Query("start_date")
-	endDateStr := c.Query("end_date")
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		logger.Log.WithError(err).Error("Invalid user id")
-		c.JSON(
-			http.StatusBadRequest,
-			models.ErrorResponse{Error: "invalid user id"},
-		)
+		log.WithError(err).Error("Invalid user id")
+		c.Error(errInvalidUserID)
 		return
 	}
 
-	defaultStart := time.Date(
-		1970,
-		1,
-		1,
-		0,
-		0,
-		0,
-		0,
-		time.UTC,
-	)
+	periodStart, periodEnd, err := parsePeriod(c)
+	if err != nil {
+		log.WithError(err).Error("Invalid period")
+		c.Error(err)
+		return
+	}
 	defaultEnd := time.Now()
 
-	var periodStart, periodEnd time.Time
-	if startDateStr != "" {
-		periodStart, err = time.Parse("01-2006", startDateStr)
+	var groups *costGroups
+	switch groupBy := c.Query("group_by"); groupBy {
+	case "":
+	case groupByCategory, groupByTag:
+		tree, err := loadCategoryTree(db.DB.WithContext(c.Request.Context()))
 		if err != nil {
-			logger.Log.WithError(err).Error("Invalid start_date format")
-			c.JSON(
-				http.StatusBadRequest,
-				models.ErrorResponse{
-					Error: "invalid start_date format, expected MM-YYYY",
-				},
-			)
+			log.WithError(err).Error("Failed to load categories")
+			c.Error(apperr.FromDB(err, nil))
 			return
 		}
-	} else {
-		periodStart = defaultStart
+		groups = newCostGroups(groupBy, tree)
+	default:
+		c.Error(apperr.Validation(
+			apperr.CodeInvalidGroupBy,
+			"invalid group_by, expected category or tag",
+		))
+		return
 	}
 
-	if endDateStr != "" {
-		periodEnd, err = time.Parse("01-2006", endDateStr)
-		if err != nil {
-			logger.Log.WithError(err).Error("Invalid end_date format")
-			c.JSON(
-				http.StatusBadRequest,
-				models.ErrorResponse{
-					Error: "invalid end_date format, expected MM-YYYY",
-				},
-			)
-			return
-		}
-		// Устанавливаем конец месяца
-		periodEnd = periodEnd.AddDate(0, 1, -1)
-	} else {
-		periodEnd = defaultEnd
-	}
+	computeStart := time.Now()
+	defer func() {
+		metrics.TotalCostDuration.Observe(time.Since(computeStart).Seconds())
+	}()
 
 	// Получаем все подписки, соответствующие фильтрам
 	var subscriptions []models.Subscription
-	dbQuery := db.DB.Model(&models.Subscription{}).
-		Where("user_id = ?", userID)
+	dbQuery := sharing.ForUser(
+		costs.Preload(db.DB.WithContext(c.Request.Context())).Model(&models.Subscription{}),
+		userID,
+	)
 
-	if serviceName != "" {
-		dbQuery = dbQuery.Where("service_name = ?", serviceName)
+	dbQuery, err = filterByService(c, dbQuery)
+	if err != nil {
+		log.WithError(err).Error("Invalid service filter")
+		metrics.TotalCostComputations.WithLabelValues("error").Inc()
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	dbQuery, err = filterByTaxonomy(c, dbQuery)
+	if err != nil {
+		log.WithError(err).Error("Invalid category or tag filter")
+		c.Error(apperr.FromDB(err, nil))
+		return
 	}
 
 	if err := dbQuery.Find(&subscriptions).Error; err != nil {
-		logger.Log.WithError(err).Error("Failed to fetch subscriptions")
-		c.JSON(
-			http.StatusInternalServerError,
-			models.ErrorResponse{Error: "database error"},
-		)
+		log.WithError(err).Error("Failed to fetch subscriptions")
+		metrics.TotalCostComputations.WithLabelValues("error").Inc()
+		c.Error(apperr.FromDB(err, nil))
 		return
 	}
 
 	// Рассчитываем общую стоимость
+	_, span := tracing.Tracer().Start(
+		c.Request.Context(),
+		"subscriptions.calculate_total_cost",
+	)
 	totalCost := 0
 	for _, sub := range subscriptions {
-		// Парсим даты подписки
-		subStart, err := time.Parse("2006-01-02", sub.StartDate)
+		// Стоимость в пересечении периода подписки с запрошенным периодом
+		// с учетом периодичности и без месяцев приостановки
+		cost, err := costs.UserCost(sub, userID, periodStart, periodEnd, defaultEnd)
 		if err != nil {
-			logger.Log.WithError(err).
+			log.WithError(err).
 				WithField("id", sub.ID).
-				Error("Invalid subscription start_date")
+				Error("Invalid subscription dates")
 			continue
 		}
-
-		var subEnd time.Time
-		if sub.EndDate != nil {
-			subEnd, err = time.Parse("2006-01-02", *sub.EndDate)
-			if err != nil {
-				logger.Log.WithError(err).
-					WithField("id", sub.ID).
-					Error("Invalid subscription end_date")
-				continue
-			}
-		} else {
-			subEnd = defaultEnd
-		}
-
-		// Определяем пересечение периода подписки с запрошенным периодом
-		effectiveStart := maxTime(subStart, periodStart)
-		effectiveEnd := minTime(subEnd, periodEnd)
-
-		// Проверяем, есть ли пересечение
-		if effectiveStart.Before(effectiveEnd) ||
-			effectiveStart.Equal(effectiveEnd) {
-			// Рассчитываем количество месяцев
-			months := (effectiveEnd.Year()-effectiveStart.Year())*12 +
-				int(effectiveEnd.Month()-effectiveStart.Month()) + 1
-			if months < 1 {
-				months = 1 // Минимально учитываем один месяц
-			}
-			totalCost += sub.Price * months
-			logger.Log.WithFields(logrus.Fields{
-				"id":     sub.ID,
-				"months": months,
-				"cost":   sub.Price * months,
+		if cost > 0 {
+			totalCost += cost
+			groups.add(sub, cost)
+			log.WithFields(logrus.Fields{
+				"id":   sub.ID,
+				"cost": cost,
 			}).Info("Calculated cost for subscription")
 		}
 	}
 
-	logger.Log.WithField("count", len(subscriptions)).
-		Info("Fetched subscriptions")
-	logger.Log.WithField("total", totalCost).Info("Total cost calculated")
-	c.JSON(http.StatusOK, models.TotalCostResponse{Total: totalCost})
-}
-
-// Вспомогательные функции для выбора максимальной и минимальной даты
-func maxTime(t1, t2 time.Time) time.Time {
-	if t1.After(t2) {
-		return t1
-	}
-	return t2
-}
+	span.SetAttributes(
+		attribute.Int("subscriptions.count", len(subscriptions)),
+		attribute.Int("subscriptions.total_cost", totalCost),
+	)
+	span.End()
 
-func minTime(t1, t2 time.Time) time.Time {
-	if t1.Before(t2) {
-		return t1
-	}
-	return t2
+	log.WithField("count", len(subscriptions)).
+		Info("Fetched subscriptions")
+	log.WithField("total", totalCost).Info("Total cost calculated")
+	metrics.TotalCostComputations.WithLabelValues("ok").Inc()
+	c.JSON(http.StatusOK, models.TotalCostResponse{
+		Total:  totalCost,
+		Groups: groups.list(),
+	})
 }
@@ -1,14 +1,15 @@
-package handlers
+package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,43 +17,67 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/nemopss/subscription-service/internal/billing"
 	"github.com/nemopss/subscription-service/internal/config"
 	"github.com/nemopss/subscription-service/internal/db"
-	"github.com/nemopss/subscription-service/internal/handlers"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
 	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/router"
+	"github.com/nemopss/subscription-service/internal/tenancy"
 )
 
-// setupTestDB подключается к тестовой базе данных и выполняет миграции
-func setupTestDB() {
-	config.LoadConfig("../../.env")
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s dbname=%s password=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_NAME_TEST"),
-		os.Getenv("DB_PASSWORD"),
-	)
+// setupTestDB подключается к тестовой базе данных и выполняет миграции.
+// Если база недоступна, тест завершается ошибкой; пропустить тесты с базой
+// можно только явно, задав SKIP_DB_TESTS=1.
+func setupTestDB(t *testing.T) {
+	if os.Getenv("SKIP_DB_TESTS") == "1" {
+		t.Skip("database tests are disabled by SKIP_DB_TESTS")
+	}
+	cfg, err := config.Load([]string{"-env-file", "../../.env"})
+	if err != nil {
+		t.Fatalf("test database is not configured: %v", err)
+	}
 
-	var err error
-	db.DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db.DB, err = gorm.Open(
+		postgres.Open(cfg.Database.TestDSN()),
+		&gorm.Config{},
+	)
+	if err != nil {
+		t.Fatalf("test database is unavailable: %v", err)
+	}
+	db.DB.Use(tenancy.Plugin{})
+	// Схема создается служебными сырыми запросами, как и миграциями
+	err = db.DB.WithContext(tenancy.System(context.Background())).AutoMigrate(
+		&models.Subscription{},
+		&models.ReminderSettings{},
+		&models.Reminder{},
+		&models.NotificationDelivery{},
+		&models.WebhookEndpoint{},
+		&models.WebhookEvent{},
+		&models.WebhookDelivery{},
+		&models.OutboxEvent{},
+		&models.Pause{},
+		&models.Service{},
+		&models.Category{},
+		&models.Budget{},
+		&models.BudgetAlert{},
+		&models.PriceChange{},
+		&models.Share{},
+		&models.Organization{},
+		&models.Member{},
+	)
 	if err != nil {
-		panic("failed to connect to test database")
+		t.Fatalf("test database migration failed: %v", err)
 	}
-	db.DB.AutoMigrate(&models.Subscription{})
+	// Данные, которые тесты пишут напрямую, принадлежат арендатору по
+	// умолчанию, как и запросы маршрутизатора без разделения арендаторов
+	db.DB = db.DB.WithContext(tenancy.WithTenant(context.Background(), tenancy.Default))
 }
 
 // setupRouter настраивает маршрутизатор Gin для тестов
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
-	r := gin.Default()
-	r.POST("/subscriptions", handlers.CreateSubscription)
-	r.GET("/subscriptions/:id", handlers.GetSubscription)
-	r.PUT("/subscriptions/:id", handlers.UpdateSubscription)
-	r.DELETE("/subscriptions/:id", handlers.DeleteSubscription)
-	r.GET("/subscriptions", handlers.ListSubscriptions)
-	r.GET("/subscriptions/total", handlers.GetTotalCostByPeriod)
-	return r
+	return router.New(config.Default(), router.Deps{})
 }
 
 // withTransaction выполняет тестовую функцию в рамках транзакции и откатывает её после завершения
@@ -75,7 +100,7 @@ func withTransaction(t *testing.T, testFunc func(tx *gorm.DB)) {
 
 // Тест для CreateSubscription
 func TestCreateSubscription(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
@@ -89,7 +114,7 @@ func TestCreateSubscription(t *testing.T) {
 		jsonData, _ := json.Marshal(sub)
 		req, _ := http.NewRequest(
 			"POST",
-			"/subscriptions",
+			"/api/v1/subscriptions",
 			bytes.NewBuffer(jsonData),
 		)
 		req.Header.Set("Content-Type", "application/json")
@@ -105,9 +130,42 @@ func TestCreateSubscription(t *testing.T) {
 	})
 }
 
+// Тест для CreateSubscription с некорректным телом: подробности разбора
+// не попадают в ответ
+func TestCreateSubscriptionMalformedBody(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest(
+		"POST",
+		"/api/v1/subscriptions",
+		bytes.NewBufferString(`{"price":"free"}`),
+	)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_request_body"`)
+	assert.Contains(t, w.Body.String(), `"detail":"request body is malformed"`)
+	assert.NotContains(t, w.Body.String(), "Go struct field")
+}
+
+// Тест для поиска дублей без пользователя: весь арендатор не загружается
+func TestListDuplicatesRequiresUser(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/api/v1/subscriptions/duplicates", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_user_id"`)
+	assert.Contains(t, w.Body.String(), "user_id is required")
+}
+
 // Тест для CreateSubscription с некорректной датой
 func TestCreateSubscriptionInvalidDate(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
@@ -120,7 +178,7 @@ func TestCreateSubscriptionInvalidDate(t *testing.T) {
 		jsonData, _ := json.Marshal(sub)
 		req, _ := http.NewRequest(
 			"POST",
-			"/subscriptions",
+			"/api/v1/subscriptions",
 			bytes.NewBuffer(jsonData),
 		)
 		req.Header.Set("Content-Type", "application/json")
@@ -132,7 +190,7 @@ func TestCreateSubscriptionInvalidDate(t *testing.T) {
 
 // Тест для GetSubscription
 func TestGetSubscription(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
@@ -146,7 +204,7 @@ func TestGetSubscription(t *testing.T) {
 		tx.Create(&sub)
 		req, _ := http.NewRequest(
 			"GET",
-			"/subscriptions/"+strconv.Itoa(sub.ID),
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID),
 			nil,
 		)
 		w := httptest.NewRecorder()
@@ -161,11 +219,11 @@ func TestGetSubscription(t *testing.T) {
 
 // Тест для GetSubscription с несуществующим ID
 func TestGetSubscriptionNotFound(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
-		req, _ := http.NewRequest("GET", "/subscriptions/999", nil)
+		req, _ := http.NewRequest("GET", "/api/v1/subscriptions/999", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 		assert.Equal(t, http.StatusNotFound, w.Code)
@@ -174,7 +232,7 @@ func TestGetSubscriptionNotFound(t *testing.T) {
 
 // Тест для UpdateSubscription
 func TestUpdateSubscription(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
@@ -195,7 +253,7 @@ func TestUpdateSubscription(t *testing.T) {
 		jsonData, _ := json.Marshal(updatedSub)
 		req, _ := http.NewRequest(
 			"PUT",
-			"/subscriptions/"+strconv.Itoa(sub.ID),
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID),
 			bytes.NewBuffer(jsonData),
 		)
 		req.Header.Set("Content-Type", "application/json")
@@ -212,7 +270,7 @@ func TestUpdateSubscription(t *testing.T) {
 
 // Тест для UpdateSubscription с несуществующим ID
 func TestUpdateSubscriptionNotFound(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
@@ -225,7 +283,7 @@ func TestUpdateSubscriptionNotFound(t *testing.T) {
 		jsonData, _ := json.Marshal(updatedSub)
 		req, _ := http.NewRequest(
 			"PUT",
-			"/subscriptions/999",
+			"/api/v1/subscriptions/999",
 			bytes.NewBuffer(jsonData),
 		)
 		req.Header.Set("Content-Type", "application/json")
@@ -237,7 +295,7 @@ func TestUpdateSubscriptionNotFound(t *testing.T) {
 
 // Тест для DeleteSubscription
 func TestDeleteSubscription(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
@@ -251,7 +309,7 @@ func TestDeleteSubscription(t *testing.T) {
 		tx.Create(&sub)
 		req, _ := http.NewRequest(
 			"DELETE",
-			"/subscriptions/"+strconv.Itoa(sub.ID),
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID),
 			nil,
 		)
 		w := httptest.NewRecorder()
@@ -265,11 +323,11 @@ func TestDeleteSubscription(t *testing.T) {
 
 // Тест для DeleteSubscription с несуществующим ID
 func TestDeleteSubscriptionNotFound(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
-		req, _ := http.NewRequest("DELETE", "/subscriptions/999", nil)
+		req, _ := http.NewRequest("DELETE", "/api/v1/subscriptions/999", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 		assert.Equal(t, http.StatusNotFound, w.Code)
@@ -278,7 +336,7 @@ func TestDeleteSubscriptionNotFound(t *testing.T) {
 
 // Тест для ListSubscriptions
 func TestListSubscriptions(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
@@ -299,7 +357,7 @@ func TestListSubscriptions(t *testing.T) {
 				StartDate:   "2025-08-01",
 			},
 		)
-		req, _ := http.NewRequest("GET", "/subscriptions", nil)
+		req, _ := http.NewRequest("GET", "/api/v1/subscriptions", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 		assert.Equal(t, http.StatusOK, w.Code)
@@ -311,7 +369,7 @@ func TestListSubscriptions(t *testing.T) {
 
 // Тест для GetTotalCostByPeriod
 func TestGetTotalCostByPeriod(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
@@ -330,7 +388,7 @@ func TestGetTotalCostByPeriod(t *testing.T) {
 		})
 		req, _ := http.NewRequest(
 			"GET",
-			"/subscriptions/total?user_id="+userID.String()+"&start_date=06-2025&end_date=07-2025",
+			"/api/v1/subscriptions/total?user_id="+userID.String()+"&start_date=06-2025&end_date=07-2025",
 			nil,
 		)
 		w := httptest.NewRecorder()
@@ -345,13 +403,13 @@ func TestGetTotalCostByPeriod(t *testing.T) {
 
 // Тест для GetTotalCostByPeriod с некорректной датой
 func TestGetTotalCostByPeriodInvalidDate(t *testing.T) {
-	setupTestDB()
+	setupTestDB(t)
 	router := setupRouter()
 
 	withTransaction(t, func(tx *gorm.DB) {
 		req, _ := http.NewRequest(
 			"GET",
-			"/subscriptions/total?user_id="+uuid.New().
+			"/api/v1/subscriptions/total?user_id="+uuid.New().
 				String()+
 				"&start_date=invalid-date",
 			nil,
@@ -361,3 +419,967 @@ func TestGetTotalCostByPeriodInvalidDate(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 }
+
+// Тест для фильтра ListSubscriptions по состоянию
+func TestListSubscriptionsStatusFilter(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		userID := uuid.New()
+		tx.Create(&models.Subscription{
+			ServiceName: "Yandex Plus",
+			Price:       400,
+			UserID:      userID,
+			StartDate:   "2025-07-01",
+			Status:      lifecycle.Paused,
+		})
+		tx.Create(&models.Subscription{
+			ServiceName: "Netflix",
+			Price:       600,
+			UserID:      userID,
+			StartDate:   "2025-08-01",
+			Status:      lifecycle.Active,
+		})
+		req, _ := http.NewRequest("GET", "/api/v1/subscriptions?status=paused", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var subs []models.Subscription
+		json.Unmarshal(w.Body.Bytes(), &subs)
+		for _, s := range subs {
+			assert.Equal(t, lifecycle.Paused, s.Status)
+		}
+		assert.NotEmpty(t, subs)
+
+		req, _ = http.NewRequest("GET", "/api/v1/subscriptions?status=archived", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// Тест для UpdateSubscription с запрещенным переходом состояния
+func TestUpdateSubscriptionInvalidStatusTransition(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		userID := uuid.New()
+		sub := models.Subscription{
+			ServiceName: "Yandex Plus",
+			Price:       400,
+			UserID:      userID,
+			StartDate:   "2025-07-01",
+			Status:      lifecycle.Cancelled,
+		}
+		tx.Create(&sub)
+		jsonData, _ := json.Marshal(models.CreateSubscription{
+			ServiceName: "Yandex Plus",
+			Price:       400,
+			UserID:      userID,
+			StartDate:   "07-2025",
+			Status:      lifecycle.Paused,
+		})
+		req, _ := http.NewRequest(
+			"PUT",
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID),
+			bytes.NewBuffer(jsonData),
+		)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_status_transition")
+	})
+}
+
+// Тест для UpdateSubscription: отмена и приостановка только своими
+// эндпоинтами, end_date отмены сохраняется
+func TestUpdateSubscriptionCancelledAndPaused(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		userID := uuid.New()
+		put := func(id int, body models.CreateSubscription) *httptest.ResponseRecorder {
+			jsonData, _ := json.Marshal(body)
+			req, _ := http.NewRequest(
+				"PUT",
+				"/api/v1/subscriptions/"+strconv.Itoa(id),
+				bytes.NewBuffer(jsonData),
+			)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		active := models.Subscription{
+			ServiceName: "Yandex Plus",
+			Price:       400,
+			UserID:      userID,
+			StartDate:   "2025-07-01",
+			Status:      lifecycle.Active,
+		}
+		tx.Create(&active)
+		for _, status := range []lifecycle.Status{lifecycle.Cancelled, lifecycle.Paused} {
+			w := put(active.ID, models.CreateSubscription{
+				ServiceName: "Yandex Plus",
+				Price:       400,
+				UserID:      userID,
+				StartDate:   "07-2025",
+				Status:      status,
+			})
+			assert.Equal(t, http.StatusConflict, w.Code, status)
+			assert.Contains(t, w.Body.String(), "/cancel")
+		}
+
+		end := "2025-12-01"
+		before := "2026-06-01"
+		cancelled := models.Subscription{
+			ServiceName:         "Netflix",
+			Price:               600,
+			UserID:              userID,
+			StartDate:           "2025-07-01",
+			EndDate:             &end,
+			EndDateBeforeCancel: &before,
+			Status:              lifecycle.Cancelled,
+		}
+		tx.Create(&cancelled)
+		w := put(cancelled.ID, models.CreateSubscription{
+			ServiceName: "Netflix",
+			Price:       700,
+			UserID:      userID,
+			StartDate:   "07-2025",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got models.Subscription
+		tx.First(&got, cancelled.ID)
+		assert.Equal(t, 700, got.Price)
+		if assert.NotNil(t, got.EndDate) {
+			assert.Equal(t, end, *got.EndDate)
+		}
+	})
+}
+
+// Тест для UpdateSubscription: истекшая подписка получает end_date
+func TestUpdateSubscriptionExpiredSetsEndDate(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		userID := uuid.New()
+		sub := models.Subscription{
+			ServiceName: "Yandex Plus",
+			Price:       400,
+			UserID:      userID,
+			StartDate:   "2025-07-01",
+			Status:      lifecycle.Active,
+		}
+		tx.Create(&sub)
+		jsonData, _ := json.Marshal(models.CreateSubscription{
+			ServiceName: "Yandex Plus",
+			Price:       400,
+			UserID:      userID,
+			StartDate:   "07-2025",
+			Status:      lifecycle.Expired,
+		})
+		req, _ := http.NewRequest(
+			"PUT",
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID),
+			bytes.NewBuffer(jsonData),
+		)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var got models.Subscription
+		tx.First(&got, sub.ID)
+		assert.Equal(t, lifecycle.Expired, got.Status)
+		if assert.NotNil(t, got.EndDate) {
+			current := billing.MonthStart(billing.MonthIndex(time.Now().UTC()))
+			assert.Equal(t, current.Format(billing.DateLayout), *got.EndDate)
+		}
+	})
+}
+
+// Тест для PauseSubscription и ResumeSubscription
+func TestPauseAndResumeSubscription(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		sub := models.Subscription{
+			ServiceName: "World Class",
+			Price:       3000,
+			UserID:      uuid.New(),
+			StartDate:   "2025-01-01",
+			Status:      lifecycle.Active,
+		}
+		tx.Create(&sub)
+		base := "/api/v1/subscriptions/" + strconv.Itoa(sub.ID)
+
+		req, _ := http.NewRequest("POST", base+"/pause", bytes.NewBufferString(`{"start_date":"03-2025"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Приостановка с будущего месяца запрещена
+		future := billing.MonthStart(billing.MonthIndex(time.Now().UTC()) + 1).Format("01-2006")
+		req, _ = http.NewRequest("POST", base+"/pause", bytes.NewBufferString(`{"start_date":"`+future+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_pause")
+
+		// Повторная приостановка запрещена
+		req, _ = http.NewRequest("POST", base+"/pause", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		req, _ = http.NewRequest("POST", base+"/resume", bytes.NewBufferString(`{"resume_date":"05-2025"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var got models.Subscription
+		json.Unmarshal(w.Body.Bytes(), &got)
+		assert.Equal(t, lifecycle.Active, got.Status)
+		if assert.Len(t, got.Pauses, 1) {
+			assert.Equal(t, "2025-03-01", got.Pauses[0].StartDate)
+			assert.Equal(t, "2025-05-01", *got.Pauses[0].EndDate)
+		}
+
+		// Март и апрель не оплачиваются
+		req, _ = http.NewRequest(
+			"GET",
+			"/api/v1/subscriptions/total?user_id="+sub.UserID.String()+"&start_date=01-2025&end_date=06-2025",
+			nil,
+		)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var total models.TotalCostResponse
+		json.Unmarshal(w.Body.Bytes(), &total)
+		assert.Equal(t, 4*3000, total.Total)
+	})
+}
+
+// Тест для CancelSubscription в конце периода и UncancelSubscription
+func TestCancelAndUncancelSubscription(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		sub := models.Subscription{
+			ServiceName: "Netflix",
+			Price:       600,
+			UserID:      uuid.New(),
+			StartDate:   "2025-01-01",
+			Status:      lifecycle.Active,
+		}
+		tx.Create(&sub)
+		base := "/api/v1/subscriptions/" + strconv.Itoa(sub.ID)
+
+		req, _ := http.NewRequest("POST", base+"/cancel", bytes.NewBufferString(`{"reason":"too expensive"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var got models.Subscription
+		json.Unmarshal(w.Body.Bytes(), &got)
+		assert.Equal(t, lifecycle.Cancelled, got.Status)
+		assert.True(t, got.CancelAtPeriodEnd)
+		assert.Equal(t, "too expensive", got.CancelReason)
+		assert.NotNil(t, got.CancelledAt)
+		assert.NotNil(t, got.EndDate)
+
+		req, _ = http.NewRequest("POST", base+"/uncancel", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		got = models.Subscription{}
+		json.Unmarshal(w.Body.Bytes(), &got)
+		assert.Equal(t, lifecycle.Active, got.Status)
+		assert.Nil(t, got.EndDate)
+		assert.Nil(t, got.CancelledAt)
+	})
+}
+
+// Тест для немедленной отмены: отозвать ее нельзя
+func TestCancelSubscriptionImmediately(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		sub := models.Subscription{
+			ServiceName: "Netflix",
+			Price:       600,
+			UserID:      uuid.New(),
+			StartDate:   "2025-01-01",
+			Status:      lifecycle.Active,
+		}
+		tx.Create(&sub)
+		base := "/api/v1/subscriptions/" + strconv.Itoa(sub.ID)
+
+		req, _ := http.NewRequest("POST", base+"/cancel", bytes.NewBufferString(`{"mode":"immediate"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"expired"`)
+
+		req, _ = http.NewRequest("POST", base+"/uncancel", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+// Тест для немедленной отмены еще не начавшейся подписки: end_date не
+// раньше start_date
+func TestCancelFutureSubscriptionImmediately(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		start := billing.MonthStart(billing.MonthIndex(time.Now().UTC()) + 2).Format(billing.DateLayout)
+		sub := models.Subscription{
+			ServiceName: "Netflix",
+			Price:       600,
+			UserID:      uuid.New(),
+			StartDate:   start,
+			Status:      lifecycle.Active,
+		}
+		tx.Create(&sub)
+
+		req, _ := http.NewRequest(
+			"POST",
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID)+"/cancel",
+			bytes.NewBufferString(`{"mode":"immediate"}`),
+		)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var got models.Subscription
+		tx.First(&got, sub.ID)
+		assert.Equal(t, lifecycle.Expired, got.Status)
+		if assert.NotNil(t, got.EndDate) {
+			assert.Equal(t, start, *got.EndDate)
+		}
+	})
+}
+
+// Тест для фильтра стоимости по сервису каталога с разными написаниями
+func TestGetTotalCostByCatalogService(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		jsonData, _ := json.Marshal(models.CreateService{
+			Name:    "Yandex Plus Test",
+			Aliases: []string{"Яндекс Плюс Тест"},
+		})
+		req, _ := http.NewRequest("POST", "/api/v1/services", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		userID := uuid.New()
+		endDate := "07-2025"
+		for _, name := range []string{"yandex plus test", "Яндекс  Плюс Тест"} {
+			jsonData, _ := json.Marshal(models.CreateSubscription{
+				ServiceName: name,
+				Price:       400,
+				UserID:      userID,
+				StartDate:   "07-2025",
+				EndDate:     &endDate,
+			})
+			req, _ := http.NewRequest("POST", "/api/v1/subscriptions", bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Contains(t, w.Body.String(), `"service_id"`)
+		}
+
+		req, _ = http.NewRequest(
+			"GET",
+			"/api/v1/subscriptions/total?user_id="+userID.String()+"&service_name=Yandex%20Plus%20Test",
+			nil,
+		)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var total models.TotalCostResponse
+		json.Unmarshal(w.Body.Bytes(), &total)
+		assert.Equal(t, 800, total.Total)
+	})
+}
+
+// Тест для фильтра по категории с подкатегориями и группировки стоимости
+func TestCategoriesAndTags(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		entertainment := models.Category{Name: "Entertainment"}
+		tx.Create(&entertainment)
+		streaming := models.Category{Name: "Streaming", ParentID: &entertainment.ID}
+		tx.Create(&streaming)
+		work := models.Category{Name: "Work"}
+		tx.Create(&work)
+
+		userID := uuid.New()
+		endDate := "2025-07-01"
+		for _, sub := range []models.Subscription{
+			{ServiceName: "Netflix", Price: 600, CategoryID: &streaming.ID, Tags: []string{"family"}},
+			{ServiceName: "Steam", Price: 300, CategoryID: &entertainment.ID},
+			{ServiceName: "GitHub", Price: 400, CategoryID: &work.ID, Tags: []string{"work", "family"}},
+		} {
+			sub.UserID = userID
+			sub.StartDate = "2025-07-01"
+			sub.EndDate = &endDate
+			tx.Create(&sub)
+		}
+
+		req, _ := http.NewRequest(
+			"GET",
+			"/api/v1/subscriptions?category_id="+strconv.Itoa(entertainment.ID),
+			nil,
+		)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var subs []models.Subscription
+		json.Unmarshal(w.Body.Bytes(), &subs)
+		assert.Len(t, subs, 2)
+
+		req, _ = http.NewRequest(
+			"GET",
+			"/api/v1/subscriptions/total?user_id="+userID.String()+"&group_by=category",
+			nil,
+		)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var total models.TotalCostResponse
+		json.Unmarshal(w.Body.Bytes(), &total)
+		assert.Equal(t, 1300, total.Total)
+		if assert.Len(t, total.Groups, 2) {
+			assert.Equal(t, "Entertainment", total.Groups[0].Key)
+			assert.Equal(t, 900, total.Groups[0].Total)
+		}
+
+		req, _ = http.NewRequest(
+			"GET",
+			"/api/v1/subscriptions/total?user_id="+userID.String()+"&tag=family",
+			nil,
+		)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		json.Unmarshal(w.Body.Bytes(), &total)
+		assert.Equal(t, 1000, total.Total)
+	})
+}
+
+// Тест для бюджетов: отчет и оповещение о превышении
+func TestBudgetReportAndAlert(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		userID := uuid.New()
+		budgetsURL := "/api/v1/users/" + userID.String() + "/budgets"
+
+		jsonData, _ := json.Marshal(models.CreateBudget{Scope: "overall", Amount: 500})
+		req, _ := http.NewRequest("POST", budgetsURL, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		// Второй общий бюджет пользователю не нужен
+		req, _ = http.NewRequest("POST", budgetsURL, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		jsonData, _ = json.Marshal(models.CreateSubscription{
+			ServiceName: "Netflix",
+			Price:       600,
+			UserID:      userID,
+			StartDate:   time.Now().UTC().Format("01-2006"),
+		})
+		req, _ = http.NewRequest("POST", "/api/v1/subscriptions", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var alerts int64
+		tx.Model(&models.BudgetAlert{}).Where("user_id = ?", userID).Count(&alerts)
+		assert.Equal(t, int64(1), alerts)
+
+		req, _ = http.NewRequest("GET", budgetsURL+"/report", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var report models.BudgetReport
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if assert.Len(t, report.Budgets, 1) {
+			assert.Equal(t, 600, report.Budgets[0].Spent)
+			assert.True(t, report.Budgets[0].Over)
+		}
+	})
+}
+
+// Тест для прогноза с запланированным изменением цены
+func TestForecastWithPriceChange(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		now := time.Now().UTC()
+		sub := models.Subscription{
+			ServiceName: "Spotify",
+			Price:       300,
+			UserID:      uuid.New(),
+			StartDate:   time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+			Status:      lifecycle.Active,
+		}
+		tx.Create(&sub)
+
+		body := `{"effective_date":"` + now.AddDate(0, 2, 1-now.Day()).Format("01-2006") + `","price":400}`
+		req, _ := http.NewRequest(
+			"POST",
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID)+"/price-changes",
+			bytes.NewBufferString(body),
+		)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		req, _ = http.NewRequest(
+			"GET",
+			"/api/v1/subscriptions/forecast?months=4&user_id="+sub.UserID.String(),
+			nil,
+		)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var forecast models.ForecastResponse
+		json.Unmarshal(w.Body.Bytes(), &forecast)
+		if assert.Len(t, forecast.Months, 4) {
+			assert.Equal(t, 300, forecast.Months[1].Total)
+			assert.Equal(t, 400, forecast.Months[2].Total)
+		}
+		assert.Equal(t, 300+300+400+400, forecast.Total)
+	})
+}
+
+// Тест для предупреждения о дубле при создании и поиска дублей
+func TestDuplicateSubscriptions(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		userID := uuid.New()
+		existing := models.Subscription{
+			ServiceName: "Netflix",
+			Price:       600,
+			UserID:      userID,
+			StartDate:   "2025-01-01",
+		}
+		tx.Create(&existing)
+
+		jsonData, _ := json.Marshal(models.CreateSubscription{
+			ServiceName: "  netflix ",
+			Price:       600,
+			UserID:      userID,
+			StartDate:   "03-2025",
+		})
+		req, _ := http.NewRequest("POST", "/api/v1/subscriptions", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var created models.CreatedSubscription
+		json.Unmarshal(w.Body.Bytes(), &created)
+		assert.NotZero(t, created.ID)
+		if assert.Len(t, created.Warnings, 1) {
+			assert.Equal(t, "possible_duplicate", created.Warnings[0].Code)
+			assert.Equal(t, []int{existing.ID}, created.Warnings[0].SubscriptionIDs)
+		}
+
+		req, _ = http.NewRequest("GET", "/api/v1/subscriptions/duplicates?user_id="+userID.String(), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var groups []models.DuplicateGroup
+		json.Unmarshal(w.Body.Bytes(), &groups)
+		if assert.Len(t, groups, 1) {
+			assert.Len(t, groups[0].Subscriptions, 2)
+		}
+	})
+}
+
+// Тест для совместной подписки: стоимость делится между участниками
+func TestShareSubscription(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		owner, member := uuid.New(), uuid.New()
+		endDate := "2025-02-01"
+		sub := models.Subscription{
+			ServiceName: "YouTube Premium Family",
+			Price:       900,
+			UserID:      owner,
+			StartDate:   "2025-01-01",
+			EndDate:     &endDate,
+		}
+		tx.Create(&sub)
+
+		body := `{"split_rule":"percentage","members":[{"user_id":"` + member.String() + `","percent":40}]}`
+		req, _ := http.NewRequest(
+			"PUT",
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID)+"/shares",
+			bytes.NewBufferString(body),
+		)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		for userID, want := range map[uuid.UUID]int{owner: 2 * 540, member: 2 * 360} {
+			req, _ = http.NewRequest("GET", "/api/v1/subscriptions/total?user_id="+userID.String(), nil)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var total models.TotalCostResponse
+			json.Unmarshal(w.Body.Bytes(), &total)
+			assert.Equal(t, want, total.Total)
+		}
+
+		// Сумма процентов больше 100
+		body = `{"split_rule":"percentage","members":[{"user_id":"` + member.String() + `","percent":140}]}`
+		req, _ = http.NewRequest(
+			"PUT",
+			"/api/v1/subscriptions/"+strconv.Itoa(sub.ID)+"/shares",
+			bytes.NewBufferString(body),
+		)
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+// Тест для CreateSubscription: разделение стоимости и изменения цены из
+// тела запроса не сохраняются в обход их проверок
+func TestCreateSubscriptionIgnoresAssociations(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		owner := uuid.New()
+		body := `{"service_name":"Netflix","price":500,"user_id":"` + owner.String() + `",` +
+			`"start_date":"07-2025","split_rule":"bogus",` +
+			`"shares":[{"user_id":"` + owner.String() + `","percent":400}],` +
+			`"price_changes":[{"effective_date":"2020-01-01","price":-100}]}`
+		req, _ := http.NewRequest("POST", "/api/v1/subscriptions", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var created models.Subscription
+		json.Unmarshal(w.Body.Bytes(), &created)
+		assert.Empty(t, created.SplitRule)
+		assert.Empty(t, created.Shares)
+		assert.Empty(t, created.PriceChanges)
+
+		var shares, changes int64
+		tx.Model(&models.Share{}).Where("subscription_id = ?", created.ID).Count(&shares)
+		tx.Model(&models.PriceChange{}).Where("subscription_id = ?", created.ID).Count(&changes)
+		assert.Zero(t, shares)
+		assert.Zero(t, changes)
+	})
+}
+
+// Тест для организации: участники и расходы по участникам
+func TestOrganizationTotal(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		owner, employee := uuid.New(), uuid.New()
+		jsonData, _ := json.Marshal(models.CreateOrganization{Name: "Acme", OwnerID: owner})
+		req, _ := http.NewRequest("POST", "/api/v1/organizations", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var org models.Organization
+		json.Unmarshal(w.Body.Bytes(), &org)
+		base := "/api/v1/organizations/" + strconv.Itoa(org.ID)
+
+		req, _ = http.NewRequest("PUT", base+"/members/"+employee.String(), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Единственного владельца нельзя удалить
+		req, _ = http.NewRequest("DELETE", base+"/members/"+owner.String(), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		endDate := "2025-01-01"
+		for _, sub := range []models.Subscription{
+			{ServiceName: "Slack", Price: 500, UserID: owner},
+			{ServiceName: "Slack", Price: 500, UserID: employee},
+			{ServiceName: "Figma", Price: 700, UserID: employee},
+			{ServiceName: "Figma", Price: 700, UserID: uuid.New()},
+		} {
+			sub.StartDate = "2025-01-01"
+			sub.EndDate = &endDate
+			tx.Create(&sub)
+		}
+
+		req, _ = http.NewRequest("GET", base+"/total", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var total models.OrganizationTotal
+		json.Unmarshal(w.Body.Bytes(), &total)
+		assert.Equal(t, 1700, total.Total)
+		if assert.Len(t, total.Members, 2) {
+			assert.Equal(t, employee, total.Members[0].UserID)
+			assert.Equal(t, 1200, total.Members[0].Total)
+		}
+		if assert.Len(t, total.Services, 2) {
+			assert.Equal(t, "Slack", total.Services[0].ServiceName)
+			assert.Equal(t, 1000, total.Services[0].Total)
+		}
+	})
+}
+
+// Тест для изоляции арендаторов: данные одного арендатора не видны и не
+// изменяемы другим
+func TestTenantIsolation(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.Tenancy.Enabled = true
+	cfg.Tenancy.HostSuffix = ".subs.test"
+	router := router.New(cfg, router.Deps{})
+
+	do := func(method, host, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Host = host
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	withTransaction(t, func(tx *gorm.DB) {
+		userID := uuid.New()
+		w := do("POST", "acme.subs.test", "/api/v1/subscriptions", models.CreateSubscription{
+			ServiceName: "Netflix",
+			Price:       800,
+			UserID:      userID,
+			StartDate:   "01-2025",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var sub models.Subscription
+		json.Unmarshal(w.Body.Bytes(), &sub)
+		path := "/api/v1/subscriptions/" + strconv.Itoa(sub.ID)
+
+		// Без арендатора запрос отклоняется
+		w = do("GET", "localhost", path, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"tenant_required"`)
+
+		assert.Equal(t, http.StatusOK, do("GET", "acme.subs.test", path, nil).Code)
+		assert.Equal(t, http.StatusNotFound, do("GET", "other.subs.test", path, nil).Code)
+		assert.Equal(t, http.StatusNotFound, do("PUT", "other.subs.test", path, models.CreateSubscription{
+			ServiceName: "Netflix",
+			Price:       1,
+			UserID:      userID,
+			StartDate:   "01-2025",
+		}).Code)
+		assert.Equal(t, http.StatusNotFound, do("DELETE", "other.subs.test", path, nil).Code)
+
+		w = do("GET", "other.subs.test", "/api/v1/subscriptions?user_id="+userID.String(), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var list []models.Subscription
+		json.Unmarshal(w.Body.Bytes(), &list)
+		assert.Empty(t, list)
+
+		w = do("GET", "other.subs.test", "/api/v1/subscriptions/total?user_id="+userID.String(), nil)
+		var total models.TotalCostResponse
+		json.Unmarshal(w.Body.Bytes(), &total)
+		assert.Zero(t, total.Total)
+
+		// Сервис каталога из чужого арендатора не мешает создать свой с тем
+		// же названием
+		w = do("POST", "other.subs.test", "/api/v1/services", models.CreateService{Name: "Netflix"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		w = do("GET", "acme.subs.test", path, nil)
+		json.Unmarshal(w.Body.Bytes(), &sub)
+		assert.Equal(t, 800, sub.Price)
+		assert.Nil(t, sub.ServiceID)
+	})
+}
+
+// Тест для аналитики совместных подписок: пользователю засчитывается его
+// доля, как в /subscriptions/total
+func TestAnalyticsShares(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		owner, member := uuid.New(), uuid.New()
+		end := "2025-03-01"
+		tx.Create(&models.Subscription{
+			ServiceName: "Kinopoisk",
+			Price:       301,
+			UserID:      owner,
+			StartDate:   "2025-01-01",
+			EndDate:     &end,
+			Status:      lifecycle.Active,
+			SplitRule:   "equal",
+			Shares:      []models.Share{{UserID: member}},
+		})
+
+		get := func(path string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", "/api/v1/"+path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+		for user, want := range map[uuid.UUID]int{owner: 3 * 151, member: 3 * 150} {
+			period := "start_date=01-2025&end_date=03-2025&user_id=" + user.String()
+
+			w := get("subscriptions/total?" + period)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var total models.TotalCostResponse
+			json.Unmarshal(w.Body.Bytes(), &total)
+			assert.Equal(t, want, total.Total)
+
+			w = get("analytics/spend?" + period)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var spend models.SpendTrend
+			json.Unmarshal(w.Body.Bytes(), &spend)
+			assert.Equal(t, total.Total, spend.Total)
+
+			w = get("analytics/top-services?" + period)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var top models.TopServices
+			json.Unmarshal(w.Body.Bytes(), &top)
+			if assert.Len(t, top.Services, 1) {
+				assert.Equal(t, want, top.Services[0].Total)
+			}
+		}
+	})
+}
+
+// Тест для аналитики расходов и оттока
+func TestAnalytics(t *testing.T) {
+	setupTestDB(t)
+	router := setupRouter()
+
+	withTransaction(t, func(tx *gorm.DB) {
+		userID := uuid.New()
+		end := "2025-03-01"
+		tx.Create(&models.Subscription{
+			ServiceName: "Netflix",
+			Price:       500,
+			UserID:      userID,
+			StartDate:   "2025-01-01",
+			EndDate:     &end,
+			Status:      lifecycle.Active,
+		})
+		tx.Create(&models.Subscription{
+			ServiceName: "Spotify",
+			Price:       300,
+			UserID:      userID,
+			StartDate:   "2025-02-01",
+			Status:      lifecycle.Active,
+		})
+		query := "?start_date=01-2025&end_date=03-2025&user_id=" + userID.String()
+
+		get := func(path string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", "/api/v1/analytics/"+path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := get("spend" + query)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var spend models.SpendTrend
+		json.Unmarshal(w.Body.Bytes(), &spend)
+		assert.Equal(t, 500+800+800, spend.Total)
+		if assert.Len(t, spend.Months, 3) {
+			assert.Nil(t, spend.Months[0].ChangePercent)
+			assert.Equal(t, 300, spend.Months[1].Change)
+			if assert.NotNil(t, spend.Months[1].ChangePercent) {
+				assert.Equal(t, 60.0, *spend.Months[1].ChangePercent)
+			}
+		}
+
+		w = get("top-services" + query + "&limit=1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var top models.TopServices
+		json.Unmarshal(w.Body.Bytes(), &top)
+		if assert.Len(t, top.Services, 1) {
+			assert.Equal(t, "Netflix", top.Services[0].ServiceName)
+			assert.Equal(t, 1500, top.Services[0].Total)
+		}
+
+		w = get("average-price" + query)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var avg models.AveragePrice
+		json.Unmarshal(w.Body.Bytes(), &avg)
+		assert.Equal(t, 2, avg.Subscriptions)
+		assert.Equal(t, 400.0, avg.AveragePrice)
+
+		w = get("churn" + query)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var churn models.ChurnReport
+		json.Unmarshal(w.Body.Bytes(), &churn)
+		if assert.Len(t, churn.Months, 3) {
+			assert.Equal(t, 1, churn.Months[1].Started)
+			assert.Equal(t, 2, churn.Months[1].Active)
+			assert.Equal(t, 1, churn.Months[2].Ended)
+			assert.Equal(t, 2, churn.Months[2].Active)
+			assert.Equal(t, 0.5, churn.Months[2].ChurnRate)
+		}
+
+		w = get("spend?start_date=04-2025&end_date=03-2025")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"invalid_analytics"`)
+	})
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/db"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/webhooks"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+var (
+	errWebhookNotFound = apperr.NotFound(
+		apperr.CodeWebhookNotFound,
+		"webhook endpoint not found",
+	)
+	errWebhookDeliveryNotFound = apperr.NotFound(
+		apperr.CodeWebhookDeliveryNotFound,
+		"webhook delivery not found",
+	)
+)
+
+// @Summary      Register a webhook endpoint
+// @Description  Registers a URL that receives signed subscription lifecycle events. The signing secret is returned only in this response.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        webhook  body      models.CreateWebhookEndpoint  true  "Webhook endpoint"
+// @Success      201      {object}  models.CreatedWebhookEndpoint
+// @Failure      400      {object}  models.Problem
+// @Failure      503      {object}  models.Problem
+// @Router       /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	var req models.CreateWebhookEndpoint
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.WithError(err).Error("Invalid request body")
+		c.Error(invalidBody(err))
+		return
+	}
+	if err := webhooks.ValidateEndpoint(req.URL, req.Events); err != nil {
+		log.WithError(err).Error("Invalid webhook endpoint")
+		c.Error(apperr.Validation(apperr.CodeInvalidWebhook, err.Error()))
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		c.Error(apperr.Internal(err))
+		return
+	}
+	if req.Events == nil {
+		req.Events = []string{}
+	}
+	endpoint := models.WebhookEndpoint{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Secret:      secret,
+		Active:      true,
+	}
+	if err := db.DB.WithContext(c.Request.Context()).Create(&endpoint).Error; err != nil {
+		log.WithError(err).Error("Failed to create webhook endpoint")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+
+	log.WithField("webhook_id", endpoint.ID).Info("Webhook endpoint registered")
+	c.JSON(http.StatusCreated, models.CreatedWebhookEndpoint{
+		WebhookEndpoint: endpoint,
+		Secret:          secret,
+	})
+}
+
+// @Summary      List webhook endpoints
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {array}   models.WebhookEndpoint
+// @Failure      503  {object}  models.Problem
+// @Router       /webhooks [get]
+func ListWebhooks(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	var endpoints []models.WebhookEndpoint
+	if err := db.DB.WithContext(c.Request.Context()).Order("id").Find(&endpoints).Error; err != nil {
+		log.WithError(err).Error("Failed to list webhook endpoints")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, endpoints)
+}
+
+// @Summary      Get a webhook endpoint
+// @Tags         webhooks
+// @Produce      json
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      200  {object}  models.WebhookEndpoint
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /webhooks/{id} [get]
+func GetWebhook(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidID)
+		return
+	}
+	var endpoint models.WebhookEndpoint
+	if err := db.DB.WithContext(c.Request.Context()).First(&endpoint, id).Error; err != nil {
+		log.WithError(err).Error("Failed to get webhook endpoint")
+		c.Error(apperr.FromDB(err, errWebhookNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// @Summary      Delete a webhook endpoint
+// @Description  Deletes the endpoint together with its delivery history
+// @Tags         webhooks
+// @Param        id   path  int  true  "Webhook ID"
+// @Success      204
+// @Failure      400  {object}  models.Problem
+// @Failure      404  {object}  models.Problem
+// @Router       /webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidID)
+		return
+	}
+	result := db.DB.WithContext(c.Request.Context()).Delete(&models.WebhookEndpoint{}, id)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Failed to delete webhook endpoint")
+		c.Error(apperr.FromDB(result.Error, nil))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.Error(errWebhookNotFound)
+		return
+	}
+	log.WithField("webhook_id", id).Info("Webhook endpoint deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      List deliveries of a webhook endpoint
+// @Description  Returns the latest deliveries, newest first, optionally filtered by status
+// @Tags         webhooks
+// @Produce      json
+// @Param        id      path      int     true   "Webhook ID"
+// @Param        status  query     string  false  "Delivery status"  Enums(pending, delivered, dead)
+// @Success      200     {array}   models.WebhookDelivery
+// @Failure      400     {object}  models.Problem
+// @Failure      404     {object}  models.Problem
+// @Router       /webhooks/{id}/deliveries [get]
+func ListWebhookDeliveries(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidID)
+		return
+	}
+	tx := db.DB.WithContext(c.Request.Context())
+	if err := tx.Select("id").First(&models.WebhookEndpoint{}, id).Error; err != nil {
+		c.Error(apperr.FromDB(err, errWebhookNotFound))
+		return
+	}
+
+	query := tx.Preload("Event").
+		Where("endpoint_id = ?", id).
+		Order("id DESC").
+		Limit(100)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		log.WithError(err).Error("Failed to list webhook deliveries")
+		c.Error(apperr.FromDB(err, nil))
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary      Redeliver a webhook event
+// @Description  Queues the event of a past delivery for immediate redelivery to the same endpoint. The original delivery is kept as history.
+// @Tags         webhooks
+// @Produce      json
+// @Param        id           path      int  true  "Webhook ID"
+// @Param        delivery_id  path      int  true  "Delivery ID"
+// @Success      202          {object}  models.WebhookDelivery
+// @Failure      400          {object}  models.Problem
+// @Failure      404          {object}  models.Problem
+// @Router       /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func RedeliverWebhook(c *gin.Context) {
+	log := logger.FromContext(c.Request.Context())
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errInvalidID)
+		return
+	}
+	deliveryID, err := strconv.Atoi(c.Param("delivery_id"))
+	if err != nil {
+		c.Error(errInvalidID)
+		return
+	}
+
+	var redelivery models.WebhookDelivery
+	err = db.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var original models.WebhookDelivery
+		err := tx.Where("endpoint_id = ?", id).First(&original, deliveryID).Error
+		if err != nil {
+			return err
+		}
+		redelivery = models.WebhookDelivery{
+			EndpointID:    original.EndpointID,
+			EventID:       original.EventID,
+			Status:        webhooks.StatusPending,
+			NextAttemptAt: time.Now().UTC(),
+		}
+		return tx.Create(&redelivery).Error
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to redeliver webhook")
+		c.Error(apperr.FromDB(err, errWebhookDeliveryNotFound))
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"webhook_id":  id,
+		"delivery_id": redelivery.ID,
+		"event_id":    redelivery.EventID,
+	}).Info("Webhook redelivery queued")
+	c.JSON(http.StatusAccepted, redelivery)
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc проверяет одну зависимость и возвращает ошибку, если она
+// недоступна
+type CheckFunc func(ctx context.Context) error
+
+// Result — результат одной проверки
+type Result struct {
+	Status    string  `json:"status"          example:"ok"`
+	LatencyMs float64 `json:"latency_ms"      example:"1.25"`
+	Error     string  `json:"error,omitempty" example:"connection refused"`
+}
+
+// Report — сводный результат всех проверок
+type Report struct {
+	Status string            `json:"status"  example:"ok"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker выполняет набор именованных проверок готовности
+type Checker struct {
+	timeout time.Duration
+	checks  []check
+}
+
+// NewChecker создает Checker, ограничивающий каждую проверку таймаутом
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку. Вызывается до начала обработки запросов.
+func (h *Checker) Add(name string, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, fn: fn})
+}
+
+// Run выполняет все проверки параллельно
+func (h *Checker) Run(ctx context.Context) Report {
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(h.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			res := h.run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = res
+			if res.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (h *Checker) run(ctx context.Context, c check) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	res := Result{
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/health"
+)
+
+// Тест для успешных проверок
+func TestCheckerAllOK(t *testing.T) {
+	h := health.NewChecker(time.Second)
+	h.Add("database", func(ctx context.Context) error { return nil })
+	h.Add("workers", func(ctx context.Context) error { return nil })
+
+	report := h.Run(context.Background())
+
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+}
+
+// Тест для неуспешной проверки
+func TestCheckerFailure(t *testing.T) {
+	h := health.NewChecker(time.Second)
+	h.Add("database", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	h.Add("workers", func(ctx context.Context) error { return nil })
+
+	report := h.Run(context.Background())
+
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	assert.Equal(t, health.StatusOK, report.Checks["workers"].Status)
+}
+
+// Тест для проверки, превысившей таймаут
+func TestCheckerTimeout(t *testing.T) {
+	h := health.NewChecker(10 * time.Millisecond)
+	h.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := h.Run(context.Background())
+
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
@@ -0,0 +1,143 @@
+package lifecycle
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Status — состояние подписки
+type Status string
+
+const (
+	Active    Status = "active"
+	Trialing  Status = "trialing"
+	Paused    Status = "paused"
+	Cancelled Status = "cancelled"
+	Expired   Status = "expired"
+)
+
+// Statuses — все состояния в порядке жизненного цикла
+var Statuses = []Status{Trialing, Active, Paused, Cancelled, Expired}
+
+// transitions — разрешенные переходы. Истечение возможно из любого
+// состояния, кроме самого истечения; истекшую подписку можно возобновить.
+// Отмененная подписка действует до конца оплаченного периода, и до этого
+// отмену можно отозвать.
+var transitions = map[Status][]Status{
+	Trialing:  {Active, Cancelled, Expired},
+	Active:    {Paused, Cancelled, Expired},
+	Paused:    {Active, Cancelled, Expired},
+	Cancelled: {Active, Expired},
+	Expired:   {Active},
+}
+
+// ParseStatus проверяет состояние
+func ParseStatus(s string) (Status, error) {
+	if slices.Contains(Statuses, Status(s)) {
+		return Status(s), nil
+	}
+	return "", fmt.Errorf(
+		"unknown status %q, expected one of %s",
+		s,
+		strings.Join(names(Statuses), ", "),
+	)
+}
+
+// ParseStatuses разбирает список состояний через запятую
+func ParseStatuses(s string) ([]Status, error) {
+	var out []Status
+	for _, part := range strings.Split(s, ",") {
+		st, err := ParseStatus(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// InitialStatuses — состояния, в которых подписку можно создать
+var InitialStatuses = []Status{Active, Trialing}
+
+// CanTransition сообщает, разрешен ли переход from → to. Переход в то же
+// состояние разрешен всегда.
+func CanTransition(from, to Status) bool {
+	return from == to || slices.Contains(transitions[from], to)
+}
+
+// Sources возвращает состояния, из которых разрешен переход в to, не
+// считая самого to. Используется для массовых переходов, которые
+// проверяют правила в условии запроса.
+func Sources(to Status) []Status {
+	var out []Status
+	for _, from := range Statuses {
+		if from != to && slices.Contains(transitions[from], to) {
+			out = append(out, from)
+		}
+	}
+	return out
+}
+
+// TransitionError — запрещенный переход между состояниями
+type TransitionError struct {
+	From, To Status
+}
+
+func (e *TransitionError) Error() string {
+	allowed := names(transitions[e.From])
+	if len(allowed) == 0 {
+		return fmt.Sprintf("cannot change status from %s", e.From)
+	}
+	return fmt.Sprintf(
+		"cannot change status from %s to %s, allowed: %s",
+		e.From,
+		e.To,
+		strings.Join(allowed, ", "),
+	)
+}
+
+// Transition проверяет переход и возвращает *TransitionError, если он
+// запрещен
+func Transition(from, to Status) error {
+	if !CanTransition(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+func names(statuses []Status) []string {
+	out := make([]string, len(statuses))
+	for i, s := range statuses {
+		out[i] = string(s)
+	}
+	return out
+}
+
+// CancelMode — способ отмены подписки
+type CancelMode string
+
+const (
+	// CancelImmediately завершает подписку сразу: она переходит в expired
+	CancelImmediately CancelMode = "immediate"
+	// CancelAtPeriodEnd оставляет подписку действовать до конца текущего
+	// оплаченного периода в состоянии cancelled
+	CancelAtPeriodEnd CancelMode = "end_of_period"
+)
+
+// ParseCancelMode проверяет способ отмены. Пустая строка означает
+// CancelAtPeriodEnd.
+func ParseCancelMode(s string) (CancelMode, error) {
+	switch CancelMode(s) {
+	case "":
+		return CancelAtPeriodEnd, nil
+	case CancelImmediately, CancelAtPeriodEnd:
+		return CancelMode(s), nil
+	}
+	return "", fmt.Errorf(
+		"unknown cancellation mode %q, expected %s or %s",
+		s,
+		CancelImmediately,
+		CancelAtPeriodEnd,
+	)
+}
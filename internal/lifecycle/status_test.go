@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Тест для разрешенных и запрещенных переходов
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		ok       bool
+	}{
+		{Trialing, Active, true},
+		{Active, Paused, true},
+		{Paused, Active, true},
+		{Active, Cancelled, true},
+		{Cancelled, Expired, true},
+		{Cancelled, Active, true},
+		{Expired, Active, true},
+		{Active, Active, true},
+		{Active, Trialing, false},
+		{Cancelled, Paused, false},
+		{Expired, Paused, false},
+		{Paused, Trialing, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := Transition(tt.from, tt.to)
+			if tt.ok {
+				assert.NoError(t, err)
+				return
+			}
+			var terr *TransitionError
+			require.ErrorAs(t, err, &terr)
+			assert.Equal(t, tt.from, terr.From)
+		})
+	}
+}
+
+// Тест для состояний, из которых разрешен переход
+func TestSources(t *testing.T) {
+	assert.Equal(t, []Status{Trialing, Active, Paused, Cancelled}, Sources(Expired))
+	assert.Equal(t, []Status{Trialing, Paused, Cancelled, Expired}, Sources(Active))
+	assert.Equal(t, []Status{Active}, Sources(Paused))
+}
+
+// Тест для разбора фильтра состояний
+func TestParseStatuses(t *testing.T) {
+	got, err := ParseStatuses("active, paused")
+	require.NoError(t, err)
+	assert.Equal(t, []Status{Active, Paused}, got)
+
+	_, err = ParseStatuses("active,archived")
+	assert.ErrorContains(t, err, "archived")
+}
+
+// Тест для разбора способа отмены
+func TestParseCancelMode(t *testing.T) {
+	got, err := ParseCancelMode("")
+	require.NoError(t, err)
+	assert.Equal(t, CancelAtPeriodEnd, got)
+
+	got, err = ParseCancelMode("immediate")
+	require.NoError(t, err)
+	assert.Equal(t, CancelImmediately, got)
+
+	_, err = ParseCancelMode("later")
+	assert.ErrorContains(t, err, "later")
+}
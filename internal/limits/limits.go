@@ -0,0 +1,95 @@
+// Package limits содержит форматы и границы параметров, которые
+// проверяет конфигурация и используют сервисы. Пакет не зависит от других
+// пакетов проекта, поэтому его может импортировать config.
+package limits
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReminderDaysBefore — максимальный срок, за который можно напомнить о
+// продлении
+const ReminderDaysBefore = 365
+
+// Limit — параметры token bucket: Rate токенов в секунду, не более Burst
+// токенов в запасе
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Window возвращает время полного восполнения запаса токенов
+func (l Limit) Window() time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// ParseLimit разбирает лимит в формате "rate:burst", например "0.5:5"
+func ParseLimit(s string) (Limit, error) {
+	rateStr, burstStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must have the form rate:burst", s)
+	}
+	rate, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		return Limit{}, fmt.Errorf("limit %q: rate must be a positive number", s)
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(burstStr))
+	if err != nil || burst < 1 {
+		return Limit{}, fmt.Errorf("limit %q: burst must be a positive integer", s)
+	}
+	return Limit{Rate: rate, Burst: burst}, nil
+}
+
+// ParseRouteLimit разбирает лимит маршрута в формате
+// "METHOD /route/template=rate:burst"
+func ParseRouteLimit(s string) (route string, limit Limit, err error) {
+	route, spec, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", Limit{}, fmt.Errorf(
+			"route limit %q must have the form \"METHOD /path=rate:burst\"",
+			s,
+		)
+	}
+	route = strings.TrimSpace(route)
+	method, path, ok := strings.Cut(route, " ")
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		return "", Limit{}, fmt.Errorf(
+			"route limit %q: route must be \"METHOD /path\"",
+			s,
+		)
+	}
+	limit, err = ParseLimit(spec)
+	if err != nil {
+		return "", Limit{}, err
+	}
+	return strings.ToUpper(method) + " " + path, limit, nil
+}
+
+// Policy — лимит по умолчанию и лимиты отдельных маршрутов. Ключ Routes —
+// "METHOD /route/template".
+type Policy struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+// ParsePolicy разбирает лимит по умолчанию ("rate:burst") и список лимитов
+// маршрутов ("METHOD /path=rate:burst")
+func ParsePolicy(def string, routes []string) (Policy, error) {
+	limit, err := ParseLimit(def)
+	if err != nil {
+		return Policy{}, err
+	}
+	p := Policy{Default: limit, Routes: make(map[string]Limit, len(routes))}
+	for _, r := range routes {
+		route, limit, err := ParseRouteLimit(r)
+		if err != nil {
+			return Policy{}, err
+		}
+		p.Routes[route] = limit
+	}
+	return p, nil
+}
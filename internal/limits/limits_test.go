@@ -0,0 +1,28 @@
+package limits
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Тест для разбора лимитов маршрутов
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("10:20", []string{
+		"get /api/v1/subscriptions/total=0.5:5",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Limit{Rate: 10, Burst: 20}, p.Default)
+	assert.Equal(
+		t,
+		Limit{Rate: 0.5, Burst: 5},
+		p.Routes["GET /api/v1/subscriptions/total"],
+	)
+
+	_, err = ParsePolicy("10", nil)
+	assert.Error(t, err)
+	_, err = ParsePolicy("10:20", []string{"/total=1:1"})
+	assert.Error(t, err)
+	_, err = ParsePolicy("10:20", []string{"GET /total=0:1"})
+	assert.Error(t, err)
+}
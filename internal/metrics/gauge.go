@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// CountFunc возвращает текущее значение метрики, например количество
+// строк в базе данных
+type CountFunc func(ctx context.Context) (int64, error)
+
+// countCollector вычисляет значение gauge при каждом опросе /metrics.
+// Если вычисление не удалось, метрика в этом опросе не отдается.
+type countCollector struct {
+	desc    *prometheus.Desc
+	fn      CountFunc
+	timeout time.Duration
+}
+
+func (c *countCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *countCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	n, err := c.fn(ctx)
+	if err != nil {
+		logger.Log.WithError(err).
+			WithField("metric", c.desc.String()).
+			Warn("Failed to collect metric")
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
+
+// NewActiveSubscriptions возвращает gauge активных подписок, значение
+// которого вычисляет fn
+func NewActiveSubscriptions(fn CountFunc) prometheus.Collector {
+	return &countCollector{
+		desc: prometheus.NewDesc(
+			namespace+"_subscriptions_active",
+			"Number of active and trialing subscriptions that have started and not yet ended.",
+			nil,
+			nil,
+		),
+		fn:      fn,
+		timeout: 2 * time.Second,
+	}
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "subscription_service"
+
+// Registry содержит все метрики сервиса и отдается на /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route template and status.",
+		},
+		[]string{"method", "route", "status"},
+	)
+	HTTPRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	DBQueryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "GORM query latency by operation, table and status (ok or error).",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{"operation", "table", "status"},
+	)
+
+	SubscriptionsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "subscriptions_created_total",
+		Help:      "Number of subscriptions created.",
+	})
+	SubscriptionsDeleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "subscriptions_deleted_total",
+		Help:      "Number of subscriptions deleted.",
+	})
+	SubscriptionsCancelled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "subscriptions_cancelled_total",
+			Help:      "Number of subscriptions cancelled, by cancellation mode.",
+		},
+		[]string{"mode"},
+	)
+	BudgetAlerts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "budget_alerts_total",
+			Help:      "Number of budget overspend alerts, by budget scope.",
+		},
+		[]string{"scope"},
+	)
+	TotalCostComputations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "total_cost_computations_total",
+			Help:      "Number of total cost computations by result.",
+		},
+		[]string{"result"},
+	)
+	TotalCostDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "total_cost_duration_seconds",
+		Help:      "Latency of total cost computations, including the database query.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	NotificationDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "notification_deliveries_total",
+			Help:      "Number of notification delivery attempts by channel and result (sent, retry, failed).",
+		},
+		[]string{"channel", "result"},
+	)
+	WebhookDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Number of webhook delivery attempts by result (delivered, retry, dead).",
+		},
+		[]string{"result"},
+	)
+	OutboxPublished = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "outbox_events_published_total",
+			Help:      "Number of outbox events handed to the message broker by result.",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		SubscriptionsCreated,
+		SubscriptionsDeleted,
+		SubscriptionsCancelled,
+		BudgetAlerts,
+		TotalCostComputations,
+		TotalCostDuration,
+		NotificationDeliveries,
+		WebhookDeliveries,
+		OutboxPublished,
+	)
+}
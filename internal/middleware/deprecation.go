@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated помечает ответы устаревших маршрутов заголовками Deprecation,
+// Sunset и Link (RFC 9745, RFC 8594). Link указывает на тот же путь с тем
+// же запросом под префиксом successorPrefix.
+func Deprecated(since, sunset time.Time, successorPrefix string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunsetHeader)
+		successor := successorPrefix + "/" +
+			strings.TrimPrefix(c.Request.URL.Path, "/")
+		if query := c.Request.URL.RawQuery; query != "" {
+			successor += "?" + query
+		}
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:subscription-service:problem:"
+)
+
+// Errors превращает ошибку, добавленную обработчиком через c.Error, в ответ
+// application/problem+json. Внутренние причины ошибок пишутся в лог и не
+// попадают в ответ.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		WriteProblem(c, c.Errors.Last().Err)
+	}
+}
+
+// WriteProblem пишет ответ application/problem+json для ошибки err
+func WriteProblem(c *gin.Context, err error) {
+	appErr := apperr.As(err)
+	writeProblem(c, statusOf(appErr.Kind), appErr)
+}
+
+func writeProblem(c *gin.Context, status int, appErr *apperr.Error) {
+	log := logger.FromContext(c.Request.Context()).
+		WithField("code", appErr.Code)
+	if appErr.Err != nil {
+		log = log.WithError(appErr.Err)
+	}
+	if status >= http.StatusInternalServerError {
+		log.Error(appErr.Message)
+	} else {
+		log.Debug(appErr.Message)
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, models.Problem{
+		Type:      problemTypePrefix + appErr.Code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    appErr.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      appErr.Code,
+		RequestID: GetRequestID(c),
+	})
+}
+
+// NotFound и MethodNotAllowed отвечают на запросы к неизвестным маршрутам
+// в том же формате, что и остальные ошибки
+func NotFound(c *gin.Context) {
+	WriteProblem(c, apperr.NotFound(apperr.CodeRouteNotFound, "route not found"))
+}
+
+func MethodNotAllowed(c *gin.Context) {
+	writeProblem(
+		c,
+		http.StatusMethodNotAllowed,
+		apperr.Validation(apperr.CodeMethodNotAllowed, "method not allowed"),
+	)
+}
+
+// Recovery перехватывает панику в обработчике и отвечает 500 без деталей
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		logger.FromContext(c.Request.Context()).
+			WithField("panic", recovered).
+			Error("Recovered from panic")
+		WriteProblem(c, apperr.Internal(nil))
+	})
+}
+
+func statusOf(kind apperr.Kind) int {
+	switch kind {
+	case apperr.KindValidation:
+		return http.StatusBadRequest
+	case apperr.KindNotFound:
+		return http.StatusNotFound
+	case apperr.KindConflict:
+		return http.StatusConflict
+	case apperr.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case apperr.KindRateLimited:
+		return http.StatusTooManyRequests
+	case apperr.KindUnauthorized:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	UserIDHeader    = "X-User-ID"
+
+	requestIDKey = "request_id"
+)
+
+// RequestID принимает X-Request-ID от клиента или балансировщика либо
+// генерирует новый и возвращает его в ответе
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// GetRequestID возвращает идентификатор текущего запроса
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// validRequestID отбрасывает пустые, слишком длинные и содержащие
+// управляющие символы идентификаторы, чтобы они не попадали в логи
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Logger сохраняет в контексте запроса логгер с идентификатором запроса,
+// методом, шаблоном маршрута, пользователем и трассой, и пишет строку
+// журнала доступа по завершении запроса
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		fields := logrus.Fields{
+			"request_id": GetRequestID(c),
+			"method":     c.Request.Method,
+			"route":      c.FullPath(),
+		}
+		// Заголовок передает клиент, поэтому в журнал попадает только UUID
+		if user, err := uuid.Parse(c.GetHeader(UserIDHeader)); err == nil {
+			fields["user_id"] = user.String()
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			fields["trace_id"] = sc.TraceID().String()
+		}
+		entry := logger.Log.WithFields(fields)
+		c.Request = c.Request.WithContext(
+			logger.WithContext(c.Request.Context(), entry),
+		)
+
+		c.Next()
+
+		access := entry.WithFields(logrus.Fields{
+			"status":     c.Writer.Status(),
+			"latency_ms": time.Since(start).Milliseconds(),
+			"path":       c.Request.URL.Path,
+			"client_ip":  c.ClientIP(),
+		})
+		switch {
+		case c.Writer.Status() >= 500:
+			access.Error("Request completed")
+		case c.Writer.Status() >= 400:
+			access.Warn("Request completed")
+		default:
+			access.Info("Request completed")
+		}
+	}
+}
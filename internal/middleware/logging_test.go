@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/middleware"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// setupLoggingRouter настраивает маршрутизатор с middleware логирования,
+// обработчик которого пишет строку через логгер запроса
+func setupLoggingRouter() (*gin.Engine, *test.Hook) {
+	gin.SetMode(gin.TestMode)
+	hook := test.NewLocal(logger.Log)
+	logger.Log.SetLevel(logrus.InfoLevel)
+
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.Logger())
+	r.GET("/items/:id", func(c *gin.Context) {
+		logger.FromContext(c.Request.Context()).Info("Item found")
+		c.Status(http.StatusOK)
+	})
+	return r, hook
+}
+
+// Тест для передачи X-Request-ID от клиента
+func TestRequestIDPropagated(t *testing.T) {
+	r, hook := setupLoggingRouter()
+
+	req, _ := http.NewRequest("GET", "/items/42", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	req.Header.Set(middleware.UserIDHeader, "60601fee-2bf1-4721-ae6f-7636e79a0cba")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-123", w.Header().Get(middleware.RequestIDHeader))
+	entries := hook.AllEntries()
+	if assert.Len(t, entries, 2) {
+		handlerEntry := entries[0]
+		assert.Equal(t, "Item found", handlerEntry.Message)
+		assert.Equal(t, "req-123", handlerEntry.Data["request_id"])
+		assert.Equal(t, "GET", handlerEntry.Data["method"])
+		assert.Equal(t, "/items/:id", handlerEntry.Data["route"])
+		assert.Equal(
+			t,
+			"60601fee-2bf1-4721-ae6f-7636e79a0cba",
+			handlerEntry.Data["user_id"],
+		)
+		assert.Equal(t, http.StatusOK, entries[1].Data["status"])
+	}
+}
+
+// Тест для генерации X-Request-ID, если клиент его не передал
+// или передал некорректный
+func TestRequestIDGenerated(t *testing.T) {
+	r, hook := setupLoggingRouter()
+
+	req, _ := http.NewRequest("GET", "/items/42", nil)
+	req.Header.Set(middleware.RequestIDHeader, "bad\nid")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	id := w.Header().Get(middleware.RequestIDHeader)
+	assert.Len(t, id, 36)
+	assert.Equal(t, id, hook.LastEntry().Data["request_id"])
+}
+
+// Тест для пропуска X-User-ID, который не является UUID
+func TestUserIDNotUUIDSkipped(t *testing.T) {
+	r, hook := setupLoggingRouter()
+
+	req, _ := http.NewRequest("GET", "/items/42", nil)
+	req.Header.Set(middleware.UserIDHeader, "admin\nlevel=error")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.NotContains(t, hook.LastEntry().Data, "user_id")
+}
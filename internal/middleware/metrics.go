@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nemopss/subscription-service/internal/metrics"
+)
+
+// Metrics считает HTTP-запросы и их длительность. В метку route попадает
+// шаблон маршрута (/api/v1/subscriptions/:id), а не фактический путь,
+// чтобы число временных рядов не зависело от идентификаторов.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequests.
+			WithLabelValues(c.Request.Method, route, status).
+			Inc()
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, status).
+			Observe(time.Since(start).Seconds())
+	}
+}
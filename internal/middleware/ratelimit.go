@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/ratelimit"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// RateLimit ограничивает частоту запросов по алгоритму token bucket.
+// Ключ — субъект токена, проверенного resolver, API-ключ из apiKeys или IP
+// клиента. Заголовки, которые клиент может менять произвольно (неизвестный
+// API-ключ, X-User-ID, непрошедший проверку токен), на ключ не влияют,
+// иначе каждый новый заголовок давал бы новый бакет. Resolver nil, если
+// разделение на арендаторов выключено. Маршруты с
+// собственным лимитом получают отдельный бакет, остальные делят общий.
+// Если хранилище недоступно, запрос пропускается.
+func RateLimit(store ratelimit.Store, policy ratelimit.Policy, apiKeys []string, resolver *tenancy.Resolver) gin.HandlerFunc {
+	known := make(map[string]bool, len(apiKeys))
+	for _, key := range apiKeys {
+		known[hashKey(key)] = true
+	}
+
+	return func(c *gin.Context) {
+		client := clientKey(c, known, resolver)
+		route := c.Request.Method + " " + c.FullPath()
+		limit, ok := policy.Routes[route]
+		key := client + "|" + route
+		if !ok {
+			limit = policy.Default
+			key = client + "|*"
+		}
+
+		res, err := store.Take(c.Request.Context(), key, limit)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).
+				WithError(err).
+				Warn("Rate limit store failed, allowing request")
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(res.Reset))
+		c.Header(
+			"RateLimit-Policy",
+			strconv.Itoa(limit.Burst)+";w="+ceilSeconds(limit.Window()),
+		)
+
+		if !res.Allowed {
+			c.Header("Retry-After", ceilSeconds(res.RetryAfter))
+			WriteProblem(c, &apperr.Error{
+				Kind:    apperr.KindRateLimited,
+				Code:    apperr.CodeRateLimited,
+				Message: "too many requests, retry later",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// clientKey определяет, чей лимит расходует запрос. Субъект и API-ключ
+// хранятся только в виде хэша. IP берется из X-Forwarded-For только для
+// доверенных прокси (gin.Engine.SetTrustedProxies).
+func clientKey(c *gin.Context, known map[string]bool, resolver *tenancy.Resolver) string {
+	if resolver != nil {
+		if p, err := authenticate(c, resolver); err == nil && p.Subject != "" {
+			return "user:" + hashKey(p.Tenant+"/"+p.Subject)
+		}
+	}
+	if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+		if hash := hashKey(apiKey); known[hash] {
+			return "key:" + hash
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
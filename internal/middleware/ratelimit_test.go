@@ -0,0 +1,122 @@
+package middleware_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/middleware"
+	"github.com/nemopss/subscription-service/internal/ratelimit"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+)
+
+// setupRateLimitRouter настраивает маршрутизатор с лимитом 1 запрос в
+// минуту для /total и 2 запроса в минуту для остальных маршрутов. Известен
+// один API-ключ, прокси не доверяются.
+func setupRateLimitRouter() *gin.Engine {
+	return setupRateLimitRouterWith(nil)
+}
+
+// setupRateLimitRouterWith настраивает тот же маршрутизатор с
+// определителем арендатора resolver
+func setupRateLimitRouterWith(resolver *tenancy.Resolver) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	policy, _ := ratelimit.ParsePolicy(
+		"0.0333:2",
+		[]string{"GET /total=0.0166:1"},
+	)
+
+	r := gin.New()
+	r.SetTrustedProxies(nil)
+	r.Use(middleware.RateLimit(ratelimit.NewMemoryStore(), policy, []string{"secret"}, resolver))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/total", ok)
+	r.GET("/items", ok)
+	return r
+}
+
+func doRequest(r *gin.Engine, path, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// Тест для отклонения запросов сверх лимита маршрута
+func TestRateLimitPerRoute(t *testing.T) {
+	r := setupRateLimitRouter()
+
+	w := doRequest(r, "/total", middleware.APIKeyHeader, "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = doRequest(r, "/total", middleware.APIKeyHeader, "secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"code":"rate_limited"`)
+
+	// Лимит маршрута не расходует общий лимит
+	w = doRequest(r, "/items", middleware.APIKeyHeader, "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+}
+
+// Тест для ключей лимита: отдельный бакет получает только известный
+// API-ключ, остальные запросы делят лимит IP
+func TestRateLimitKeys(t *testing.T) {
+	r := setupRateLimitRouter()
+
+	assert.Equal(t, http.StatusOK, doRequest(r, "/total", middleware.APIKeyHeader, "secret").Code)
+	assert.Equal(t, http.StatusOK, doRequest(r, "/total", middleware.UserIDHeader, "u1").Code)
+	// Заголовки, которые клиент меняет произвольно, не дают нового бакета
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r, "/total", middleware.UserIDHeader, "u2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r, "/total", middleware.APIKeyHeader, "guess").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r, "/total", "X-Forwarded-For", "203.0.113.7").Code)
+}
+
+// signToken подписывает claims ключом secret по HS256
+func signToken(secret string, claims map[string]any) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, _ := json.Marshal(claims)
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Тест для ключа лимита по субъекту проверенного токена
+func TestRateLimitPrincipal(t *testing.T) {
+	r := setupRateLimitRouterWith(&tenancy.Resolver{
+		Secret: []byte("jwt-secret"),
+		Claim:  "tenant_id",
+	})
+	bearer := func(claims map[string]any) string {
+		return "Bearer " + signToken("jwt-secret", claims)
+	}
+	alice := bearer(map[string]any{"tenant_id": "acme", "sub": "alice"})
+
+	assert.Equal(t, http.StatusOK, doRequest(r, "/total", "Authorization", alice).Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r, "/total", "Authorization", alice).Code)
+	// Каждый пользователь расходует свой лимит, в том числе за одним IP
+	assert.Equal(t, http.StatusOK, doRequest(r, "/total", "Authorization",
+		bearer(map[string]any{"tenant_id": "acme", "sub": "bob"})).Code)
+	assert.Equal(t, http.StatusOK, doRequest(r, "/total", "Authorization",
+		bearer(map[string]any{"tenant_id": "globex", "sub": "alice"})).Code)
+	// Без субъекта и с токеном, не прошедшим проверку, лимит общий по IP
+	assert.Equal(t, http.StatusOK, doRequest(r, "/total", "Authorization",
+		bearer(map[string]any{"tenant_id": "acme"})).Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRequest(r, "/total", "Authorization",
+		"Bearer "+signToken("guess", map[string]any{"tenant_id": "acme", "sub": "carol"})).Code)
+}
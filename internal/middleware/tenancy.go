@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nemopss/subscription-service/internal/apperr"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// Tenant определяет арендатора запроса и передает его обработчикам через
+// контекст запроса: запросы к базе данных ограничиваются им автоматически.
+// Если resolver не задан, разделение выключено и все запросы относятся к
+// арендатору по умолчанию. Запрос, для которого арендатор не определен,
+// отклоняется с 401.
+func Tenant(resolver *tenancy.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := tenancy.Default
+		if resolver != nil {
+			p, err := authenticate(c, resolver)
+			if err != nil {
+				code := apperr.CodeTenantRequired
+				if errors.Is(err, tenancy.ErrInvalidToken) {
+					code = apperr.CodeInvalidToken
+				}
+				WriteProblem(c, apperr.Unauthorized(code, err.Error()))
+				return
+			}
+			id = p.Tenant
+		}
+
+		ctx := tenancy.WithTenant(c.Request.Context(), id)
+		ctx = logger.WithContext(ctx, logger.FromContext(ctx).WithField("tenant_id", id))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// principalKey — ключ gin.Context с результатом authenticate
+const principalKey = "principal"
+
+type authentication struct {
+	principal tenancy.Principal
+	err       error
+}
+
+// authenticate проверяет токен запроса один раз за запрос: результат
+// сохраняется в gin.Context и используется RateLimit и Tenant
+func authenticate(c *gin.Context, resolver *tenancy.Resolver) (tenancy.Principal, error) {
+	if v, ok := c.Get(principalKey); ok {
+		a := v.(authentication)
+		return a.principal, a.err
+	}
+	p, err := resolver.Authenticate(c.Request)
+	c.Set(principalKey, authentication{principal: p, err: err})
+	return p, err
+}
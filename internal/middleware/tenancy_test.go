@@ -0,0 +1,65 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/middleware"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+)
+
+// setupTenantRouter настраивает маршрутизатор, отвечающий арендатором
+// запроса
+func setupTenantRouter(resolver *tenancy.Resolver) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.Tenant(resolver))
+	r.GET("/tenant", func(c *gin.Context) {
+		id, _ := tenancy.FromContext(c.Request.Context())
+		c.String(http.StatusOK, id)
+	})
+	return r
+}
+
+func doTenantRequest(r *gin.Engine, host, auth string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/tenant", nil)
+	req.Host = host
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// Тест для арендатора по умолчанию при выключенном разделении
+func TestTenantDisabled(t *testing.T) {
+	w := doTenantRequest(setupTenantRouter(nil), "acme.example.com", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, tenancy.Default, w.Body.String())
+}
+
+// Тест для определения арендатора и отклонения запросов без него
+func TestTenantResolved(t *testing.T) {
+	r := setupTenantRouter(&tenancy.Resolver{
+		HostSuffix: ".example.com",
+		Secret:     []byte("secret"),
+		Claim:      "tenant_id",
+	})
+
+	w := doTenantRequest(r, "acme.example.com", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme", w.Body.String())
+
+	w = doTenantRequest(r, "example.com", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"tenant_required"`)
+
+	w = doTenantRequest(r, "acme.example.com", "Bearer invalid")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_token"`)
+}
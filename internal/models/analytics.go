@@ -0,0 +1,63 @@
+package models
+
+// SpendMonth — расходы за месяц и их изменение относительно предыдущего
+// месяца
+type SpendMonth struct {
+	Month  string `json:"month"  example:"10-2026"`
+	Total  int    `json:"total"  example:"1500"`
+	Change int    `json:"change" example:"300"`
+	// ChangePercent не задан, если в предыдущем месяце расходов не было
+	ChangePercent *float64 `json:"change_percent,omitempty" example:"25"`
+}
+
+// SpendTrend — расходы по месяцам периода с From по To
+type SpendTrend struct {
+	From   string       `json:"from"   example:"11-2025"`
+	To     string       `json:"to"     example:"10-2026"`
+	Total  int          `json:"total"  example:"18000"`
+	Months []SpendMonth `json:"months"`
+}
+
+// ServiceSpend — расходы на сервис за период. Подписки, связанные с
+// каталогом, группируются по сервису каталога, остальные — по названию.
+type ServiceSpend struct {
+	ServiceName   string `json:"service_name"         example:"Yandex Plus"`
+	ServiceID     *int   `json:"service_id,omitempty" example:"1"`
+	Subscriptions int    `json:"subscriptions"        example:"3"`
+	Total         int    `json:"total"                example:"4800"`
+}
+
+// TopServices — сервисы с наибольшими расходами за период
+type TopServices struct {
+	From     string         `json:"from"     example:"11-2025"`
+	To       string         `json:"to"       example:"10-2026"`
+	Services []ServiceSpend `json:"services"`
+}
+
+// AveragePrice — средняя текущая цена подписок, действовавших в периоде.
+// AverageMonthlyPrice приводит цену списания к месяцу по периодичности.
+type AveragePrice struct {
+	From                string  `json:"from"                  example:"11-2025"`
+	To                  string  `json:"to"                    example:"10-2026"`
+	Subscriptions       int     `json:"subscriptions"         example:"12"`
+	AveragePrice        float64 `json:"average_price"         example:"512.5"`
+	AverageMonthlyPrice float64 `json:"average_monthly_price" example:"430.25"`
+}
+
+// ChurnMonth — подписки, начавшиеся и закончившиеся в месяце. Active —
+// подписки, действовавшие в месяце, включая начавшиеся и закончившиеся в
+// нем; ChurnRate — доля Ended от Active.
+type ChurnMonth struct {
+	Month     string  `json:"month"      example:"10-2026"`
+	Started   int     `json:"started"    example:"4"`
+	Ended     int     `json:"ended"      example:"2"`
+	Active    int     `json:"active"     example:"40"`
+	ChurnRate float64 `json:"churn_rate" example:"0.05"`
+}
+
+// ChurnReport — отток подписок по закончившимся месяцам периода
+type ChurnReport struct {
+	From   string       `json:"from"   example:"11-2025"`
+	To     string       `json:"to"     example:"10-2026"`
+	Months []ChurnMonth `json:"months"`
+}
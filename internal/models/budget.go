@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Budget — месячный лимит расходов пользователя: на все подписки, на
+// категорию вместе с подкатегориями или на сервис каталога
+type Budget struct {
+	ID         int       `json:"id"                    gorm:"primaryKey"`
+	TenantID   string    `json:"-"                     gorm:"not null;default:default;index"`
+	UserID     uuid.UUID `json:"user_id"               gorm:"type:uuid;not null;index"`
+	Scope      string    `json:"scope"                 gorm:"not null"   enums:"overall,category,service"`
+	CategoryID *int      `json:"category_id,omitempty"`
+	ServiceID  *int      `json:"service_id,omitempty"`
+	Amount     int       `json:"amount"                gorm:"not null"   example:"2000"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// Бюджет удаляется вместе с категорией или сервисом
+	Category *Category `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Service  *Service  `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+type CreateBudget struct {
+	Scope      string `json:"scope"                 enums:"overall,category,service"`
+	CategoryID *int   `json:"category_id,omitempty" example:"1"`
+	ServiceID  *int   `json:"service_id,omitempty"  example:"1"`
+	Amount     int    `json:"amount"                example:"2000"`
+}
+
+// BudgetStatus — расходы в рамках бюджета за месяц. Spent считается так
+// же, как /subscriptions/total, с подписками без end_date до конца месяца.
+type BudgetStatus struct {
+	Budget    Budget `json:"budget"`
+	Spent     int    `json:"spent"     example:"2400"`
+	Remaining int    `json:"remaining" example:"-400"`
+	Over      bool   `json:"over"`
+}
+
+type BudgetReport struct {
+	Month   string         `json:"month"   example:"10-2026"`
+	Budgets []BudgetStatus `json:"budgets"`
+}
+
+// BudgetAlert — превышение бюджета. Уникальный индекс по бюджету и месяцу
+// гарантирует одно оповещение о превышении в месяц.
+type BudgetAlert struct {
+	ID        int       `json:"id"         gorm:"primaryKey"`
+	TenantID  string    `json:"-"          gorm:"not null;default:default;index"`
+	BudgetID  int       `json:"budget_id"  gorm:"not null;uniqueIndex:idx_budget_alerts_budget_month"`
+	UserID    uuid.UUID `json:"user_id"    gorm:"type:uuid;not null;index"`
+	Month     string    `json:"month"      gorm:"not null;uniqueIndex:idx_budget_alerts_budget_month" example:"2026-10-01"`
+	Amount    int       `json:"amount"     gorm:"not null"`
+	Spent     int       `json:"spent"      gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	Budget    *Budget   `json:"-"          gorm:"constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Category — категория подписок. Категории образуют дерево: фильтр и
+// группировка по категории учитывают ее подкатегории.
+type Category struct {
+	ID       int       `json:"id"                  gorm:"primaryKey"`
+	TenantID string    `json:"-"                   gorm:"not null;default:default;index"`
+	Name     string    `json:"name"                gorm:"not null" example:"Entertainment"`
+	ParentID *int      `json:"parent_id,omitempty" gorm:"index"`
+	Parent   *Category `json:"-"                   gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+type CreateCategory struct {
+	Name     string `json:"name"                example:"Streaming"`
+	ParentID *int   `json:"parent_id,omitempty" example:"1"`
+}
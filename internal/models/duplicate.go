@@ -0,0 +1,26 @@
+package models
+
+import "github.com/google/uuid"
+
+// DuplicateGroup — подписки одного пользователя на один сервис с
+// пересекающимися периодами
+type DuplicateGroup struct {
+	UserID        uuid.UUID      `json:"user_id"`
+	ServiceName   string         `json:"service_name"         example:"Yandex Plus"`
+	ServiceID     *int           `json:"service_id,omitempty" example:"1"`
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// Warning — предупреждение о возможной ошибке в данных, не мешающее
+// выполнению запроса
+type Warning struct {
+	Code            string `json:"code"                       example:"possible_duplicate"`
+	Message         string `json:"message"`
+	SubscriptionIDs []int  `json:"subscription_ids,omitempty"`
+}
+
+// CreatedSubscription — созданная подписка с предупреждениями
+type CreatedSubscription struct {
+	Subscription
+	Warnings []Warning `json:"warnings,omitempty"`
+}
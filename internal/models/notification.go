@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// NotificationDelivery — уведомление в очереди доставки вместе с историей
+// попыток отправки
+type NotificationDelivery struct {
+	ID            uint       `json:"id"              gorm:"primaryKey"`
+	TenantID      string     `json:"-"               gorm:"not null;default:default;index"`
+	Channel       string     `json:"channel"         gorm:"not null"`
+	Recipient     string     `json:"recipient"       gorm:"not null"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"            gorm:"not null"`
+	Status        string     `json:"status"          gorm:"not null;default:pending;index:idx_notification_deliveries_due,priority:1"`
+	Attempts      int        `json:"attempts"        gorm:"not null;default:0"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"not null;index:idx_notification_deliveries_due,priority:2"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization — организация или домохозяйство, объединяющее
+// пользователей для общих отчетов о расходах
+type Organization struct {
+	ID        int       `json:"id"                gorm:"primaryKey"`
+	TenantID  string    `json:"-"                 gorm:"not null;default:default;index"`
+	Name      string    `json:"name"              gorm:"not null"       example:"Acme"`
+	Kind      string    `json:"kind"              gorm:"not null;default:organization" enums:"organization,household"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Members   []Member  `json:"members,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// Member — пользователь в организации. Пользователь может входить в
+// несколько организаций.
+type Member struct {
+	OrganizationID int       `json:"-"          gorm:"primaryKey"`
+	UserID         uuid.UUID `json:"user_id"    gorm:"type:uuid;primaryKey;index"`
+	TenantID       string    `json:"-"          gorm:"not null;default:default;index"`
+	Role           string    `json:"role"       gorm:"not null" enums:"owner,admin,member"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type CreateOrganization struct {
+	Name string `json:"name"           example:"Acme"`
+	Kind string `json:"kind,omitempty" enums:"organization,household"`
+	// Пользователь, который становится первым владельцем
+	OwnerID uuid.UUID `json:"owner_id"`
+}
+
+type UpdateOrganization struct {
+	Name string `json:"name"           example:"Acme"`
+	Kind string `json:"kind,omitempty" enums:"organization,household"`
+}
+
+type UpdateMember struct {
+	Role string `json:"role,omitempty" enums:"owner,admin,member"`
+}
+
+type MemberCost struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role"  example:"member"`
+	Total  int       `json:"total" example:"1200"`
+}
+
+type ServiceCost struct {
+	ServiceName string `json:"service_name"         example:"Yandex Plus"`
+	ServiceID   *int   `json:"service_id,omitempty" example:"1"`
+	Total       int    `json:"total"                example:"2400"`
+}
+
+// OrganizationTotal — расходы участников организации за период: всего,
+// по участникам и по сервисам
+type OrganizationTotal struct {
+	Total    int           `json:"total"    example:"3600"`
+	Members  []MemberCost  `json:"members"`
+	Services []ServiceCost `json:"services"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// OutboxEvent — доменное событие, ожидающее публикации в брокер. ID
+// задает порядок публикации.
+type OutboxEvent struct {
+	ID            uint64     `gorm:"primaryKey"`
+	TenantID      string     `gorm:"not null;default:default;index"`
+	EventID       string     `gorm:"type:uuid;not null;uniqueIndex"`
+	AggregateType string     `gorm:"not null"`
+	AggregateID   string     `gorm:"not null"`
+	Type          string     `gorm:"not null"`
+	Payload       string     `gorm:"type:jsonb;not null"`
+	CreatedAt     time.Time  `gorm:"not null"`
+	PublishedAt   *time.Time `gorm:"index"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Pause — приостановка подписки. Списания не производятся в месяцы с
+// StartDate по месяц перед EndDate; EndDate == nil означает, что
+// подписка приостановлена до сих пор.
+type Pause struct {
+	ID             int       `json:"id"                 gorm:"primaryKey"`
+	TenantID       string    `json:"-"                  gorm:"not null;default:default;index"`
+	SubscriptionID int       `json:"-"                  gorm:"not null;index"`
+	StartDate      string    `json:"start_date"         gorm:"not null"       example:"2026-11-01"`
+	EndDate        *string   `json:"end_date,omitempty" example:"2027-02-01"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type PauseSubscription struct {
+	// Первый приостановленный месяц в формате MM-YYYY, по умолчанию текущий;
+	// не позже текущего
+	StartDate string `json:"start_date,omitempty" example:"11-2026"`
+}
+
+type ResumeSubscription struct {
+	// Первый оплачиваемый месяц в формате MM-YYYY, по умолчанию текущий
+	ResumeDate string `json:"resume_date,omitempty" example:"02-2027"`
+}
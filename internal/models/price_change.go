@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// PriceChange — запланированное изменение цены подписки: списания с
+// месяца EffectiveDate производятся по Price
+type PriceChange struct {
+	ID             int       `json:"id"             gorm:"primaryKey"`
+	TenantID       string    `json:"-"              gorm:"not null;default:default;index"`
+	SubscriptionID int       `json:"-"              gorm:"not null;uniqueIndex:idx_price_changes_subscription_date"`
+	EffectiveDate  string    `json:"effective_date" gorm:"not null;uniqueIndex:idx_price_changes_subscription_date" example:"2027-01-01"`
+	Price          int       `json:"price"          gorm:"not null" example:"499"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type CreatePriceChange struct {
+	// Первый месяц с новой ценой в формате MM-YYYY, не раньше текущего
+	EffectiveDate string `json:"effective_date" example:"01-2027"`
+	Price         int    `json:"price"          example:"499"`
+}
+
+type ForecastMonth struct {
+	Month string `json:"month" example:"11-2026"`
+	Total int    `json:"total" example:"1000"`
+}
+
+// ForecastResponse — ожидаемые списания по месяцам начиная с From
+type ForecastResponse struct {
+	From   string          `json:"from"   example:"10-2026"`
+	Total  int             `json:"total"  example:"12000"`
+	Months []ForecastMonth `json:"months"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Problem — описание ошибки в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	Type      string `json:"type"                 example:"urn:subscription-service:problem:subscription_not_found"`
+	Title     string `json:"title"                example:"Not Found"`
+	Status    int    `json:"status"               example:"404"`
+	Detail    string `json:"detail,omitempty"     example:"subscription not found"`
+	Instance  string `json:"instance,omitempty"   example:"/api/v1/subscriptions/42"`
+	Code      string `json:"code"                 example:"subscription_not_found"`
+	RequestID string `json:"request_id,omitempty" example:"8d1f5c1e-4c6a-4f0b-9d49-0b8f1f8f0c2a"`
+}
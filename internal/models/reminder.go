@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReminderSettings — настройки напоминаний о продлении для пользователя
+type ReminderSettings struct {
+	TenantID   string    `json:"-"           gorm:"primaryKey;default:default"`
+	UserID     uuid.UUID `json:"user_id"     gorm:"type:uuid;primaryKey"`
+	DaysBefore int       `json:"days_before" gorm:"not null"            example:"3"`
+	Enabled    bool      `json:"enabled"     gorm:"not null;default:true"`
+	// Channel и Recipient задают канал доставки: email, webhook или
+	// telegram. Если канал не задан, напоминание только пишется в журнал.
+	Channel   string    `json:"channel,omitempty"   enums:"email,webhook,telegram"`
+	Recipient string    `json:"recipient,omitempty" example:"user@example.com"`
+	Locale    string    `json:"locale,omitempty"    example:"ru"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpdateReminderSettings struct {
+	DaysBefore *int    `json:"days_before,omitempty" example:"3"`
+	Enabled    *bool   `json:"enabled,omitempty"     example:"true"`
+	Channel    *string `json:"channel,omitempty"     enums:"email,webhook,telegram"`
+	Recipient  *string `json:"recipient,omitempty"   example:"user@example.com"`
+	Locale     *string `json:"locale,omitempty"      example:"ru"`
+}
+
+// Reminder — отправленное напоминание. Уникальный индекс по подписке и
+// дате списания гарантирует, что о каждом продлении напоминают один раз,
+// в том числе после перезапуска и при нескольких репликах.
+type Reminder struct {
+	ID             uint      `gorm:"primaryKey"`
+	TenantID       string    `gorm:"not null;default:default;index"`
+	SubscriptionID int       `gorm:"not null;uniqueIndex:idx_reminders_subscription_charge"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
+	ChargeDate     string    `gorm:"not null;uniqueIndex:idx_reminders_subscription_charge"`
+	CreatedAt      time.Time
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+)
+
+// Service — сервис из каталога. Подписки связываются с ним по ServiceID;
+// название подписки сопоставляется с Name и Aliases без учета регистра и
+// лишних пробелов.
+type Service struct {
+	ID        int            `json:"id"                 gorm:"primaryKey"`
+	TenantID  string         `json:"-"                  gorm:"not null;default:default;uniqueIndex:idx_services_tenant_name"`
+	Name      string         `json:"name"               gorm:"not null;uniqueIndex:idx_services_tenant_name" example:"Yandex Plus"`
+	Aliases   []string       `json:"aliases"            gorm:"serializer:json;not null" example:"Яндекс Плюс,yandex+"`
+	Category  string         `json:"category,omitempty" example:"entertainment"`
+	LogoURL   string         `json:"logo_url,omitempty" example:"https://example.com/logos/yandex-plus.png"`
+	Prices    []ServicePrice `json:"prices"             gorm:"serializer:json;not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// ServicePrice — цена сервиса по умолчанию для валюты и периодичности
+type ServicePrice struct {
+	Currency     string        `json:"currency"      example:"RUB"`
+	BillingCycle billing.Cycle `json:"billing_cycle" enums:"monthly,quarterly,yearly"`
+	Amount       int           `json:"amount"        example:"400"`
+}
+
+type CreateService struct {
+	Name     string         `json:"name"               example:"Yandex Plus"`
+	Aliases  []string       `json:"aliases,omitempty"  example:"Яндекс Плюс,yandex+"`
+	Category string         `json:"category,omitempty" example:"entertainment"`
+	LogoURL  string         `json:"logo_url,omitempty" example:"https://example.com/logos/yandex-plus.png"`
+	Prices   []ServicePrice `json:"prices,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "github.com/google/uuid"
+
+// Share — участник совместной подписки. Percent задается для правила
+// percentage, Amount — для fixed; при равном разделении оба пустые.
+// Владелец подписки (UserID подписки) платит остаток.
+type Share struct {
+	ID             int       `json:"-"                 gorm:"primaryKey"`
+	TenantID       string    `json:"-"                 gorm:"not null;default:default;index"`
+	SubscriptionID int       `json:"-"                 gorm:"not null;uniqueIndex:idx_shares_subscription_user"`
+	UserID         uuid.UUID `json:"user_id"           gorm:"type:uuid;not null;uniqueIndex:idx_shares_subscription_user;index"`
+	Percent        *int      `json:"percent,omitempty" example:"25"`
+	Amount         *int      `json:"amount,omitempty"  example:"150"`
+}
+
+type ShareSubscription struct {
+	SplitRule string  `json:"split_rule" enums:"equal,percentage,fixed"`
+	Members   []Share `json:"members"`
+}
@@ -1,30 +1,89 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
 )
 
 type Subscription struct {
-	ID          int       `json:"id"                 gorm:"primaryKey"`
-	ServiceName string    `json:"service_name"       gorm:"not null"`
-	Price       int       `json:"price"              gorm:"not null"`
-	UserID      uuid.UUID `json:"user_id"            gorm:"not null"`
-	StartDate   string    `json:"start_date"         gorm:"not null"`
-	EndDate     *string   `json:"end_date,omitempty"`
+	ID           int              `json:"id"                 gorm:"primaryKey"`
+	TenantID     string           `json:"-"                  gorm:"not null;default:default;index"`
+	ServiceName  string           `json:"service_name"       gorm:"not null"`
+	ServiceID    *int             `json:"service_id,omitempty" gorm:"index"`
+	CategoryID   *int             `json:"category_id,omitempty" gorm:"index"`
+	Tags         []string         `json:"tags"               gorm:"type:jsonb;serializer:json;not null;default:'[]'" example:"work,family"`
+	Price        int              `json:"price"              gorm:"not null"`
+	UserID       uuid.UUID        `json:"user_id"            gorm:"not null"`
+	StartDate    string           `json:"start_date"         gorm:"not null"`
+	EndDate      *string          `json:"end_date,omitempty"`
+	BillingCycle billing.Cycle    `json:"billing_cycle"      gorm:"not null;default:monthly" enums:"monthly,quarterly,yearly"`
+	Status       lifecycle.Status `json:"status"             gorm:"not null;default:active;index" enums:"trialing,active,paused,cancelled,expired"`
+	ExpiredAt    *time.Time       `json:"expired_at,omitempty"`
+	// Отмена подписки. EndDateBeforeCancel — end_date до отмены; он
+	// восстанавливается, если отмену отозвать.
+	CancelledAt         *time.Time `json:"cancelled_at,omitempty"`
+	CancelReason        string     `json:"cancel_reason,omitempty"`
+	CancelAtPeriodEnd   bool       `json:"cancel_at_period_end"`
+	EndDateBeforeCancel *string    `json:"-"`
+	// Pauses — приостановки в порядке начала
+	Pauses []Pause `json:"pauses,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	// SplitRule — правило разделения стоимости совместной подписки между
+	// владельцем и участниками Shares; пустое у несовместной подписки
+	SplitRule string  `json:"split_rule,omitempty" enums:"equal,percentage,fixed"`
+	Shares    []Share `json:"shares,omitempty"     gorm:"constraint:OnDelete:CASCADE"`
+	// PriceChanges — запланированные изменения цены в порядке вступления
+	PriceChanges []PriceChange `json:"price_changes,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	// Service — сервис из каталога; при удалении сервиса связь снимается
+	Service *Service `json:"-" gorm:"constraint:OnDelete:SET NULL"`
+	// Category — категория; при удалении категории связь снимается
+	Category *Category `json:"-" gorm:"constraint:OnDelete:SET NULL"`
+}
+
+// BeforeSave сохраняет отсутствие тегов как пустой список, а не null
+func (s *Subscription) BeforeSave(*gorm.DB) error {
+	if s.Tags == nil {
+		s.Tags = []string{}
+	}
+	return nil
 }
 
 type CreateSubscription struct {
-	ServiceName string    `json:"service_name"       gorm:"not null"`
-	Price       int       `json:"price"              gorm:"not null"`
-	UserID      uuid.UUID `json:"user_id"            gorm:"not null"`
-	StartDate   string    `json:"start_date"         gorm:"not null"`
-	EndDate     *string   `json:"end_date,omitempty"`
+	ServiceName  string           `json:"service_name"       gorm:"not null"`
+	Price        int              `json:"price"              gorm:"not null"`
+	UserID       uuid.UUID        `json:"user_id"            gorm:"not null"`
+	StartDate    string           `json:"start_date"         gorm:"not null"`
+	EndDate      *string          `json:"end_date,omitempty"`
+	BillingCycle billing.Cycle    `json:"billing_cycle,omitempty" enums:"monthly,quarterly,yearly"`
+	Status       lifecycle.Status `json:"status,omitempty"        enums:"trialing,active,paused,cancelled,expired"`
+	// ServiceID связывает подписку с сервисом каталога. Если не задан,
+	// сервис ищется по service_name среди названий и псевдонимов.
+	ServiceID  *int     `json:"service_id,omitempty"`
+	CategoryID *int     `json:"category_id,omitempty"`
+	Tags       []string `json:"tags,omitempty" example:"work,family"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error" example:"error"`
+type CancelSubscription struct {
+	// immediate завершает подписку сразу, end_of_period (по умолчанию) —
+	// в конце текущего оплаченного периода
+	Mode   string `json:"mode,omitempty"   enums:"immediate,end_of_period"`
+	Reason string `json:"reason,omitempty" example:"too expensive"`
 }
 
 type TotalCostResponse struct {
 	Total int `json:"total" example:"1000"`
+	// Groups заполняется при группировке по категории или тегу
+	Groups []CostGroup `json:"groups,omitempty"`
+}
+
+// CostGroup — стоимость подписок одной категории верхнего уровня или
+// одного тега. Пустой Key — подписки без категории или без тегов.
+type CostGroup struct {
+	Key        string `json:"key"                   example:"Entertainment"`
+	CategoryID *int   `json:"category_id,omitempty" example:"1"`
+	Total      int    `json:"total"                 example:"600"`
 }
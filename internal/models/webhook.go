@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// WebhookEndpoint — адрес, на который отправляются события подписок.
+// Пустой список Events означает подписку на все события.
+type WebhookEndpoint struct {
+	ID          uint      `json:"id"                    gorm:"primaryKey"`
+	TenantID    string    `json:"-"                     gorm:"not null;default:default;index"`
+	URL         string    `json:"url"                   gorm:"not null"                         example:"https://example.com/hooks/subscriptions"`
+	Description string    `json:"description,omitempty"`
+	Events      []string  `json:"events"                gorm:"serializer:json;not null"         example:"subscription.created,subscription.deleted"`
+	Secret      string    `json:"-"                     gorm:"not null"`
+	Active      bool      `json:"active"                gorm:"not null;default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateWebhookEndpoint struct {
+	URL         string   `json:"url"                   example:"https://example.com/hooks/subscriptions"`
+	Description string   `json:"description,omitempty"`
+	Events      []string `json:"events,omitempty"      example:"subscription.created,subscription.deleted"`
+}
+
+// CreatedWebhookEndpoint возвращается при регистрации. Секрет подписи
+// показывается только один раз.
+type CreatedWebhookEndpoint struct {
+	WebhookEndpoint
+	Secret string `json:"secret" example:"whsec_5f0c..."`
+}
+
+// WebhookEvent — событие жизненного цикла подписки. Payload — тело
+// запроса, которое получает каждый адрес.
+type WebhookEvent struct {
+	ID        string    `json:"id"         gorm:"type:uuid;primaryKey"`
+	TenantID  string    `json:"-"          gorm:"not null;default:default;index"`
+	Type      string    `json:"type"       gorm:"not null;index"`
+	Payload   string    `json:"-"          gorm:"type:jsonb;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery — доставка события на один адрес
+type WebhookDelivery struct {
+	ID             uint             `json:"id"                         gorm:"primaryKey"`
+	TenantID       string           `json:"-"                          gorm:"not null;default:default;index"`
+	EndpointID     uint             `json:"endpoint_id"                gorm:"not null;index"`
+	Endpoint       *WebhookEndpoint `json:"-"                          gorm:"constraint:OnDelete:CASCADE"`
+	EventID        string           `json:"event_id"                   gorm:"type:uuid;not null;index"`
+	Event          *WebhookEvent    `json:"event,omitempty"            gorm:"foreignKey:EventID"`
+	Status         string           `json:"status"                     gorm:"not null;default:pending;index:idx_webhook_deliveries_due,priority:1" enums:"pending,delivered,dead"`
+	Attempts       int              `json:"attempts"                   gorm:"not null;default:0"`
+	NextAttemptAt  time.Time        `json:"next_attempt_at"            gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	LastStatusCode int              `json:"last_status_code,omitempty"`
+	LastError      string           `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/metrics"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// Статусы доставки уведомления
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Options — параметры очереди доставки
+type Options struct {
+	// PollInterval — период выборки уведомлений, готовых к отправке
+	PollInterval time.Duration
+	// BatchSize — число уведомлений, забираемых за один проход
+	BatchSize int
+	// MaxAttempts — число попыток, после которого доставка прекращается
+	MaxAttempts int
+	// BackoffBase и BackoffMax задают экспоненциальную задержку между
+	// попытками: BackoffBase, 2*BackoffBase, 4*BackoffBase... не больше
+	// BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// ClaimTimeout — время, на которое уведомление резервируется за
+	// репликой. Если реплика упадет во время отправки, после этого срока
+	// уведомление заберет другая.
+	ClaimTimeout time.Duration
+}
+
+// Dispatcher доставляет уведомления из таблицы notification_deliveries.
+// Уведомления ставятся в очередь через Enqueue и отправляются фоновой
+// задачей, поэтому недоступность канала не влияет на вызывающий код.
+type Dispatcher struct {
+	db        *gorm.DB
+	notifiers map[string]Notifier
+	opts      Options
+	now       func() time.Time
+}
+
+func NewDispatcher(db *gorm.DB, opts Options, notifiers ...Notifier) *Dispatcher {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.ClaimTimeout <= 0 {
+		opts.ClaimTimeout = 5 * time.Minute
+	}
+	d := &Dispatcher{
+		db:        db,
+		notifiers: make(map[string]Notifier, len(notifiers)),
+		opts:      opts,
+		now:       time.Now,
+	}
+	for _, n := range notifiers {
+		d.notifiers[n.Channel()] = n
+	}
+	return d
+}
+
+// Enqueue ставит уведомление в очередь. tx может быть транзакцией
+// вызывающего кода: тогда уведомление будет отправлено, только если она
+// зафиксирована.
+func (d *Dispatcher) Enqueue(tx *gorm.DB, msg Message) error {
+	return tx.Create(&models.NotificationDelivery{
+		Channel:       msg.Channel,
+		Recipient:     msg.Recipient,
+		Subject:       msg.Subject,
+		Body:          msg.Body,
+		Status:        StatusPending,
+		NextAttemptAt: d.now().UTC(),
+	}).Error
+}
+
+func (d *Dispatcher) Name() string {
+	return "notification-dispatcher"
+}
+
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		for {
+			n, err := d.DeliverDue(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Log.WithError(err).Error("Notification delivery pass failed")
+				}
+				break
+			}
+			// Полная пачка — вероятно, в очереди есть еще уведомления
+			if n < d.opts.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// DeliverDue отправляет пачку уведомлений, срок попытки которых наступил,
+// и возвращает их число
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	batch, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := range batch {
+		d.deliver(ctx, &batch[i])
+	}
+	return len(batch), nil
+}
+
+// claim резервирует уведомления за этой репликой, сдвигая время следующей
+// попытки на ClaimTimeout. SKIP LOCKED позволяет нескольким репликам
+// разбирать очередь параллельно, не блокируя друг друга.
+func (d *Dispatcher) claim(ctx context.Context) ([]models.NotificationDelivery, error) {
+	var batch []models.NotificationDelivery
+	now := d.now().UTC()
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("next_attempt_at").
+			Limit(d.opts.BatchSize).
+			Find(&batch).Error
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(batch))
+		for i, n := range batch {
+			ids[i] = n.ID
+		}
+		return tx.Model(&models.NotificationDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(d.opts.ClaimTimeout)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim notifications: %w", err)
+	}
+	return batch, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, n *models.NotificationDelivery) {
+	log := logger.Log.WithFields(logrus.Fields{
+		"notification_id": n.ID,
+		"channel":         n.Channel,
+	})
+
+	err := d.send(ctx, n)
+	n.Attempts++
+	now := d.now().UTC()
+	result := StatusSent
+
+	switch {
+	case err == nil:
+		n.Status = StatusSent
+		n.SentAt = &now
+		n.LastError = ""
+		log.Info("Notification sent")
+	case IsPermanent(err) || n.Attempts >= d.opts.MaxAttempts:
+		n.Status = StatusFailed
+		n.LastError = err.Error()
+		result = StatusFailed
+		log.WithError(err).WithField("attempts", n.Attempts).
+			Error("Notification delivery failed permanently")
+	default:
+		n.NextAttemptAt = now.Add(Backoff(n.Attempts, d.opts.BackoffBase, d.opts.BackoffMax))
+		n.LastError = err.Error()
+		result = "retry"
+		log.WithError(err).WithFields(logrus.Fields{
+			"attempts":        n.Attempts,
+			"next_attempt_at": n.NextAttemptAt,
+		}).Warn("Notification delivery failed, will retry")
+	}
+	metrics.NotificationDeliveries.WithLabelValues(n.Channel, result).Inc()
+
+	// Сохраняем результат даже после отмены ctx, иначе отправленное
+	// уведомление уйдет повторно после ClaimTimeout
+	saveCtx := context.WithoutCancel(ctx)
+	if err := d.db.WithContext(saveCtx).Save(n).Error; err != nil {
+		log.WithError(err).Error("Failed to save notification delivery")
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, n *models.NotificationDelivery) error {
+	notifier, ok := d.notifiers[n.Channel]
+	if !ok {
+		return Permanent(fmt.Errorf("notification channel %q is not configured", n.Channel))
+	}
+	return notifier.Notify(ctx, Message{
+		Channel:   n.Channel,
+		Recipient: n.Recipient,
+		Subject:   n.Subject,
+		Body:      n.Body,
+	})
+}
+
+// Backoff возвращает задержку перед попыткой после attempt неудачных:
+// base * 2^(attempt-1), но не больше max
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max || d <= 0 {
+			return max
+		}
+	}
+	return min(d, max)
+}
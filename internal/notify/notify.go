@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+)
+
+// Каналы доставки уведомлений
+const (
+	ChannelEmail    = "email"
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+)
+
+// Message — уведомление, готовое к отправке. Recipient зависит от канала:
+// адрес электронной почты, URL вебхука или chat_id в Telegram.
+type Message struct {
+	Channel   string
+	Recipient string
+	Subject   string
+	Body      string
+}
+
+// Notifier доставляет сообщения по одному каналу
+type Notifier interface {
+	Channel() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// permanentError — ошибка, после которой повторять отправку бессмысленно
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent помечает ошибку как неустранимую: доставка прекращается без
+// повторных попыток
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent сообщает, помечена ли ошибка через Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// ValidateRecipient проверяет адрес получателя для канала
+func ValidateRecipient(channel, recipient string) error {
+	switch channel {
+	case ChannelEmail:
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return fmt.Errorf("invalid email address %q", recipient)
+		}
+	case ChannelWebhook:
+		u, err := url.Parse(recipient)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q", recipient)
+		}
+	case ChannelTelegram:
+		if _, err := strconv.ParseInt(recipient, 10, 64); err != nil {
+			return fmt.Errorf("invalid telegram chat id %q", recipient)
+		}
+	default:
+		return fmt.Errorf("unknown notification channel %q", channel)
+	}
+	return nil
+}
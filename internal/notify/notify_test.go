@@ -0,0 +1,146 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nemopss/subscription-service/internal/notify"
+	"github.com/nemopss/subscription-service/internal/notify/notifytest"
+)
+
+// Тест для отправки письма через SMTP
+func TestSMTPNotifier(t *testing.T) {
+	srv, err := notifytest.NewSMTPServer()
+	require.NoError(t, err)
+	defer srv.Close()
+
+	n := &notify.SMTPNotifier{
+		Host: srv.Host(),
+		Port: srv.Port(),
+		From: "noreply@example.com",
+	}
+	err = n.Notify(context.Background(), notify.Message{
+		Channel:   notify.ChannelEmail,
+		Recipient: "user@example.com",
+		Subject:   "Продление",
+		Body:      "Hello\nworld",
+	})
+	require.NoError(t, err)
+
+	mails := srv.Mails()
+	require.Len(t, mails, 1)
+	assert.Equal(t, "noreply@example.com", mails[0].From)
+	assert.Equal(t, []string{"user@example.com"}, mails[0].To)
+	assert.Contains(t, mails[0].Data, "Subject: =?utf-8?q?")
+	assert.Contains(t, mails[0].Data, "Hello\r\nworld")
+}
+
+// Тест для постоянной ошибки при отвергнутом адресе
+func TestSMTPNotifierRejectedRecipient(t *testing.T) {
+	srv, err := notifytest.NewSMTPServer()
+	require.NoError(t, err)
+	defer srv.Close()
+	srv.RejectRecipient("gone@example.com")
+
+	n := &notify.SMTPNotifier{Host: srv.Host(), Port: srv.Port(), From: "noreply@example.com"}
+	err = n.Notify(context.Background(), notify.Message{Recipient: "gone@example.com", Body: "x"})
+
+	require.Error(t, err)
+	assert.True(t, notify.IsPermanent(err))
+}
+
+// Тест для вебхука: тело запроса и классификация ошибок
+func TestWebhookNotifier(t *testing.T) {
+	sink := notifytest.NewHTTPSink()
+	defer sink.Close()
+	n := &notify.WebhookNotifier{}
+	msg := notify.Message{Recipient: sink.URL + "/hook", Subject: "s", Body: "b"}
+
+	require.NoError(t, n.Notify(context.Background(), msg))
+	reqs := sink.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "/hook", reqs[0].Path)
+	var payload notify.WebhookPayload
+	require.NoError(t, json.Unmarshal(reqs[0].Body, &payload))
+	assert.Equal(t, notify.WebhookPayload{Subject: "s", Body: "b"}, payload)
+
+	sink.SetResponse(http.StatusServiceUnavailable, "")
+	err := n.Notify(context.Background(), msg)
+	require.Error(t, err)
+	assert.False(t, notify.IsPermanent(err))
+
+	sink.SetResponse(http.StatusGone, "")
+	err = n.Notify(context.Background(), msg)
+	require.Error(t, err)
+	assert.True(t, notify.IsPermanent(err))
+}
+
+// Тест для Telegram Bot API
+func TestTelegramNotifier(t *testing.T) {
+	sink := notifytest.NewHTTPSink()
+	defer sink.Close()
+	n := &notify.TelegramNotifier{Token: "123:abc", APIURL: sink.URL}
+
+	err := n.Notify(context.Background(), notify.Message{Recipient: "42", Subject: "s", Body: "b"})
+	require.NoError(t, err)
+
+	reqs := sink.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "/bot123:abc/sendMessage", reqs[0].Path)
+	assert.JSONEq(t, `{"chat_id":"42","text":"s\n\nb"}`, string(reqs[0].Body))
+
+	sink.SetResponse(http.StatusBadRequest, `{"ok":false,"description":"chat not found"}`)
+	err = n.Notify(context.Background(), notify.Message{Recipient: "43", Body: "b"})
+	assert.True(t, notify.IsPermanent(err))
+	assert.ErrorContains(t, err, "chat not found")
+}
+
+// Тест для выбора локали шаблона
+func TestTemplatesRender(t *testing.T) {
+	tmpl, err := notify.LoadTemplates("en")
+	require.NoError(t, err)
+	data := map[string]any{
+		"ServiceName":  "Yandex Plus",
+		"ChargeDate":   "2026-10-21",
+		"Price":        400,
+		"BillingCycle": "yearly",
+	}
+
+	subject, body, err := tmpl.Render("renewal_reminder", "ru-RU", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Продление Yandex Plus 2026-10-21", subject)
+	assert.Contains(t, body, "раз в год")
+
+	subject, _, err = tmpl.Render("renewal_reminder", "de", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Yandex Plus renews on 2026-10-21", subject)
+
+	_, _, err = tmpl.Render("missing", "en", data)
+	assert.Error(t, err)
+}
+
+// Тест для экспоненциальной задержки между попытками
+func TestBackoff(t *testing.T) {
+	base, max := 30*time.Second, 10*time.Minute
+	assert.Equal(t, 30*time.Second, notify.Backoff(1, base, max))
+	assert.Equal(t, time.Minute, notify.Backoff(2, base, max))
+	assert.Equal(t, 8*time.Minute, notify.Backoff(5, base, max))
+	assert.Equal(t, max, notify.Backoff(6, base, max))
+	assert.Equal(t, max, notify.Backoff(100, base, max))
+}
+
+// Тест для проверки адресов получателей
+func TestValidateRecipient(t *testing.T) {
+	assert.NoError(t, notify.ValidateRecipient(notify.ChannelEmail, "user@example.com"))
+	assert.Error(t, notify.ValidateRecipient(notify.ChannelEmail, "user"))
+	assert.NoError(t, notify.ValidateRecipient(notify.ChannelWebhook, "https://example.com/hook"))
+	assert.Error(t, notify.ValidateRecipient(notify.ChannelWebhook, "ftp://example.com"))
+	assert.NoError(t, notify.ValidateRecipient(notify.ChannelTelegram, "-100123"))
+	assert.Error(t, notify.ValidateRecipient("sms", "+100"))
+}
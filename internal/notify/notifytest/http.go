@@ -0,0 +1,66 @@
+package notifytest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Request — запрос, принятый HTTPSink
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// HTTPSink — HTTP-сервер, запоминающий запросы. По умолчанию отвечает
+// 200 с телом {"ok":true}; SetResponse меняет ответ.
+type HTTPSink struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+	status   int
+	body     string
+}
+
+func NewHTTPSink() *HTTPSink {
+	s := &HTTPSink{status: http.StatusOK, body: `{"ok":true}`}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// SetResponse задает статус и тело ответа на следующие запросы
+func (s *HTTPSink) SetResponse(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	s.body = body
+}
+
+// Requests возвращает принятые запросы
+func (s *HTTPSink) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *HTTPSink) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	status, respBody := s.status, s.body
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, respBody)
+}
@@ -0,0 +1,168 @@
+// Package notifytest содержит локальные приемники уведомлений для тестов:
+// SMTP-сервер и HTTP-сервер, которые запоминают все полученные сообщения.
+package notifytest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Mail — письмо, принятое SMTPServer
+type Mail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// SMTPServer — минимальный SMTP-сервер без TLS и аутентификации.
+// RejectRecipients заставляет отвечать 550 на RCPT TO для указанных адресов.
+type SMTPServer struct {
+	Addr string
+
+	listener net.Listener
+	mu       sync.Mutex
+	mails    []Mail
+	rejected map[string]bool
+	wg       sync.WaitGroup
+}
+
+// NewSMTPServer запускает сервер на случайном локальном порту
+func NewSMTPServer() (*SMTPServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &SMTPServer{
+		Addr:     l.Addr().String(),
+		listener: l,
+		rejected: make(map[string]bool),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Host и Port возвращают адрес сервера для настройки клиента
+func (s *SMTPServer) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr)
+	return host
+}
+
+func (s *SMTPServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// RejectRecipient отвергает письма на адрес постоянной ошибкой
+func (s *SMTPServer) RejectRecipient(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected[strings.ToLower(addr)] = true
+}
+
+// Mails возвращает принятые письма
+func (s *SMTPServer) Mails() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mail(nil), s.mails...)
+}
+
+func (s *SMTPServer) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *SMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *SMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+
+	reply("220 notifytest ESMTP")
+	var mail Mail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-notifytest")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "HELO"):
+			reply("250 notifytest")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail = Mail{From: trimAddr(line[len("MAIL FROM:"):])}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			addr := trimAddr(line[len("RCPT TO:"):])
+			s.mu.Lock()
+			rejected := s.rejected[strings.ToLower(addr)]
+			s.mu.Unlock()
+			if rejected {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			mail.To = append(mail.To, addr)
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			mail.Data = data.String()
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "RSET":
+			mail = Mail{}
+			reply("250 OK")
+		case cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// trimAddr убирает угловые скобки и параметры ESMTP из адреса
+func trimAddr(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, ">"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimPrefix(s, "<")
+}
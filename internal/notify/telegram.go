@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultTelegramAPIURL — адрес Telegram Bot API
+const DefaultTelegramAPIURL = "https://api.telegram.org"
+
+// TelegramNotifier отправляет уведомления через Telegram Bot API.
+// Получатель — chat_id пользователя или группы.
+type TelegramNotifier struct {
+	Token  string
+	APIURL string
+	Client *http.Client
+}
+
+type telegramMessage struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+func (n *TelegramNotifier) Channel() string {
+	return ChannelTelegram
+}
+
+func (n *TelegramNotifier) Notify(ctx context.Context, msg Message) error {
+	text := msg.Body
+	if msg.Subject != "" {
+		text = msg.Subject + "\n\n" + msg.Body
+	}
+	payload, err := json.Marshal(telegramMessage{ChatID: msg.Recipient, Text: text})
+	if err != nil {
+		return Permanent(err)
+	}
+
+	apiURL := n.APIURL
+	if apiURL == "" {
+		apiURL = DefaultTelegramAPIURL
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		strings.TrimRight(apiURL, "/")+"/bot"+n.Token+"/sendMessage",
+		bytes.NewReader(payload),
+	)
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Ошибка содержит URL с токеном бота, поэтому не передаем ее дальше
+		return fmt.Errorf("telegram request failed")
+	}
+	defer resp.Body.Close()
+
+	var body telegramResponse
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if err := statusError("telegram", resp.StatusCode); err != nil {
+		if body.Description != "" {
+			err = fmt.Errorf("%w: %s", err, body.Description)
+		}
+		return err
+	}
+	if !body.OK {
+		return Permanent(fmt.Errorf("telegram rejected message: %s", body.Description))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Templates хранит шаблоны сообщений. Файл шаблона называется
+// <имя>.<локаль>.tmpl и определяет блоки "subject" и "body".
+type Templates struct {
+	defaultLocale string
+	byKey         map[string]*template.Template
+}
+
+// LoadTemplates разбирает встроенные шаблоны. defaultLocale используется,
+// если для запрошенной локали нет перевода.
+func LoadTemplates(defaultLocale string) (*Templates, error) {
+	t := &Templates{
+		defaultLocale: defaultLocale,
+		byKey:         make(map[string]*template.Template),
+	}
+
+	files, err := fs.Glob(templateFS, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		key := strings.TrimSuffix(path.Base(file), ".tmpl")
+		tmpl, err := template.ParseFS(templateFS, file)
+		if err != nil {
+			return nil, fmt.Errorf("parse template %s: %w", file, err)
+		}
+		for _, block := range []string{"subject", "body"} {
+			if tmpl.Lookup(block) == nil {
+				return nil, fmt.Errorf("template %s: missing %q block", file, block)
+			}
+		}
+		t.byKey[key] = tmpl
+	}
+	return t, nil
+}
+
+// Render заполняет шаблон name для локали. Для "ru-RU" сначала ищется
+// "ru-RU", затем "ru", затем локаль по умолчанию.
+func (t *Templates) Render(name, locale string, data any) (subject, body string, err error) {
+	tmpl, ok := t.lookup(name, locale)
+	if !ok {
+		return "", "", fmt.Errorf("template %q not found", name)
+	}
+
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "subject", data); err != nil {
+		return "", "", fmt.Errorf("render %s subject: %w", name, err)
+	}
+	subject = strings.TrimSpace(b.String())
+
+	b.Reset()
+	if err := tmpl.ExecuteTemplate(&b, "body", data); err != nil {
+		return "", "", fmt.Errorf("render %s body: %w", name, err)
+	}
+	return subject, strings.TrimSpace(b.String()), nil
+}
+
+func (t *Templates) lookup(name, locale string) (*template.Template, bool) {
+	candidates := []string{locale}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		candidates = append(candidates, lang)
+	}
+	candidates = append(candidates, t.defaultLocale)
+
+	for _, l := range candidates {
+		if tmpl, ok := t.byKey[name+"."+strings.ToLower(l)]; ok {
+			return tmpl, true
+		}
+	}
+	return nil, false
+}
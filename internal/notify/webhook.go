@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookNotifier отправляет уведомление POST-запросом с JSON-телом на
+// URL получателя
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+// WebhookPayload — тело запроса вебхука
+type WebhookPayload struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+func (n *WebhookNotifier) Channel() string {
+	return ChannelWebhook
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(WebhookPayload{Subject: msg.Subject, Body: msg.Body})
+	if err != nil {
+		return Permanent(err)
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		msg.Recipient,
+		bytes.NewReader(payload),
+	)
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return statusError("webhook", resp.StatusCode)
+}
+
+func (n *WebhookNotifier) client() *http.Client {
+	if n.Client != nil {
+		return n.Client
+	}
+	return http.DefaultClient
+}
+
+// statusError превращает неуспешный HTTP-статус в ошибку. Ошибки клиента
+// (кроме 408 и 429) не исправятся повтором и считаются постоянными.
+func statusError(service string, status int) error {
+	if status >= 200 && status < 300 {
+		return nil
+	}
+	err := fmt.Errorf("%s responded with status %d", service, status)
+	if status >= 400 && status < 500 &&
+		status != http.StatusRequestTimeout &&
+		status != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package orgs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nemopss/subscription-service/internal/catalog"
+	"github.com/nemopss/subscription-service/internal/costs"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// Виды организаций
+const (
+	KindOrganization = "organization"
+	KindHousehold    = "household"
+)
+
+// Роли участников. Владелец есть в организации всегда.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// ParseKind проверяет вид организации; пустой означает organization
+func ParseKind(kind string) (string, error) {
+	switch kind {
+	case "":
+		return KindOrganization, nil
+	case KindOrganization, KindHousehold:
+		return kind, nil
+	}
+	return "", fmt.Errorf("unknown kind %q, expected organization or household", kind)
+}
+
+// ParseRole проверяет роль участника; пустая означает member
+func ParseRole(role string) (string, error) {
+	switch role {
+	case "":
+		return RoleMember, nil
+	case RoleOwner, RoleAdmin, RoleMember:
+		return role, nil
+	}
+	return "", fmt.Errorf("unknown role %q, expected owner, admin or member", role)
+}
+
+// KeepsOwner сообщает, останется ли в организации владелец, если
+// пользователь userID получит роль role. Пустая роль означает удаление.
+func KeepsOwner(members []models.Member, userID uuid.UUID, role string) bool {
+	if role == RoleOwner {
+		return true
+	}
+	for _, m := range members {
+		if m.UserID != userID && m.Role == RoleOwner {
+			return true
+		}
+	}
+	return false
+}
+
+// UserIDs возвращает идентификаторы участников
+func UserIDs(members []models.Member) []uuid.UUID {
+	out := make([]uuid.UUID, 0, len(members))
+	for _, m := range members {
+		out = append(out, m.UserID)
+	}
+	return out
+}
+
+// Total считает расходы участников по подпискам за период так же, как
+// /subscriptions/total: каждый участник платит свою долю совместных
+// подписок, поэтому подписка, разделенная между участниками, учитывается
+// один раз. Сервисы из каталога группируются по сервису, остальные — по
+// нормализованному названию.
+func Total(members []models.Member, subs []models.Subscription, from, to, openEnd time.Time) models.OrganizationTotal {
+	out := models.OrganizationTotal{
+		Members:  make([]models.MemberCost, 0, len(members)),
+		Services: []models.ServiceCost{},
+	}
+	services := map[string]*models.ServiceCost{}
+	var order []string
+
+	for _, m := range members {
+		mc := models.MemberCost{UserID: m.UserID, Role: m.Role}
+		for _, sub := range subs {
+			cost, err := costs.UserCost(sub, m.UserID, from, to, openEnd)
+			if err != nil {
+				logger.Log.WithError(err).
+					WithField("id", sub.ID).
+					Error("Invalid subscription dates")
+				continue
+			}
+			if cost == 0 {
+				continue
+			}
+			mc.Total += cost
+
+			key, name := "name:"+catalog.Normalize(sub.ServiceName), sub.ServiceName
+			if sub.Service != nil {
+				key, name = "service:"+strconv.Itoa(sub.Service.ID), sub.Service.Name
+			}
+			sc, ok := services[key]
+			if !ok {
+				sc = &models.ServiceCost{ServiceName: name}
+				if sub.Service != nil {
+					sc.ServiceID = &sub.Service.ID
+				}
+				services[key] = sc
+				order = append(order, key)
+			}
+			sc.Total += cost
+		}
+		out.Total += mc.Total
+		out.Members = append(out.Members, mc)
+	}
+
+	for _, key := range order {
+		out.Services = append(out.Services, *services[key])
+	}
+	sort.SliceStable(out.Members, func(i, j int) bool {
+		return out.Members[i].Total > out.Members[j].Total
+	})
+	sort.SliceStable(out.Services, func(i, j int) bool {
+		return out.Services[i].Total > out.Services[j].Total
+	})
+	return out
+}
@@ -0,0 +1,81 @@
+package orgs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Тест для проверки, что в организации остается владелец
+func TestKeepsOwner(t *testing.T) {
+	owner, admin := uuid.New(), uuid.New()
+	members := []models.Member{
+		{UserID: owner, Role: RoleOwner},
+		{UserID: admin, Role: RoleAdmin},
+	}
+
+	assert.True(t, KeepsOwner(members, admin, RoleMember))
+	assert.True(t, KeepsOwner(members, admin, ""))
+	assert.True(t, KeepsOwner(members, owner, RoleOwner))
+	assert.False(t, KeepsOwner(members, owner, RoleAdmin))
+	assert.False(t, KeepsOwner(members, owner, ""))
+
+	members[1].Role = RoleOwner
+	assert.True(t, KeepsOwner(members, owner, ""))
+}
+
+// Тест для расходов организации по участникам и сервисам
+func TestTotal(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	members := []models.Member{
+		{UserID: alice, Role: RoleOwner},
+		{UserID: bob, Role: RoleMember},
+	}
+	end := "2025-02-01"
+	service := &models.Service{ID: 7, Name: "Yandex Plus"}
+	subs := []models.Subscription{
+		// Разделена между участниками и учитывается один раз
+		{UserID: alice, ServiceName: "Yandex Plus", Service: service, Price: 400, StartDate: "2025-01-01", EndDate: &end,
+			SplitRule: "equal", Shares: []models.Share{{UserID: bob}}},
+		{UserID: bob, ServiceName: "яндекс плюс", Service: service, Price: 300, StartDate: "2025-01-01", EndDate: &end},
+		{UserID: bob, ServiceName: "Netflix", Price: 600, StartDate: "2025-01-01", EndDate: &end},
+	}
+
+	got := Total(members, subs, date(2025, 1, 1), date(2025, 12, 31), date(2025, 12, 31))
+	assert.Equal(t, 2*(400+300+600), got.Total)
+	assert.Equal(t, []models.MemberCost{
+		{UserID: bob, Role: RoleMember, Total: 2 * (200 + 300 + 600)},
+		{UserID: alice, Role: RoleOwner, Total: 2 * 200},
+	}, got.Members)
+	if assert.Len(t, got.Services, 2) {
+		assert.Equal(t, "Yandex Plus", got.Services[0].ServiceName)
+		assert.Equal(t, 7, *got.Services[0].ServiceID)
+		assert.Equal(t, 1400, got.Services[0].Total)
+		assert.Equal(t, "Netflix", got.Services[1].ServiceName)
+		assert.Nil(t, got.Services[1].ServiceID)
+		assert.Equal(t, 1200, got.Services[1].Total)
+	}
+}
+
+// Тест для разбора вида организации и роли
+func TestParseKindAndRole(t *testing.T) {
+	kind, err := ParseKind("")
+	assert.NoError(t, err)
+	assert.Equal(t, KindOrganization, kind)
+	_, err = ParseKind("company")
+	assert.Error(t, err)
+
+	role, err := ParseRole("")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleMember, role)
+	_, err = ParseRole("guest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/broker"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+)
+
+// Заголовки сообщений в брокере
+const (
+	HeaderEventID   = broker.HeaderMessageID
+	HeaderEventType = "Event-Type"
+)
+
+// Envelope — тело сообщения, которое получают потребители
+type Envelope struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	TenantID      string    `json:"tenant_id"`
+	AggregateType string    `json:"aggregate_type"`
+	AggregateID   string    `json:"aggregate_id"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	Data          any       `json:"data"`
+}
+
+// Write записывает событие в outbox. tx должна быть транзакцией, в которой
+// выполняется само изменение: тогда событие опубликуется тогда и только
+// тогда, когда изменение зафиксировано. Арендатор события — арендатор из
+// контекста tx.
+func Write(tx *gorm.DB, aggregateType, aggregateID, eventType string, data any) error {
+	tenantID, _ := tenancy.FromContext(tx.Statement.Context)
+	env := Envelope{
+		ID:            uuid.NewString(),
+		Type:          eventType,
+		TenantID:      tenantID,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		OccurredAt:    time.Now().UTC(),
+		Data:          data,
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxEvent{
+		EventID:       env.ID,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Type:          eventType,
+		Payload:       string(payload),
+		CreatedAt:     env.OccurredAt,
+	}).Error
+}
+
+// toMessages превращает события в сообщения брокера. Ключ — агрегат,
+// поэтому события одной подписки попадают в один раздел.
+func toMessages(events []models.OutboxEvent) []broker.Message {
+	msgs := make([]broker.Message, len(events))
+	for i, e := range events {
+		msgs[i] = broker.Message{
+			Key:   e.AggregateType + ":" + e.AggregateID,
+			Value: []byte(e.Payload),
+			Headers: map[string]string{
+				HeaderEventID:   e.EventID,
+				HeaderEventType: e.Type,
+			},
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/nemopss/subscription-service/internal/broker"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+)
+
+// Тест для ключей, заголовков и порядка сообщений
+func TestToMessagesKeepsOrderAndKeys(t *testing.T) {
+	events := []models.OutboxEvent{
+		{ID: 1, EventID: "e1", AggregateType: "subscription", AggregateID: "7", Type: "subscription.created", Payload: `{"n":1}`},
+		{ID: 2, EventID: "e2", AggregateType: "subscription", AggregateID: "8", Type: "subscription.created", Payload: `{"n":2}`},
+		{ID: 3, EventID: "e3", AggregateType: "subscription", AggregateID: "7", Type: "subscription.deleted", Payload: `{"n":3}`},
+	}
+
+	b := broker.NewMemoryBroker()
+	require.NoError(t, b.Publish(context.Background(), toMessages(events)...))
+
+	msgs := b.Messages()
+	require.Len(t, msgs, 3)
+	var forSeven []string
+	for _, m := range msgs {
+		if m.Key == "subscription:7" {
+			forSeven = append(forSeven, m.Headers[HeaderEventType])
+		}
+	}
+	assert.Equal(t, []string{"subscription.created", "subscription.deleted"}, forSeven)
+	assert.Equal(t, "e2", msgs[1].Headers[HeaderEventID])
+	assert.Equal(t, `{"n":2}`, string(msgs[1].Value))
+}
+
+// Тест для отказа брокера: пачка не публикуется частично
+func TestMemoryBrokerFailNext(t *testing.T) {
+	b := broker.NewMemoryBroker()
+	b.FailNext(errors.New("broker down"))
+
+	err := b.Publish(context.Background(), broker.Message{Key: "a"}, broker.Message{Key: "b"})
+	assert.Error(t, err)
+	assert.Empty(t, b.Messages())
+
+	require.NoError(t, b.Publish(context.Background(), broker.Message{Key: "a"}))
+	assert.Len(t, b.Messages(), 1)
+}
+
+// Тест для арендатора в теле события: берется из контекста транзакции
+func TestWriteRecordsTenant(t *testing.T) {
+	db, err := gorm.Open(
+		postgres.New(postgres.Config{DSN: "host=localhost"}),
+		&gorm.Config{
+			DryRun:                 true,
+			DisableAutomaticPing:   true,
+			SkipDefaultTransaction: true,
+			Logger:                 logger.Discard,
+		},
+	)
+	require.NoError(t, err)
+	var written *models.OutboxEvent
+	require.NoError(t, db.Callback().Create().After("gorm:create").Register(
+		"test:capture",
+		func(tx *gorm.DB) { written, _ = tx.Statement.Dest.(*models.OutboxEvent) },
+	))
+
+	tx := db.WithContext(tenancy.WithTenant(context.Background(), "acme"))
+	require.NoError(t, Write(tx, "subscription", "7", "subscription.created", map[string]int{"id": 7}))
+	require.NotNil(t, written)
+
+	var env Envelope
+	require.NoError(t, json.Unmarshal([]byte(written.Payload), &env))
+	assert.Equal(t, "acme", env.TenantID)
+	assert.Equal(t, written.EventID, env.ID)
+	assert.Contains(t, written.Payload, `"tenant_id":"acme"`)
+}
@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/broker"
+	"github.com/nemopss/subscription-service/internal/metrics"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// relayLockKey — ключ advisory-блокировки, которую держит работающий relay
+const relayLockKey = 0x6f7574626f78 // "outbox"
+
+// Options — параметры relay
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Retention — сколько хранить опубликованные события; 0 — не удалять
+	Retention time.Duration
+}
+
+// Relay публикует события из outbox в брокер.
+//
+// Доставка — at-least-once: событие отмечается опубликованным только
+// после подтверждения брокера, и если отметка не сохранится, событие будет
+// опубликовано повторно. Потребители должны устранять повторы по
+// заголовку Message-Id.
+//
+// Порядок: в каждый момент публикует только одна реплика (advisory-
+// блокировка на время транзакции), события берутся по возрастанию ID, а
+// пачка публикуется целиком или не публикуется вовсе. Поэтому события
+// одной подписки приходят в порядке записи.
+type Relay struct {
+	db        *gorm.DB
+	publisher broker.Publisher
+	opts      Options
+	now       func() time.Time
+}
+
+func NewRelay(db *gorm.DB, publisher broker.Publisher, opts Options) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &Relay{db: db, publisher: publisher, opts: opts, now: time.Now}
+}
+
+func (r *Relay) Name() string {
+	return "outbox-relay"
+}
+
+func (r *Relay) Run(ctx context.Context) error {
+	defer r.publisher.Close()
+
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		for {
+			n, err := r.PublishPending(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Log.WithError(err).Error("Outbox relay pass failed")
+				}
+				break
+			}
+			if n < r.opts.BatchSize {
+				break
+			}
+		}
+		if err := r.cleanup(ctx); err != nil && ctx.Err() == nil {
+			logger.Log.WithError(err).Error("Failed to delete published outbox events")
+		}
+	}
+}
+
+// PublishPending публикует следующую пачку неопубликованных событий и
+// возвращает ее размер. Если relay работает в другой реплике, возвращает 0.
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	published := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", relayLockKey).
+			Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		var batch []models.OutboxEvent
+		err := tx.Where("published_at IS NULL").
+			Order("id").
+			Limit(r.opts.BatchSize).
+			Find(&batch).Error
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		if err := r.publisher.Publish(ctx, toMessages(batch)...); err != nil {
+			metrics.OutboxPublished.WithLabelValues("error").Add(float64(len(batch)))
+			return fmt.Errorf("publish outbox events: %w", err)
+		}
+		metrics.OutboxPublished.WithLabelValues("ok").Add(float64(len(batch)))
+
+		ids := make([]uint64, len(batch))
+		for i, e := range batch {
+			ids[i] = e.ID
+		}
+		published = len(batch)
+		return tx.Model(&models.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("published_at", r.now().UTC()).Error
+	})
+	return published, err
+}
+
+// cleanup удаляет опубликованные события старше Retention
+func (r *Relay) cleanup(ctx context.Context) error {
+	if r.opts.Retention <= 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Where("published_at < ?", r.now().UTC().Add(-r.opts.Retention)).
+		Delete(&models.OutboxEvent{}).Error
+}
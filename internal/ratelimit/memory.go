@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	window time.Duration
+}
+
+// MemoryStore хранит бакеты в памяти процесса
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+
+	// sweepInterval — период удаления полностью восполненных бакетов
+	sweepInterval time.Duration
+	lastSweep     time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:       make(map[string]*bucket),
+		now:           time.Now,
+		sweepInterval: time.Minute,
+		lastSweep:     time.Now(),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	// Бакеты удаляются и без фоновой задачи, чтобы память не росла, если
+	// хранилище не запущено как задача
+	if now.Sub(s.lastSweep) >= s.sweepInterval {
+		s.sweepLocked(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.window = limit.Window()
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.last = now
+
+	res := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	res.Remaining = int(math.Floor(b.tokens))
+	res.Reset = seconds((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return res, nil
+}
+
+// Name и Run позволяют запускать очистку бакетов как фоновую задачу
+func (s *MemoryStore) Name() string {
+	return "ratelimit-sweeper"
+}
+
+func (s *MemoryStore) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+// sweep удаляет бакеты, которые успели полностью восполниться: они
+// неотличимы от новых
+func (s *MemoryStore) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked(s.now())
+}
+
+func (s *MemoryStore) sweepLocked(now time.Time) {
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= b.window {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
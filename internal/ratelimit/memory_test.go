@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Тест для расхода и восполнения токенов
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	res, _ := s.Take(ctx, "user:1", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, _ = s.Take(ctx, "user:1", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 2*time.Second, res.Reset)
+
+	res, _ = s.Take(ctx, "user:1", limit)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// Другой ключ не затронут
+	res, _ = s.Take(ctx, "user:2", limit)
+	assert.True(t, res.Allowed)
+
+	now = now.Add(1500 * time.Millisecond)
+	res, _ = s.Take(ctx, "user:1", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
+
+// Тест для удаления восполненных бакетов
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	s.Take(context.Background(), "user:1", Limit{Rate: 1, Burst: 2})
+
+	now = now.Add(time.Second)
+	s.sweep()
+	assert.Len(t, s.buckets, 1)
+
+	now = now.Add(time.Second)
+	s.sweep()
+	assert.Empty(t, s.buckets)
+}
+
+// Тест для удаления восполненных бакетов без фоновой задачи
+func TestMemoryStoreSweepOnTake(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	s.lastSweep = now
+	limit := Limit{Rate: 1, Burst: 2}
+	for _, key := range []string{"ip:1", "ip:2", "ip:3"} {
+		s.Take(context.Background(), key, limit)
+	}
+
+	now = now.Add(s.sweepInterval)
+	s.Take(context.Background(), "ip:4", limit)
+	assert.Len(t, s.buckets, 1)
+}
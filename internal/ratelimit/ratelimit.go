@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/nemopss/subscription-service/internal/limits"
+)
+
+// Limit и Policy описаны в пакете limits, чтобы конфигурация могла
+// проверять лимиты, не завися от хранилищ
+type (
+	Limit  = limits.Limit
+	Policy = limits.Policy
+)
+
+// ParsePolicy разбирает лимит по умолчанию и лимиты маршрутов
+var ParsePolicy = limits.ParsePolicy
+
+// Result — результат попытки взять токен
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset — время до полного восполнения запаса
+	Reset time.Duration
+	// RetryAfter — время до появления следующего токена, если запрос
+	// отклонен
+	RetryAfter time.Duration
+}
+
+// Store хранит состояние бакетов. Реализация в памяти подходит для одного
+// экземпляра сервиса; для нескольких реплик нужна общая (например, Redis).
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,44 @@
+package reminders
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/notify"
+)
+
+// TemplateName — шаблон сообщения о предстоящем продлении
+const TemplateName = "renewal_reminder"
+
+// NotifySender ставит напоминания в очередь уведомлений по каналу из
+// настроек пользователя. Напоминания пользователей без канала передаются
+// Fallback.
+type NotifySender struct {
+	Dispatcher *notify.Dispatcher
+	Templates  *notify.Templates
+	Fallback   Sender
+}
+
+func (s *NotifySender) SendReminder(ctx context.Context, tx *gorm.DB, r Reminder) error {
+	if r.Channel == "" {
+		return s.Fallback.SendReminder(ctx, tx, r)
+	}
+
+	subject, body, err := s.Templates.Render(TemplateName, r.Locale, map[string]any{
+		"ServiceName":  r.ServiceName,
+		"Price":        r.Price,
+		"BillingCycle": r.BillingCycle,
+		"ChargeDate":   r.ChargeDate.Format(billing.DateLayout),
+	})
+	if err != nil {
+		return err
+	}
+	return s.Dispatcher.Enqueue(tx, notify.Message{
+		Channel:   r.Channel,
+		Recipient: r.Recipient,
+		Subject:   subject,
+		Body:      body,
+	})
+}
@@ -0,0 +1,56 @@
+package reminders
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/limits"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// MaxDaysBefore — максимальный срок, за который можно напомнить о продлении
+const MaxDaysBefore = limits.ReminderDaysBefore
+
+// Reminder — напоминание о предстоящем списании по подписке
+type Reminder struct {
+	SubscriptionID int
+	UserID         uuid.UUID
+	ServiceName    string
+	Price          int
+	BillingCycle   billing.Cycle
+	ChargeDate     time.Time
+
+	// Канал доставки из настроек пользователя
+	Channel   string
+	Recipient string
+	Locale    string
+}
+
+// Sender доставляет напоминания пользователю. tx — транзакция, в которой
+// записывается факт отправки: Sender может поставить уведомление в
+// очередь в ней же. Ошибка означает, что напоминание не доставлено и
+// будет отправлено при следующем проходе.
+type Sender interface {
+	SendReminder(ctx context.Context, tx *gorm.DB, r Reminder) error
+}
+
+// LogSender записывает напоминания в журнал. Используется, пока не
+// настроен другой канал доставки.
+type LogSender struct{}
+
+func (LogSender) SendReminder(ctx context.Context, _ *gorm.DB, r Reminder) error {
+	logger.FromContext(ctx).WithFields(logrus.Fields{
+		"subscription_id": r.SubscriptionID,
+		"user_id":         r.UserID,
+		"service_name":    r.ServiceName,
+		"price":           r.Price,
+		"billing_cycle":   r.BillingCycle,
+		"charge_date":     r.ChargeDate.Format(billing.DateLayout),
+	}).Info("Renewal reminder")
+	return nil
+}
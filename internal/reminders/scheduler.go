@@ -0,0 +1,232 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+	"github.com/nemopss/subscription-service/internal/lifecycle"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+const batchSize = 500
+
+// Scheduler периодически просматривает активные подписки и отправляет
+// напоминания о списаниях, до которых осталось не больше заданного числа
+// дней.
+//
+// Каждое напоминание записывается в таблицу reminders в одной транзакции
+// с отправкой. Уникальный индекс по (subscription_id, charge_date) не дает
+// отправить одно напоминание дважды: ни после перезапуска, ни при
+// одновременной работе нескольких реплик. Если отправка не удалась,
+// транзакция откатывается и напоминание повторяется на следующем проходе.
+type Scheduler struct {
+	db         *gorm.DB
+	sender     Sender
+	interval   time.Duration
+	daysBefore int
+	now        func() time.Time
+}
+
+// NewScheduler создает планировщик. daysBefore применяется к
+// пользователям без собственных настроек.
+func NewScheduler(db *gorm.DB, sender Sender, interval time.Duration, daysBefore int) *Scheduler {
+	return &Scheduler{
+		db:         db,
+		sender:     sender,
+		interval:   interval,
+		daysBefore: daysBefore,
+		now:        time.Now,
+	}
+}
+
+func (s *Scheduler) Name() string {
+	return "renewal-reminders"
+}
+
+// Run выполняет проход сразу после запуска и затем раз в interval
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			logger.Log.WithError(err).Error("Renewal reminder pass failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// candidate — активная подписка вместе с настройками напоминаний
+// пользователя
+type candidate struct {
+	models.Subscription
+	DaysBefore int
+	Channel    string
+	Recipient  string
+	Locale     string
+}
+
+// RunOnce просматривает все активные подписки пачками по batchSize
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	today := s.now().UTC()
+	todayStr := today.Format(billing.DateLayout)
+
+	sent, lastID := 0, 0
+	for {
+		var batch []candidate
+		err := s.db.WithContext(ctx).
+			Table("subscriptions AS s").
+			Select(
+				"s.*, COALESCE(rs.days_before, ?) AS days_before, "+
+					"rs.channel, rs.recipient, rs.locale",
+				s.daysBefore,
+			).
+			Joins("LEFT JOIN reminder_settings rs ON rs.tenant_id = s.tenant_id AND rs.user_id = s.user_id").
+			Where("s.id > ?", lastID).
+			Where("s.status IN ?", []lifecycle.Status{lifecycle.Active, lifecycle.Trialing}).
+			Where("s.end_date IS NULL OR s.end_date >= ?", todayStr).
+			Where("COALESCE(rs.enabled, TRUE)").
+			Order("s.id").
+			Limit(batchSize).
+			Scan(&batch).Error
+		if err != nil {
+			return fmt.Errorf("load subscriptions: %w", err)
+		}
+
+		for _, c := range batch {
+			ok, err := s.remind(ctx, c, today)
+			if err != nil {
+				logger.Log.WithError(err).
+					WithField("subscription_id", c.ID).
+					Error("Failed to send renewal reminder")
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+
+		if len(batch) < batchSize {
+			break
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+
+	logger.Log.WithField("sent", sent).Info("Renewal reminder pass finished")
+	return nil
+}
+
+// remind отправляет напоминание по подписке, если оно причитается и еще
+// не было отправлено
+func (s *Scheduler) remind(ctx context.Context, c candidate, today time.Time) (bool, error) {
+	start, err := time.Parse(billing.DateLayout, c.StartDate)
+	if err != nil {
+		return false, fmt.Errorf("parse start_date: %w", err)
+	}
+	var end *time.Time
+	if c.EndDate != nil {
+		t, err := time.Parse(billing.DateLayout, *c.EndDate)
+		if err != nil {
+			return false, fmt.Errorf("parse end_date: %w", err)
+		}
+		end = &t
+	}
+
+	charge, ok := due(start, c.BillingCycle, end, c.DaysBefore, today)
+	if !ok {
+		return false, nil
+	}
+
+	r := Reminder{
+		SubscriptionID: c.ID,
+		UserID:         c.UserID,
+		ServiceName:    c.ServiceName,
+		Price:          c.Price,
+		BillingCycle:   c.BillingCycle,
+		ChargeDate:     charge,
+		Channel:        c.Channel,
+		Recipient:      c.Recipient,
+		Locale:         c.Locale,
+	}
+	// Напоминание и уведомление принадлежат арендатору подписки
+	sent := false
+	err = tenancy.Scope(s.db.WithContext(ctx), c.TenantID).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.Reminder{
+				SubscriptionID: c.ID,
+				UserID:         c.UserID,
+				ChargeDate:     charge.Format(billing.DateLayout),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// Уже отправлено ранее или другой репликой
+			return nil
+		}
+		if err := s.sender.SendReminder(ctx, tx, r); err != nil {
+			return err
+		}
+		sent = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if sent {
+		logger.Log.WithFields(logrus.Fields{
+			"subscription_id": c.ID,
+			"charge_date":     charge.Format(billing.DateLayout),
+		}).Info("Renewal reminder sent")
+	}
+	return sent, nil
+}
+
+// due возвращает дату ближайшего продления, если до нее осталось не
+// больше daysBefore дней. Первое списание (в день начала подписки) и
+// списания после окончания подписки продлениями не считаются.
+func due(
+	start time.Time,
+	cycle billing.Cycle,
+	end *time.Time,
+	daysBefore int,
+	today time.Time,
+) (time.Time, bool) {
+	charge := billing.NextChargeDate(start, cycle, today)
+	if !charge.After(start) {
+		return time.Time{}, false
+	}
+	if end != nil && charge.After(*end) {
+		return time.Time{}, false
+	}
+
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	if charge.After(today.AddDate(0, 0, daysBefore)) {
+		return time.Time{}, false
+	}
+	return charge, true
+}
+
+// Defaults возвращает настройки пользователя, которые действуют, пока он
+// не задал собственные
+func Defaults(userID uuid.UUID, daysBefore int) models.ReminderSettings {
+	return models.ReminderSettings{
+		UserID:     userID,
+		DaysBefore: daysBefore,
+		Enabled:    true,
+	}
+}
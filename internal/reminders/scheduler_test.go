@@ -0,0 +1,53 @@
+package reminders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/billing"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(billing.DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Тест для выбора подписок, о продлении которых пора напомнить
+func TestDue(t *testing.T) {
+	end := date("2026-10-31")
+	tests := []struct {
+		name   string
+		start  string
+		cycle  billing.Cycle
+		end    *time.Time
+		days   int
+		today  string
+		charge string
+		due    bool
+	}{
+		{"monthly within window", "2026-01-21", billing.Monthly, nil, 3, "2026-10-19", "2026-10-21", true},
+		{"monthly on charge day", "2026-01-21", billing.Monthly, nil, 0, "2026-10-21", "2026-10-21", true},
+		{"monthly outside window", "2026-01-25", billing.Monthly, nil, 3, "2026-10-19", "", false},
+		{"yearly within window", "2024-10-20", billing.Yearly, nil, 7, "2026-10-19", "2026-10-20", true},
+		{"quarterly not this month", "2026-02-20", billing.Quarterly, nil, 7, "2026-10-19", "", false},
+		{"quarterly this month", "2026-01-20", billing.Quarterly, nil, 7, "2026-10-14", "2026-10-20", true},
+		{"first charge is not a renewal", "2026-10-20", billing.Monthly, nil, 3, "2026-10-19", "", false},
+		{"ended before charge", "2026-01-21", billing.Monthly, &end, 3, "2026-10-30", "", false},
+		{"ends after charge", "2026-01-21", billing.Monthly, &end, 3, "2026-10-19", "2026-10-21", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charge, ok := due(date(tt.start), tt.cycle, tt.end, tt.days, date(tt.today))
+			assert.Equal(t, tt.due, ok)
+			if tt.due {
+				assert.Equal(t, date(tt.charge), charge)
+			}
+		})
+	}
+}
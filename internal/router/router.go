@@ -0,0 +1,142 @@
+package router
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
+	"github.com/nemopss/subscription-service/internal/config"
+	"github.com/nemopss/subscription-service/internal/handlers"
+	"github.com/nemopss/subscription-service/internal/health"
+	"github.com/nemopss/subscription-service/internal/metrics"
+	"github.com/nemopss/subscription-service/internal/middleware"
+	"github.com/nemopss/subscription-service/internal/ratelimit"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+)
+
+// apiVersion описывает одну версию API: префикс и функцию регистрации
+// маршрутов. Каждая версия регистрирует свои обработчики и может
+// использовать собственные модели, поэтому /api/v2 добавляется новой
+// записью в versions, не затрагивая /api/v1.
+type apiVersion struct {
+	prefix   string
+	register func(rg *gin.RouterGroup, cfg *config.Config)
+}
+
+var versions = []apiVersion{
+	{prefix: "/api/v1", register: registerV1},
+}
+
+// Маршруты без префикса версии оставлены как псевдонимы /api/v1 на время
+// миграции клиентов.
+var (
+	legacyPrefix     = "/api/v1"
+	legacyDeprecated = time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC)
+	legacySunset     = time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// Deps — зависимости, которые маршрутизатор передает обработчикам
+type Deps struct {
+	// Health выполняет проверки готовности для /readyz. Если не задан,
+	// /readyz отвечает без проверок.
+	Health *health.Checker
+	// RateLimitStore хранит состояние лимитов. Если не задан, используется
+	// хранилище в памяти.
+	RateLimitStore ratelimit.Store
+}
+
+// New создает маршрутизатор со всеми версиями API, проверками состояния,
+// а также, если они включены в конфигурации, трассировкой, метриками,
+// устаревшими псевдонимами и Swagger. Метрики HTTP собираются для всех маршрутов,
+// зарегистрированных после подключения middleware.
+func New(cfg *config.Config, deps Deps) *gin.Engine {
+	r := gin.New()
+	// IP клиента из X-Forwarded-For принимается только от известных прокси;
+	// список проверен при загрузке конфигурации
+	_ = r.SetTrustedProxies(cfg.Server.TrustedProxies)
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(middleware.NotFound)
+	r.NoMethod(middleware.MethodNotAllowed)
+	r.Use(middleware.RequestID())
+
+	if cfg.Tracing.Enabled {
+		r.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	}
+	r.Use(middleware.Logger(), middleware.Recovery())
+
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.Metrics())
+		r.GET(cfg.Metrics.Path, gin.WrapH(promhttp.HandlerFor(
+			metrics.Registry,
+			promhttp.HandlerOpts{Registry: metrics.Registry},
+		)))
+	}
+
+	// Ошибки обработчиков превращаются в ответы последними, чтобы журнал
+	// доступа и метрики видели итоговый статус
+	r.Use(middleware.Errors())
+
+	if deps.Health == nil {
+		deps.Health = health.NewChecker(cfg.Health.Timeout)
+	}
+	r.GET("/healthz", handlers.Liveness)
+	r.GET("/readyz", handlers.Readiness(deps.Health))
+
+	// Middleware, общие для всех маршрутов API (но не для служебных
+	// эндпоинтов /healthz, /readyz и /metrics)
+	var api []gin.HandlerFunc
+	resolver := newResolver(cfg.Tenancy)
+	if cfg.RateLimit.Enabled {
+		if deps.RateLimitStore == nil {
+			deps.RateLimitStore = ratelimit.NewMemoryStore()
+		}
+		// Конфигурация проверена при загрузке
+		policy, _ := ratelimit.ParsePolicy(
+			cfg.RateLimit.Default,
+			cfg.RateLimit.Routes,
+		)
+		api = append(api, middleware.RateLimit(
+			deps.RateLimitStore,
+			policy,
+			cfg.RateLimit.APIKeys,
+			resolver,
+		))
+	}
+	api = append(api, middleware.Tenant(resolver))
+
+	for _, v := range versions {
+		v.register(r.Group(v.prefix, api...), cfg)
+	}
+
+	if cfg.Features.LegacyRoutes {
+		legacy := append([]gin.HandlerFunc{middleware.Deprecated(
+			legacyDeprecated,
+			legacySunset,
+			legacyPrefix,
+		)}, api...)
+		registerV1(r.Group("", legacy...), cfg)
+	}
+
+	if cfg.Features.Swagger {
+		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+
+	return r
+}
+
+// newResolver создает определитель арендатора запроса или возвращает nil,
+// если разделение на арендаторов выключено
+func newResolver(cfg config.TenancyConfig) *tenancy.Resolver {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &tenancy.Resolver{
+		HostSuffix: cfg.HostSuffix,
+		Secret:     []byte(cfg.JWTSecret),
+		Claim:      cfg.JWTClaim,
+	}
+}
@@ -0,0 +1,194 @@
+package router_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/nemopss/subscription-service/internal/config"
+	"github.com/nemopss/subscription-service/internal/health"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/router"
+	"github.com/nemopss/subscription-service/internal/tracing"
+)
+
+// Тест для устаревших маршрутов без префикса версии
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := router.New(config.Default(), router.Deps{})
+
+	req, _ := http.NewRequest("GET", "/subscriptions/abc", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Deprecation"))
+	assert.NotEmpty(t, w.Header().Get("Sunset"))
+	assert.Equal(
+		t,
+		"</api/v1/subscriptions/abc>; rel=\"successor-version\"",
+		w.Header().Get("Link"),
+	)
+
+	// Преемник сохраняет строку запроса
+	req, _ = http.NewRequest("GET", "/subscriptions/abc?user_id=u1&tags=a%2Cb", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(
+		t,
+		"</api/v1/subscriptions/abc?user_id=u1&tags=a%2Cb>; rel=\"successor-version\"",
+		w.Header().Get("Link"),
+	)
+}
+
+// Тест для маршрутов /api/v1
+func TestVersionedRoutesAreNotDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := router.New(config.Default(), router.Deps{})
+
+	req, _ := http.NewRequest("GET", "/api/v1/subscriptions/abc", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+}
+
+// Тест для отключения устаревших маршрутов
+func TestLegacyRoutesDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.Features.LegacyRoutes = false
+	r := router.New(cfg, router.Deps{})
+
+	req, _ := http.NewRequest("GET", "/subscriptions/abc", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// Тест для проверок состояния
+func TestHealthEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	r := router.New(config.Default(), router.Deps{Health: checker})
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/readyz", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var report health.Report
+	json.Unmarshal(w.Body.Bytes(), &report)
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+}
+
+// Тест для метрик HTTP с шаблоном маршрута в метке route
+func TestMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := router.New(config.Default(), router.Deps{})
+
+	req, _ := http.NewRequest("GET", "/api/v1/subscriptions/abc", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(
+		t,
+		w.Body.String(),
+		`subscription_service_http_requests_total{method="GET",route="/api/v1/subscriptions/:id",status="400"}`,
+	)
+	assert.NotContains(t, w.Body.String(), "/api/v1/subscriptions/abc")
+}
+
+// Тест для серверного спана, продолжающего трассу из traceparent
+func TestTracingPropagatesTraceparent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	cfg := config.Default()
+	_, err := tracing.Init(context.Background(), cfg.Tracing)
+	assert.NoError(t, err)
+	cfg.Tracing.Enabled = true
+	r := router.New(cfg, router.Deps{})
+
+	req, _ := http.NewRequest("GET", "/api/v1/subscriptions/abc", nil)
+	req.Header.Set(
+		"traceparent",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "/api/v1/subscriptions/:id", spans[0].Name)
+		assert.Equal(
+			t,
+			"4bf92f3577b34da6a3ce929d0e0e4736",
+			spans[0].SpanContext.TraceID().String(),
+		)
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+	}
+}
+
+// Тест для ответов об ошибках в формате application/problem+json
+func TestProblemResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := router.New(config.Default(), router.Deps{})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		{"GET", "/api/v1/subscriptions/abc", http.StatusBadRequest, "invalid_id"},
+		{"GET", "/api/v1/subscriptions/total?user_id=nope", http.StatusBadRequest, "invalid_user_id"},
+		{"GET", "/api/v1/unknown", http.StatusNotFound, "route_not_found"},
+		{"PATCH", "/api/v1/subscriptions", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		var problem models.Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		assert.Equal(t, tt.status, problem.Status)
+		assert.Equal(t, tt.code, problem.Code)
+		assert.Equal(t, "urn:subscription-service:problem:"+tt.code, problem.Type)
+		assert.Equal(t, "req-1", problem.RequestID)
+	}
+}
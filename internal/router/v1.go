@@ -0,0 +1,86 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/nemopss/subscription-service/internal/config"
+	"github.com/nemopss/subscription-service/internal/handlers"
+)
+
+// registerV1 регистрирует маршруты первой версии API.
+func registerV1(rg *gin.RouterGroup, cfg *config.Config) {
+	subs := rg.Group("/subscriptions")
+	subs.POST("", handlers.CreateSubscription)
+	subs.GET("/:id", handlers.GetSubscription)
+	subs.PUT("/:id", handlers.UpdateSubscription)
+	subs.DELETE("/:id", handlers.DeleteSubscription)
+	subs.POST("/:id/pause", handlers.PauseSubscription)
+	subs.POST("/:id/resume", handlers.ResumeSubscription)
+	subs.POST("/:id/cancel", handlers.CancelSubscription)
+	subs.POST("/:id/uncancel", handlers.UncancelSubscription)
+	subs.POST("/:id/price-changes", handlers.CreatePriceChange)
+	subs.DELETE("/:id/price-changes/:change_id", handlers.DeletePriceChange)
+	subs.PUT("/:id/shares", handlers.ShareSubscription)
+	subs.DELETE("/:id/shares", handlers.UnshareSubscription)
+	subs.GET("", handlers.ListSubscriptions)
+	subs.GET("/total", handlers.GetTotalCostByPeriod)
+	subs.GET("/forecast", handlers.GetForecast)
+	subs.GET("/duplicates", handlers.ListDuplicates)
+
+	services := rg.Group("/services")
+	services.POST("", handlers.CreateService)
+	services.GET("", handlers.ListServices)
+	services.GET("/:id", handlers.GetService)
+	services.PUT("/:id", handlers.UpdateService)
+	services.DELETE("/:id", handlers.DeleteService)
+
+	categories := rg.Group("/categories")
+	categories.POST("", handlers.CreateCategory)
+	categories.GET("", handlers.ListCategories)
+	categories.GET("/:id", handlers.GetCategory)
+	categories.PUT("/:id", handlers.UpdateCategory)
+	categories.DELETE("/:id", handlers.DeleteCategory)
+
+	organizations := rg.Group("/organizations")
+	organizations.POST("", handlers.CreateOrganization)
+	organizations.GET("", handlers.ListOrganizations)
+	organizations.GET("/:id", handlers.GetOrganization)
+	organizations.PUT("/:id", handlers.UpdateOrganization)
+	organizations.DELETE("/:id", handlers.DeleteOrganization)
+	organizations.PUT("/:id/members/:user_id", handlers.PutMember)
+	organizations.DELETE("/:id/members/:user_id", handlers.DeleteMember)
+	organizations.GET("/:id/subscriptions", handlers.ListOrganizationSubscriptions)
+	organizations.GET("/:id/total", handlers.GetOrganizationTotal)
+
+	stats := rg.Group("/analytics")
+	stats.GET("/spend", handlers.GetSpendTrend)
+	stats.GET("/top-services", handlers.GetTopServices)
+	stats.GET("/average-price", handlers.GetAveragePrice)
+	stats.GET("/churn", handlers.GetChurn)
+
+	users := rg.Group("/users/:user_id")
+	users.GET(
+		"/reminder-settings",
+		handlers.GetReminderSettings(cfg.Reminders.DaysBefore),
+	)
+	users.PUT(
+		"/reminder-settings",
+		handlers.UpdateReminderSettings(cfg.Reminders.DaysBefore),
+	)
+	users.POST("/budgets", handlers.CreateBudget)
+	users.GET("/budgets", handlers.ListBudgets)
+	users.GET("/budgets/report", handlers.GetBudgetReport)
+	users.PUT("/budgets/:id", handlers.UpdateBudget)
+	users.DELETE("/budgets/:id", handlers.DeleteBudget)
+
+	hooks := rg.Group("/webhooks")
+	hooks.POST("", handlers.CreateWebhook)
+	hooks.GET("", handlers.ListWebhooks)
+	hooks.GET("/:id", handlers.GetWebhook)
+	hooks.DELETE("/:id", handlers.DeleteWebhook)
+	hooks.GET("/:id/deliveries", handlers.ListWebhookDeliveries)
+	hooks.POST(
+		"/:id/deliveries/:delivery_id/redeliver",
+		handlers.RedeliverWebhook,
+	)
+}
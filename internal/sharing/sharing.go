@@ -0,0 +1,131 @@
+package sharing
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+// Правила разделения стоимости совместной подписки
+const (
+	// Equal делит цену поровну между владельцем и участниками
+	Equal = "equal"
+	// Percentage назначает участникам доли в процентах от цены
+	Percentage = "percentage"
+	// Fixed назначает участникам фиксированные суммы с каждого списания
+	Fixed = "fixed"
+)
+
+// MaxMembers — наибольшее число участников подписки кроме владельца
+const MaxMembers = 20
+
+// Validate проверяет участников совместной подписки sub. Владелец не
+// может быть участником; сумма процентов не больше 100, сумма
+// фиксированных долей не больше текущей цены подписки.
+func Validate(sub models.Subscription, req models.ShareSubscription) error {
+	if len(req.Members) == 0 || len(req.Members) > MaxMembers {
+		return fmt.Errorf("members must contain from 1 to %d users", MaxMembers)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(req.Members))
+	sum := 0
+	for _, m := range req.Members {
+		if m.UserID == uuid.Nil || m.UserID == sub.UserID {
+			return fmt.Errorf("member user_id must be set and differ from the owner")
+		}
+		if seen[m.UserID] {
+			return fmt.Errorf("member %s is listed twice", m.UserID)
+		}
+		seen[m.UserID] = true
+
+		switch req.SplitRule {
+		case Equal:
+			if m.Percent != nil || m.Amount != nil {
+				return fmt.Errorf("an equal split takes neither percent nor amount")
+			}
+		case Percentage:
+			if m.Percent == nil || *m.Percent <= 0 || m.Amount != nil {
+				return fmt.Errorf("a percentage split requires a positive percent for every member")
+			}
+			sum += *m.Percent
+		case Fixed:
+			if m.Amount == nil || *m.Amount < 0 || m.Percent != nil {
+				return fmt.Errorf("a fixed split requires a non-negative amount for every member")
+			}
+			sum += *m.Amount
+		default:
+			return fmt.Errorf("unknown split_rule %q, expected equal, percentage or fixed", req.SplitRule)
+		}
+	}
+
+	if req.SplitRule == Percentage && sum > 100 {
+		return fmt.Errorf("percentages add up to %d, more than 100", sum)
+	}
+	if req.SplitRule == Fixed && sum > sub.Price {
+		return fmt.Errorf("fixed amounts add up to %d, more than the price %d", sum, sub.Price)
+	}
+	return nil
+}
+
+// ShareOf возвращает часть списания price, которую платит пользователь.
+// Владелец несовместной подписки платит все, пользователь, не
+// участвующий в подписке, — ничего. Остаток от округления и нераспределенная
+// часть цены приходятся на владельца; если цена стала меньше суммы
+// фиксированных долей, участники платят по порядку, пока хватает цены.
+func ShareOf(sub models.Subscription, userID uuid.UUID, price int) int {
+	if sub.SplitRule == "" || len(sub.Shares) == 0 {
+		if userID == sub.UserID {
+			return price
+		}
+		return 0
+	}
+
+	rest := price
+	mine := 0
+	for _, m := range sub.Shares {
+		var part int
+		switch sub.SplitRule {
+		case Equal:
+			part = price / (len(sub.Shares) + 1)
+		case Percentage:
+			if m.Percent != nil {
+				part = price * *m.Percent / 100
+			}
+		case Fixed:
+			if m.Amount != nil {
+				part = *m.Amount
+			}
+		}
+		part = min(part, rest)
+		rest -= part
+		if m.UserID == userID {
+			mine = part
+		}
+	}
+	if userID == sub.UserID {
+		return rest
+	}
+	return mine
+}
+
+// Participants возвращает владельца и участников подписки
+func Participants(sub models.Subscription) []uuid.UUID {
+	out := []uuid.UUID{sub.UserID}
+	for _, m := range sub.Shares {
+		out = append(out, m.UserID)
+	}
+	return out
+}
+
+// ForUser ограничивает запрос подписками, которыми пользователь владеет
+// или в которых участвует
+func ForUser(tx *gorm.DB, userID uuid.UUID) *gorm.DB {
+	members := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&models.Share{}).
+		Select("subscription_id").
+		Where("user_id = ?", userID)
+	return tx.Where("(user_id = ? OR id IN (?))", userID, members)
+}
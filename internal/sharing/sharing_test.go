@@ -0,0 +1,67 @@
+package sharing
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// Тест для проверки участников совместной подписки
+func TestValidate(t *testing.T) {
+	owner, a, b := uuid.New(), uuid.New(), uuid.New()
+	sub := models.Subscription{UserID: owner, Price: 1000}
+
+	valid := []models.ShareSubscription{
+		{SplitRule: Equal, Members: []models.Share{{UserID: a}, {UserID: b}}},
+		{SplitRule: Percentage, Members: []models.Share{{UserID: a, Percent: intPtr(60)}, {UserID: b, Percent: intPtr(40)}}},
+		{SplitRule: Fixed, Members: []models.Share{{UserID: a, Amount: intPtr(1000)}}},
+	}
+	for _, req := range valid {
+		assert.NoError(t, Validate(sub, req), req.SplitRule)
+	}
+
+	invalid := []models.ShareSubscription{
+		{SplitRule: Equal},
+		{SplitRule: Equal, Members: []models.Share{{UserID: owner}}},
+		{SplitRule: Equal, Members: []models.Share{{UserID: a}, {UserID: a}}},
+		{SplitRule: Equal, Members: []models.Share{{UserID: a, Percent: intPtr(10)}}},
+		{SplitRule: Percentage, Members: []models.Share{{UserID: a, Percent: intPtr(70)}, {UserID: b, Percent: intPtr(40)}}},
+		{SplitRule: Fixed, Members: []models.Share{{UserID: a, Amount: intPtr(1001)}}},
+		{SplitRule: "weighted", Members: []models.Share{{UserID: a}}},
+	}
+	for _, req := range invalid {
+		assert.Error(t, Validate(sub, req), req.SplitRule)
+	}
+}
+
+// Тест для долей участников и владельца
+func TestShareOf(t *testing.T) {
+	owner, a, b := uuid.New(), uuid.New(), uuid.New()
+
+	sub := models.Subscription{UserID: owner, SplitRule: Equal, Shares: []models.Share{{UserID: a}, {UserID: b}}}
+	assert.Equal(t, 333, ShareOf(sub, a, 1000))
+	assert.Equal(t, 334, ShareOf(sub, owner, 1000))
+	assert.Equal(t, 0, ShareOf(sub, uuid.New(), 1000))
+
+	sub = models.Subscription{UserID: owner, SplitRule: Percentage, Shares: []models.Share{{UserID: a, Percent: intPtr(25)}}}
+	assert.Equal(t, 250, ShareOf(sub, a, 1000))
+	assert.Equal(t, 750, ShareOf(sub, owner, 1000))
+
+	// Цена снизилась ниже суммы фиксированных долей
+	sub = models.Subscription{UserID: owner, SplitRule: Fixed, Shares: []models.Share{
+		{UserID: a, Amount: intPtr(300)},
+		{UserID: b, Amount: intPtr(300)},
+	}}
+	assert.Equal(t, 300, ShareOf(sub, a, 500))
+	assert.Equal(t, 200, ShareOf(sub, b, 500))
+	assert.Equal(t, 0, ShareOf(sub, owner, 500))
+
+	assert.Equal(t, 1000, ShareOf(models.Subscription{UserID: owner}, owner, 1000))
+}
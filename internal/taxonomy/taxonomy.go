@@ -0,0 +1,97 @@
+package taxonomy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+// Ограничения пользовательских тегов
+const (
+	MaxTags      = 20
+	MaxTagLength = 50
+)
+
+// NormalizeTags приводит теги к нижнему регистру, убирает лишние пробелы
+// и повторы. Пустые теги пропускаются.
+func NormalizeTags(tags []string) ([]string, error) {
+	out := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || slices.Contains(out, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+		out = append(out, tag)
+	}
+	if len(out) > MaxTags {
+		return nil, fmt.Errorf("a subscription can have at most %d tags", MaxTags)
+	}
+	return out, nil
+}
+
+// Tree — категории, проиндексированные для обхода иерархии
+type Tree struct {
+	byID     map[int]models.Category
+	children map[int][]int
+}
+
+// NewTree строит дерево из плоского списка категорий
+func NewTree(categories []models.Category) *Tree {
+	t := &Tree{
+		byID:     make(map[int]models.Category, len(categories)),
+		children: map[int][]int{},
+	}
+	for _, c := range categories {
+		t.byID[c.ID] = c
+		if c.ParentID != nil {
+			t.children[*c.ParentID] = append(t.children[*c.ParentID], c.ID)
+		}
+	}
+	return t
+}
+
+// Get возвращает категорию по ID
+func (t *Tree) Get(id int) (models.Category, bool) {
+	c, ok := t.byID[id]
+	return c, ok
+}
+
+// Subtree возвращает ID категории и всех ее потомков
+func (t *Tree) Subtree(id int) []int {
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, t.children[ids[i]]...)
+	}
+	return ids
+}
+
+// Root возвращает категорию верхнего уровня, в которую входит id
+func (t *Tree) Root(id int) (models.Category, bool) {
+	c, ok := t.byID[id]
+	for i := 0; ok && c.ParentID != nil && i < len(t.byID); i++ {
+		parent, found := t.byID[*c.ParentID]
+		if !found {
+			break
+		}
+		c = parent
+	}
+	return c, ok
+}
+
+// CanMove сообщает, можно ли сделать parentID родителем категории id, не
+// создав цикл. Новая категория передается с id == 0.
+func (t *Tree) CanMove(id int, parentID *int) bool {
+	if parentID == nil {
+		return true
+	}
+	if _, ok := t.byID[*parentID]; !ok {
+		return false
+	}
+	return id == 0 || !slices.Contains(t.Subtree(id), *parentID)
+}
@@ -0,0 +1,56 @@
+package taxonomy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nemopss/subscription-service/internal/models"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// Тест для нормализации тегов
+func TestNormalizeTags(t *testing.T) {
+	got, err := NormalizeTags([]string{" Work ", "work", "", "Family  Plan"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work", "family plan"}, got)
+
+	got, err = NormalizeTags(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, got)
+
+	tooMany := make([]string, MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = string(rune('a' + i))
+	}
+	_, err = NormalizeTags(tooMany)
+	assert.Error(t, err)
+}
+
+// Тест для обхода дерева категорий
+func TestTree(t *testing.T) {
+	tree := NewTree([]models.Category{
+		{ID: 1, Name: "Entertainment"},
+		{ID: 2, Name: "Streaming", ParentID: intPtr(1)},
+		{ID: 3, Name: "Video", ParentID: intPtr(2)},
+		{ID: 4, Name: "Work"},
+	})
+
+	assert.ElementsMatch(t, []int{1, 2, 3}, tree.Subtree(1))
+	assert.Equal(t, []int{4}, tree.Subtree(4))
+
+	root, ok := tree.Root(3)
+	assert.True(t, ok)
+	assert.Equal(t, 1, root.ID)
+
+	assert.True(t, tree.CanMove(3, intPtr(4)))
+	assert.True(t, tree.CanMove(0, intPtr(3)))
+	assert.False(t, tree.CanMove(1, intPtr(3)), "moving under a descendant creates a cycle")
+	assert.False(t, tree.CanMove(1, intPtr(1)))
+	assert.False(t, tree.CanMove(2, intPtr(99)), "unknown parent")
+	assert.True(t, tree.CanMove(2, nil))
+}
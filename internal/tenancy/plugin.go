@@ -0,0 +1,187 @@
+package tenancy
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Column — колонка арендатора в таблицах с данными арендаторов
+const Column = "tenant_id"
+
+// field — поле моделей, по которому плагин узнает данные арендаторов
+const field = "TenantID"
+
+// ErrNoTenant возвращается для запроса к данным арендаторов, в контексте
+// которого нет ни арендатора, ни признака служебного запроса
+var ErrNoTenant = errors.New("tenant is not set for the query")
+
+// Plugin ограничивает запросы GORM арендатором из контекста, поэтому
+// обработчикам не нужно добавлять условие самим и о нем нельзя забыть.
+// Действует на модели с полем TenantID:
+//   - выборки, обновления и удаления получают условие tenant_id = ?;
+//   - при создании TenantID заполняется арендатором, а upsert не
+//     обновляет чужие строки;
+//   - обновления не меняют tenant_id.
+//
+// Запрос без арендатора завершается ErrNoTenant, если контекст не помечен
+// через System. Сырые запросы (Raw, Exec) плагин разобрать не может: от
+// имени арендатора они выполняются, только если построены через Raw или
+// Exec этого пакета, иначе завершаются ErrRawQuery.
+//
+// С RowLevelSecurity плагин также задает для каждого запроса параметр
+// сеанса Setting — арендатора, SystemSetting для служебных запросов или
+// пустую строку, — чтобы политики Postgres RLS действовали и на запросы
+// сервиса. Запрос вне транзакции выполняется в короткой транзакции, поэтому
+// стоит трех дополнительных обращений к базе, начало транзакции — одного.
+type Plugin struct {
+	RowLevelSecurity bool
+}
+
+func (Plugin) Name() string {
+	return "tenancy"
+}
+
+func (p Plugin) Initialize(db *gorm.DB) error {
+	if p.RowLevelSecurity {
+		sqlDB, ok := db.ConnPool.(*sql.DB)
+		if !ok {
+			return fmt.Errorf("row level security needs *sql.DB, got %T", db.ConnPool)
+		}
+		pool := &sessionPool{db: sqlDB}
+		db.ConnPool = pool
+		db.Statement.ConnPool = pool
+	}
+
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		register  func(name string, fn func(*gorm.DB)) error
+		fn        func(*gorm.DB)
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, assign},
+		{"query", cb.Query().Before("gorm:query").Register, restrict},
+		{"row", cb.Row().Before("gorm:row").Register, restrict},
+		{"update", cb.Update().Before("gorm:update").Register, restrictUpdate},
+		{"delete", cb.Delete().Before("gorm:delete").Register, restrictDelete},
+		{"raw", cb.Raw().Before("gorm:raw").Register, checkRaw},
+	}
+	for _, h := range hooks {
+		if err := h.register("tenancy:"+h.operation, h.fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tenant возвращает арендатора, которым нужно ограничить запрос. false —
+// запрос не ограничивается: модель без арендатора, служебный запрос или
+// ошибка ErrNoTenant.
+func tenant(db *gorm.DB) (string, bool) {
+	stmt := db.Statement
+	if stmt.Schema == nil || stmt.Schema.LookUpField(field) == nil {
+		return "", false
+	}
+	if id, ok := FromContext(stmt.Context); ok {
+		return id, true
+	}
+	if !IsSystem(stmt.Context) {
+		db.AddError(ErrNoTenant)
+	}
+	return "", false
+}
+
+func condition(id string) clause.Expression {
+	return clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: Column},
+		Value:  id,
+	}
+}
+
+func assign(db *gorm.DB) {
+	id, ok := tenant(db)
+	if !ok {
+		return
+	}
+	db.Statement.SetColumn(field, id, true)
+
+	// Upsert не должен перезаписать строку другого арендатора с тем же
+	// ключом
+	c, ok := db.Statement.Clauses["ON CONFLICT"]
+	if !ok {
+		return
+	}
+	onConflict, ok := c.Expression.(clause.OnConflict)
+	if !ok || onConflict.DoNothing {
+		return
+	}
+	onConflict.Where.Exprs = append(onConflict.Where.Exprs, condition(id))
+	c.Expression = onConflict
+	db.Statement.Clauses["ON CONFLICT"] = c
+}
+
+func restrict(db *gorm.DB) {
+	// Условия не попадут в запрос, построенный через Raw
+	if db.Statement.SQL.Len() > 0 {
+		checkRaw(db)
+		return
+	}
+	if id, ok := tenant(db); ok {
+		addCondition(db.Statement, id)
+	}
+}
+
+func restrictUpdate(db *gorm.DB) {
+	id, ok := tenant(db)
+	if !ok {
+		return
+	}
+	db.Statement.Omits = append(db.Statement.Omits, Column)
+	if conditioned(db) {
+		addCondition(db.Statement, id)
+	}
+}
+
+func restrictDelete(db *gorm.DB) {
+	if id, ok := tenant(db); ok && conditioned(db) {
+		addCondition(db.Statement, id)
+	}
+}
+
+// conditioned сообщает, есть ли у обновления или удаления собственные
+// условия. Без них условие арендатора не добавляется, чтобы GORM, как и
+// раньше, отклонил запрос к целой таблице.
+func conditioned(db *gorm.DB) bool {
+	stmt := db.Statement
+	if _, ok := stmt.Clauses["WHERE"]; ok || db.AllowGlobalUpdate {
+		return true
+	}
+	_, values := schema.GetIdentityFieldValuesMap(
+		stmt.Context,
+		stmt.ReflectValue,
+		stmt.Schema.PrimaryFields,
+	)
+	return len(values) > 0
+}
+
+// addCondition добавляет условие арендатора к условиям запроса. Прежние
+// условия берутся в скобки, чтобы OR в них не обошел ограничение.
+func addCondition(stmt *gorm.Statement, id string) {
+	where := clause.Where{Exprs: []clause.Expression{condition(id)}}
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if existing, ok := c.Expression.(clause.Where); ok && len(existing.Exprs) > 0 {
+			where.Exprs = []clause.Expression{
+				clause.And(existing.Exprs...),
+				condition(id),
+			}
+		}
+		c.Expression = where
+		stmt.Clauses["WHERE"] = c
+		return
+	}
+	stmt.AddClause(where)
+}
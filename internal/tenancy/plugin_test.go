@@ -0,0 +1,216 @@
+package tenancy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+type record struct {
+	ID       int
+	TenantID string
+	Name     string
+}
+
+type global struct {
+	ID   int
+	Name string
+}
+
+// dryRun открывает GORM без подключения к базе: запросы только строятся
+func dryRun(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(
+		postgres.New(postgres.Config{DSN: "host=localhost"}),
+		&gorm.Config{
+			DryRun:                 true,
+			DisableAutomaticPing:   true,
+			SkipDefaultTransaction: true,
+			Logger:                 logger.Discard,
+		},
+	)
+	require.NoError(t, err)
+	require.NoError(t, db.Use(Plugin{}))
+	return db
+}
+
+// Тест для условия арендатора в выборках, обновлениях и удалениях
+func TestPluginRestricts(t *testing.T) {
+	db := dryRun(t).WithContext(WithTenant(context.Background(), "acme"))
+
+	stmt := db.Where("name = ?", "a").Or("name = ?", "b").Find(&[]record{}).Statement
+	assert.Equal(t,
+		`SELECT * FROM "records" WHERE (name = $1 OR name = $2) AND "records"."tenant_id" = $3`,
+		stmt.SQL.String(),
+	)
+	assert.Equal(t, []any{"a", "b", "acme"}, stmt.Vars)
+
+	stmt = db.Model(&record{ID: 7}).Update("name", "c").Statement
+	assert.Equal(t,
+		`UPDATE "records" SET "name"=$1 WHERE "records"."tenant_id" = $2 AND "id" = $3`,
+		stmt.SQL.String(),
+	)
+
+	stmt = db.Delete(&record{}, 7).Statement
+	assert.Equal(t,
+		`DELETE FROM "records" WHERE "records"."id" = $1 AND "records"."tenant_id" = $2`,
+		stmt.SQL.String(),
+	)
+
+	var count int64
+	stmt = db.Model(&record{}).Count(&count).Statement
+	assert.Contains(t, stmt.SQL.String(), `WHERE "records"."tenant_id" = $1`)
+}
+
+// Тест для записи арендатора при создании и защиты upsert
+func TestPluginAssigns(t *testing.T) {
+	db := dryRun(t).WithContext(WithTenant(context.Background(), "acme"))
+
+	r := record{Name: "a", TenantID: "other"}
+	db.Create(&r)
+	assert.Equal(t, "acme", r.TenantID)
+
+	rs := []record{{Name: "a"}, {Name: "b"}}
+	db.Create(&rs)
+	assert.Equal(t, "acme", rs[1].TenantID)
+
+	stmt := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record{ID: 1}).Statement
+	assert.Contains(t, stmt.SQL.String(), `DO UPDATE SET`)
+	assert.Contains(t, stmt.SQL.String(), `WHERE "records"."tenant_id" = `)
+}
+
+// Тест для обновления без условий: GORM по-прежнему отклоняет его, а
+// tenant_id не меняется
+func TestPluginKeepsSafeguards(t *testing.T) {
+	db := dryRun(t).WithContext(WithTenant(context.Background(), "acme"))
+
+	err := db.Model(&record{}).Update("name", "c").Error
+	assert.ErrorIs(t, err, gorm.ErrMissingWhereClause)
+
+	err = db.Delete(&record{}).Error
+	assert.ErrorIs(t, err, gorm.ErrMissingWhereClause)
+
+	stmt := db.Save(&record{ID: 3, Name: "c"}).Statement
+	assert.NotContains(t, stmt.SQL.String(), `"tenant_id"=`)
+}
+
+// Тест для запросов без арендатора
+func TestPluginRequiresTenant(t *testing.T) {
+	db := dryRun(t)
+
+	err := db.Find(&[]record{}).Error
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	// Модели без арендатора не ограничиваются
+	assert.NoError(t, db.Find(&[]global{}).Error)
+
+	system := db.WithContext(System(context.Background()))
+	stmt := system.Find(&[]record{}).Statement
+	assert.NoError(t, stmt.Error)
+	assert.Equal(t, `SELECT * FROM "records"`, stmt.SQL.String())
+
+	// Арендатор важнее признака служебного запроса
+	scoped := Scope(system, "acme")
+	stmt = scoped.Find(&[]record{}).Statement
+	assert.Contains(t, stmt.SQL.String(), `"records"."tenant_id" = $1`)
+}
+
+// Тест для значения параметра сеанса app.tenant_id
+func TestSetting(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", setting(ctx))
+	assert.Equal(t, SystemSetting, setting(System(ctx)))
+	assert.Equal(t, "acme", setting(WithTenant(ctx, "acme")))
+	assert.Equal(t, "acme", setting(WithTenant(System(ctx), "acme")))
+}
+
+// Тест для подключения пула, задающего параметр сеанса для RLS
+func TestPluginRowLevelSecurity(t *testing.T) {
+	db, err := gorm.Open(
+		postgres.New(postgres.Config{DSN: "host=localhost"}),
+		&gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard},
+	)
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+
+	require.NoError(t, db.Use(Plugin{RowLevelSecurity: true}))
+	assert.IsType(t, &sessionPool{}, db.Statement.ConnPool)
+	assert.IsType(t, &sessionPool{}, db.Session(&gorm.Session{}).Statement.ConnPool)
+
+	// Пул по-прежнему доступен для настройки и метрик
+	wrapped, err := db.DB()
+	require.NoError(t, err)
+	assert.Same(t, sqlDB, wrapped)
+}
+
+// Тест для сырых запросов: от имени арендатора только через Raw и Exec
+func TestPluginRaw(t *testing.T) {
+	base := dryRun(t)
+	db := base.WithContext(WithTenant(context.Background(), "acme"))
+
+	var n int64
+	err := db.Raw("SELECT count(*) FROM records").Scan(&n).Error
+	assert.ErrorIs(t, err, ErrRawQuery)
+	assert.ErrorIs(t, db.Exec("DELETE FROM records").Error, ErrRawQuery)
+	assert.ErrorIs(t, db.Raw("SELECT * FROM records").Find(&[]record{}).Error, ErrRawQuery)
+
+	stmt := Raw(db, "SELECT * FROM records WHERE tenant_id = @tenant_id AND id > ?", 3).
+		Find(&[]record{}).Statement
+	assert.NoError(t, stmt.Error)
+	assert.Equal(t, `SELECT * FROM records WHERE tenant_id = $1 AND id > $2`, stmt.SQL.String())
+	assert.Equal(t, []any{"acme", 3}, stmt.Vars)
+
+	stmt = Exec(db, "DELETE FROM records WHERE tenant_id = @tenant_id").Statement
+	assert.NoError(t, stmt.Error)
+	assert.Equal(t, []any{"acme"}, stmt.Vars)
+
+	// Запрос без условия по арендатору не строится
+	assert.ErrorIs(t, Raw(db, "SELECT * FROM records").Find(&[]record{}).Error, ErrRawQuery)
+
+	// Арендатор в контексте сменился после построения запроса
+	q := Raw(db, "SELECT * FROM records WHERE tenant_id = @tenant_id")
+	assert.ErrorIs(t, q.WithContext(WithTenant(context.Background(), "other")).Find(&[]record{}).Error, ErrRawQuery)
+
+	// Без арендатора сырые запросы разрешены только служебному коду
+	assert.ErrorIs(t, base.Exec("DELETE FROM records").Error, ErrNoTenant)
+	assert.ErrorIs(t, Raw(base, "SELECT 1 WHERE @tenant_id = ''").Find(&[]record{}).Error, ErrNoTenant)
+	system := base.WithContext(System(context.Background()))
+	assert.NoError(t, system.Exec("DELETE FROM records").Error)
+	assert.NoError(t, base.Exec("SAVEPOINT sp1").Error)
+}
+
+// Тест для строки, прочитанной до Scan
+func TestBufferedRow(t *testing.T) {
+	ctx := context.Background()
+	rows, err := rowDB.QueryContext(ctx, "", bufferedRow{values: []any{int64(3), []byte("acme")}, found: true})
+	require.NoError(t, err)
+	r := read(rows)
+	require.NoError(t, r.err)
+
+	var n int
+	var name string
+	require.NoError(t, rowOf(ctx, r).Scan(&n, &name))
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "acme", name)
+	assert.Error(t, rowOf(ctx, r).Scan(&n))
+
+	// Строка остается доступной после отмены контекста
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.NoError(t, rowOf(canceled, r).Scan(&n, &name))
+
+	rows, err = rowDB.QueryContext(ctx, "", bufferedRow{})
+	require.NoError(t, err)
+	assert.ErrorIs(t, rowOf(ctx, read(rows)).Scan(&n), sql.ErrNoRows)
+
+	failed := errors.New("failed")
+	assert.ErrorIs(t, rowOf(ctx, bufferedRow{err: failed}).Scan(&n), failed)
+}
@@ -0,0 +1,83 @@
+package tenancy
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Param — именованный параметр сырого запроса, который Raw и Exec
+// заменяют арендатором из контекста
+const Param = "tenant_id"
+
+// ErrRawQuery возвращается для сырого запроса от имени арендатора,
+// построенного не через Raw или Exec, и для запроса без параметра Param
+var ErrRawQuery = errors.New("raw query of a tenant must be built with tenancy.Raw or tenancy.Exec and use @" + Param)
+
+// rawKey — настройка запроса GORM с арендатором, для которого Raw или Exec
+// построили запрос
+const rawKey = "tenancy:raw"
+
+// Raw строит сырой запрос арендатора из контекста tx, как gorm.DB.Raw.
+// Условие по арендатору для каждой таблицы запроса записывается явно через
+// @tenant_id, значение которого Raw подставляет сам:
+//
+//	tenancy.Raw(tx, "SELECT count(*) FROM subscriptions WHERE tenant_id = @tenant_id AND user_id = ?", userID)
+//
+// Без арендатора в контексте запрос завершается ErrNoTenant; служебные
+// запросы без арендатора выполняются обычным tx.Raw.
+func Raw(tx *gorm.DB, query string, values ...any) *gorm.DB {
+	tx, values = bind(tx, query, values)
+	return tx.Raw(query, values...)
+}
+
+// Exec выполняет сырую команду арендатора из контекста tx, как
+// gorm.DB.Exec; условие по арендатору записывается так же, как для Raw
+func Exec(tx *gorm.DB, query string, values ...any) *gorm.DB {
+	tx, values = bind(tx, query, values)
+	return tx.Exec(query, values...)
+}
+
+// bind добавляет к параметрам запроса арендатора из контекста tx. Параметр
+// добавляется последним, чтобы не сдвинуть позиционные параметры.
+func bind(tx *gorm.DB, query string, values []any) (*gorm.DB, []any) {
+	id, ok := FromContext(tx.Statement.Context)
+	switch {
+	case !ok:
+		tx = tx.Session(&gorm.Session{})
+		tx.AddError(ErrNoTenant)
+	case !strings.Contains(query, "@"+Param):
+		tx = tx.Session(&gorm.Session{})
+		tx.AddError(ErrRawQuery)
+	default:
+		tx = tx.Set(rawKey, id)
+	}
+	return tx, append(values, sql.Named(Param, id))
+}
+
+// checkRaw пропускает сырой запрос, только если он построен через Raw или
+// Exec для арендатора из контекста или выполняется в служебном контексте
+// без арендатора. Точки сохранения вложенных транзакций GORM выполняет
+// сырыми командами, они не читают данных и пропускаются всегда.
+func checkRaw(db *gorm.DB) {
+	stmt := db.Statement
+	id, ok := FromContext(stmt.Context)
+	if bound, built := db.Get(rawKey); built {
+		if !ok || bound != id {
+			db.AddError(ErrRawQuery)
+		}
+		return
+	}
+	query := stmt.SQL.String()
+	if strings.HasPrefix(query, "SAVEPOINT ") || strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT ") {
+		return
+	}
+	switch {
+	case ok:
+		db.AddError(ErrRawQuery)
+	case !IsSystem(stmt.Context):
+		db.AddError(ErrNoTenant)
+	}
+}
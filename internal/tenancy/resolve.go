@@ -0,0 +1,130 @@
+package tenancy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnresolved — в запросе нет ни токена, ни адреса с арендатором
+	ErrUnresolved = errors.New("tenant is not specified")
+	// ErrInvalidToken — токен не прошел проверку или не содержит арендатора
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+// Resolver определяет арендатора запроса: по claim JWT из заголовка
+// Authorization или по поддомену в Host
+type Resolver struct {
+	// HostSuffix — общая часть адресов арендаторов: для ".example.com"
+	// запрос к acme.example.com относится к арендатору acme. Пустой
+	// отключает определение по адресу.
+	HostSuffix string
+	// Secret — ключ HS256 для проверки токенов. Пустой отключает
+	// определение по токену.
+	Secret []byte
+	// Claim — claim токена с идентификатором арендатора
+	Claim string
+	now   func() time.Time
+}
+
+// Principal — от чьего имени выполняется запрос: арендатор и субъект
+// токена (claim sub). Если арендатор определен по адресу или в токене нет
+// sub, субъект пустой.
+type Principal struct {
+	Tenant  string
+	Subject string
+}
+
+// Resolve возвращает арендатора запроса
+func (r Resolver) Resolve(req *http.Request) (string, error) {
+	p, err := r.Authenticate(req)
+	return p.Tenant, err
+}
+
+// Authenticate возвращает арендатора и субъекта запроса. Токен важнее
+// адреса; если токен передан, но не прошел проверку, адрес не используется.
+func (r Resolver) Authenticate(req *http.Request) (Principal, error) {
+	if len(r.Secret) > 0 {
+		if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+			return r.fromToken(strings.TrimSpace(token))
+		}
+	}
+	if r.HostSuffix != "" {
+		if id, ok := r.fromHost(req.Host); ok {
+			return Principal{Tenant: id}, nil
+		}
+	}
+	return Principal{}, ErrUnresolved
+}
+
+// fromHost выделяет арендатора из поддомена. Вложенные поддомены не
+// допускаются.
+func (r Resolver) fromHost(hostport string) (string, bool) {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	id, ok := strings.CutSuffix(strings.ToLower(host), strings.ToLower(r.HostSuffix))
+	if !ok || !Valid(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// fromToken проверяет подпись HS256 и срок действия токена и возвращает
+// арендатора из claim и субъекта
+func (r Resolver) fromToken(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Principal{}, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	mac := hmac.New(sha256.New, r.Secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+	if exp, ok := claims["exp"].(float64); ok && now().Unix() >= int64(exp) {
+		return Principal{}, ErrInvalidToken
+	}
+	id, _ := claims[r.Claim].(string)
+	if !Valid(id) {
+		return Principal{}, ErrInvalidToken
+	}
+	subject, _ := claims["sub"].(string)
+	return Principal{Tenant: id, Subject: subject}, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,107 @@
+package tenancy
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// bufferedRow — прочитанная строка результата: значения столбцов, признак
+// того, что строка найдена, и ошибка запроса
+type bufferedRow struct {
+	values []any
+	found  bool
+	err    error
+}
+
+// read читает первую строку rows и закрывает их
+func read(rows *sql.Rows) bufferedRow {
+	defer rows.Close()
+	if !rows.Next() {
+		return bufferedRow{err: rows.Err()}
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return bufferedRow{err: err}
+	}
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return bufferedRow{err: err}
+	}
+	return bufferedRow{values: values, found: true, err: rows.Close()}
+}
+
+// rowDB выдает *sql.Row с прочитанной строкой: других способов получить
+// *sql.Row пакет database/sql не дает
+var rowDB = sql.OpenDB(rowConnector{})
+
+// rowOf возвращает *sql.Row, Scan которого работает как для строки r,
+// полученной от базы
+func rowOf(ctx context.Context, r bufferedRow) *sql.Row {
+	// Строка уже прочитана, отмена контекста не должна ее терять
+	return rowDB.QueryRowContext(context.WithoutCancel(ctx), "", r)
+}
+
+// errUnsupported возвращается rowDB на все, кроме выдачи строки
+var errUnsupported = errors.New("tenancy: buffered row supports queries only")
+
+type rowConnector struct{}
+
+func (rowConnector) Connect(context.Context) (driver.Conn, error) { return rowConn{}, nil }
+
+func (rowConnector) Driver() driver.Driver { return rowDriver{} }
+
+type rowDriver struct{}
+
+func (rowDriver) Open(string) (driver.Conn, error) { return rowConn{}, nil }
+
+// rowConn возвращает строку, переданную единственным аргументом запроса
+type rowConn struct{}
+
+func (rowConn) Prepare(string) (driver.Stmt, error) { return nil, errUnsupported }
+
+func (rowConn) Close() error { return nil }
+
+func (rowConn) Begin() (driver.Tx, error) { return nil, errUnsupported }
+
+// CheckNamedValue пропускает bufferedRow в аргументы запроса без
+// преобразования
+func (rowConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (rowConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	r, ok := args[0].Value.(bufferedRow)
+	if !ok {
+		return nil, errUnsupported
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &rowRows{row: r}, nil
+}
+
+// rowRows — результат rowConn из одной строки или без строк
+type rowRows struct {
+	row  bufferedRow
+	done bool
+}
+
+func (r *rowRows) Columns() []string { return make([]string, len(r.row.values)) }
+
+func (r *rowRows) Close() error { return nil }
+
+func (r *rowRows) Next(dest []driver.Value) error {
+	if r.done || !r.row.found {
+		return io.EOF
+	}
+	r.done = true
+	for i, v := range r.row.values {
+		dest[i] = v
+	}
+	return nil
+}
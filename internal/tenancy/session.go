@@ -0,0 +1,170 @@
+package tenancy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Setting — параметр сеанса Postgres, по которому политики RLS отбирают
+// строки арендатора
+const Setting = "app.tenant_id"
+
+// SystemSetting — значение Setting для служебных запросов: политики RLS
+// пропускают строки всех арендаторов
+const SystemSetting = "*"
+
+// errPrepare возвращается при попытке подготовить выражение: подготовленное
+// выражение выполнялось бы на любом соединении пула без параметра сеанса
+var errPrepare = errors.New("prepared statements are not supported with row level security")
+
+// setLocal задает Setting до конца текущей транзакции
+const setLocal = "SELECT set_config('" + Setting + "', $1, true)"
+
+// setting возвращает значение Setting для запросов с контекстом ctx:
+// арендатора, SystemSetting для служебных запросов или пустую строку, при
+// которой политики не пропускают ни одной строки
+func setting(ctx context.Context) string {
+	if id, ok := FromContext(ctx); ok {
+		return id
+	}
+	if IsSystem(ctx) {
+		return SystemSetting
+	}
+	return ""
+}
+
+// sessionPool выполняет запросы GORM так, чтобы параметр сеанса Setting
+// совпадал с арендатором из контекста запроса. Запрос вне транзакции
+// выполняется в отдельной короткой транзакции, в начале которой параметр
+// задается через SET LOCAL, поэтому соединение возвращается в пул без
+// параметра; в транзакции GORM параметр задается при ее начале и при смене
+// арендатора в контексте (см. Scope).
+type sessionPool struct {
+	db *sql.DB
+}
+
+// begin начинает транзакцию и задает в ней параметр сеанса
+func (p *sessionPool) begin(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, string, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	value := setting(ctx)
+	if _, err := tx.ExecContext(ctx, setLocal, value); err != nil {
+		tx.Rollback()
+		return nil, "", err
+	}
+	return tx, value, nil
+}
+
+func (p *sessionPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errPrepare
+}
+
+func (p *sessionPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	tx, _, err := p.begin(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+func (p *sessionPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	tx, _, err := p.begin(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Commit ждет закрытия rows и только затем завершает транзакцию и
+	// возвращает соединение в пул
+	go tx.Commit()
+	return rows, nil
+}
+
+// QueryRowContext читает строку сразу, а не при Scan: вызывающий может не
+// прочитать *sql.Row, и транзакция с соединением остались бы занятыми
+func (p *sessionPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	rows, err := p.QueryContext(ctx, query, args...)
+	if err != nil {
+		return rowOf(ctx, bufferedRow{err: err})
+	}
+	return rowOf(ctx, read(rows))
+}
+
+func (p *sessionPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, value, err := p.begin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionTx{Tx: tx, setting: value}, nil
+}
+
+// GetDBConn позволяет получить *sql.DB через gorm.DB.DB()
+func (p *sessionPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+// unknown — значение sessionTx.setting, при котором параметр сеанса будет
+// задан перед следующим запросом
+const unknown = "\x00"
+
+// sessionTx — транзакция sessionPool, setting — текущее значение
+// параметра сеанса в ней
+type sessionTx struct {
+	*sql.Tx
+	setting string
+}
+
+// sync задает параметр сеанса, если арендатор в контексте изменился
+func (t *sessionTx) sync(ctx context.Context) error {
+	value := setting(ctx)
+	if value == t.setting {
+		return nil
+	}
+	if _, err := t.Tx.ExecContext(ctx, setLocal, value); err != nil {
+		return err
+	}
+	t.setting = value
+	return nil
+}
+
+func (t *sessionTx) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errPrepare
+}
+
+func (t *sessionTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := t.sync(ctx); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT ") {
+		// Откат к точке сохранения отменяет и заданный после нее параметр
+		defer func() { t.setting = unknown }()
+	}
+	return t.Tx.ExecContext(ctx, query, args...)
+}
+
+func (t *sessionTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := t.sync(ctx); err != nil {
+		return nil, err
+	}
+	return t.Tx.QueryContext(ctx, query, args...)
+}
+
+func (t *sessionTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	// После ошибки sync транзакция прервана и запрос вернет ошибку в *sql.Row
+	_ = t.sync(ctx)
+	return t.Tx.QueryRowContext(ctx, query, args...)
+}
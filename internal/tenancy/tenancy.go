@@ -0,0 +1,60 @@
+package tenancy
+
+import (
+	"context"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// Default — арендатор, которому принадлежат все данные, если разделение
+// на арендаторов выключено, и данные, созданные до его появления
+const Default = "default"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type (
+	tenantKey struct{}
+	systemKey struct{}
+)
+
+// Valid проверяет идентификатор арендатора: строчные латинские буквы,
+// цифры и дефисы, как в метке DNS-имени
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// WithTenant возвращает контекст запросов арендатора id. Запросы GORM с
+// этим контекстом видят и изменяют только строки арендатора.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext возвращает арендатора из контекста
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// System возвращает контекст служебных запросов без ограничения по
+// арендатору: для миграций и фоновых задач, обрабатывающих данные всех
+// арендаторов. Арендатор, заданный через WithTenant, важнее.
+func System(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey{}, true)
+}
+
+// IsSystem сообщает, разрешены ли в контексте запросы без арендатора
+func IsSystem(ctx context.Context) bool {
+	system, _ := ctx.Value(systemKey{}).(bool)
+	return system
+}
+
+// Scope ограничивает запросы tx арендатором id, сохраняя транзакцию.
+// Используется служебным кодом, когда он действует от имени арендатора
+// обрабатываемой записи. Пустой id оставляет tx без изменений.
+func Scope(tx *gorm.DB, id string) *gorm.DB {
+	if id == "" {
+		return tx
+	}
+	return tx.WithContext(WithTenant(tx.Statement.Context, id))
+}
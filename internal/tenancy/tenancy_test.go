@@ -0,0 +1,84 @@
+package tenancy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// token подписывает claims ключом secret по HS256
+func token(secret string, claims map[string]any) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, _ := json.Marshal(claims)
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Тест для проверки идентификаторов арендаторов
+func TestValid(t *testing.T) {
+	for _, id := range []string{"acme", "a", "acme-2", "42"} {
+		assert.True(t, Valid(id), id)
+	}
+	for _, id := range []string{"", "Acme", "-acme", "acme-", "ac.me", "ac_me"} {
+		assert.False(t, Valid(id), id)
+	}
+}
+
+// Тест для определения арендатора по адресу и токену
+func TestResolve(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	r := Resolver{
+		HostSuffix: ".subs.example.com",
+		Secret:     []byte("secret"),
+		Claim:      "tenant_id",
+		now:        func() time.Time { return now },
+	}
+	resolve := func(host, auth string) (string, error) {
+		req, _ := http.NewRequest("GET", "/api/v1/subscriptions", nil)
+		req.Host = host
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return r.Resolve(req)
+	}
+
+	id, err := resolve("acme.subs.example.com:8080", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", id)
+
+	_, err = resolve("a.b.subs.example.com", "")
+	assert.ErrorIs(t, err, ErrUnresolved)
+	_, err = resolve("localhost", "")
+	assert.ErrorIs(t, err, ErrUnresolved)
+
+	// Токен важнее адреса
+	valid := token("secret", map[string]any{"tenant_id": "globex", "exp": now.Add(time.Hour).Unix()})
+	id, err = resolve("acme.subs.example.com", "Bearer "+valid)
+	assert.NoError(t, err)
+	assert.Equal(t, "globex", id)
+
+	// Субъект токена определяет пользователя
+	req, _ := http.NewRequest("GET", "/api/v1/subscriptions", nil)
+	req.Header.Set("Authorization", "Bearer "+token("secret", map[string]any{"tenant_id": "globex", "sub": "alice"}))
+	p, err := r.Authenticate(req)
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{Tenant: "globex", Subject: "alice"}, p)
+
+	for name, auth := range map[string]string{
+		"wrong key": token("other", map[string]any{"tenant_id": "globex"}),
+		"expired":   token("secret", map[string]any{"tenant_id": "globex", "exp": now.Unix()}),
+		"no claim":  token("secret", map[string]any{"sub": "globex"}),
+		"malformed": "abc",
+	} {
+		_, err = resolve("acme.subs.example.com", "Bearer "+auth)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+}
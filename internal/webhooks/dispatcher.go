@@ -0,0 +1,218 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/nemopss/subscription-service/internal/metrics"
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/notify"
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// Статусы доставки события
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// StatusDead — доставка прекращена после всех попыток или потому, что
+	// адрес ответил 410 Gone. Ее можно повторить через redeliver.
+	StatusDead = "dead"
+)
+
+// Options — параметры доставки событий
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	ClaimTimeout time.Duration
+	Client       *http.Client
+}
+
+// Dispatcher отправляет события из таблицы webhook_deliveries. Как и
+// очередь уведомлений, доставки резервируются через SKIP LOCKED, поэтому
+// несколько реплик не отправляют одно событие одновременно.
+type Dispatcher struct {
+	db   *gorm.DB
+	opts Options
+	now  func() time.Time
+}
+
+func NewDispatcher(db *gorm.DB, opts Options) *Dispatcher {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.ClaimTimeout <= 0 {
+		opts.ClaimTimeout = 5 * time.Minute
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &Dispatcher{db: db, opts: opts, now: time.Now}
+}
+
+func (d *Dispatcher) Name() string {
+	return "webhook-dispatcher"
+}
+
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		for {
+			n, err := d.DeliverDue(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Log.WithError(err).Error("Webhook delivery pass failed")
+				}
+				break
+			}
+			if n < d.opts.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// DeliverDue отправляет пачку доставок, срок попытки которых наступил
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	batch, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := range batch {
+		d.deliver(ctx, &batch[i])
+	}
+	return len(batch), nil
+}
+
+func (d *Dispatcher) claim(ctx context.Context) ([]models.WebhookDelivery, error) {
+	var batch []models.WebhookDelivery
+	now := d.now().UTC()
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+			Order("next_attempt_at").
+			Limit(d.opts.BatchSize).
+			Find(&batch).Error
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(batch))
+		for i, del := range batch {
+			ids[i] = del.ID
+		}
+		err = tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(d.opts.ClaimTimeout)).Error
+		if err != nil {
+			return err
+		}
+
+		// Адреса и события подгружаются после блокировки отдельными
+		// запросами: FOR UPDATE не должен распространяться на них
+		return tx.Preload("Endpoint").Preload("Event").
+			Where("id IN ?", ids).
+			Order("next_attempt_at").
+			Find(&batch).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	return batch, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, del *models.WebhookDelivery) {
+	log := logger.Log.WithFields(logrus.Fields{
+		"delivery_id": del.ID,
+		"endpoint_id": del.EndpointID,
+		"event_id":    del.EventID,
+	})
+
+	status, err := d.post(ctx, del.Endpoint, del.Event)
+	del.Attempts++
+	del.LastStatusCode = status
+	now := d.now().UTC()
+	result := StatusDelivered
+
+	switch {
+	case err == nil:
+		del.Status = StatusDelivered
+		del.DeliveredAt = &now
+		del.LastError = ""
+		log.Info("Webhook delivered")
+	case status == http.StatusGone || del.Attempts >= d.opts.MaxAttempts:
+		del.Status = StatusDead
+		del.LastError = err.Error()
+		result = StatusDead
+		log.WithError(err).WithField("attempts", del.Attempts).
+			Error("Webhook delivery moved to dead letter")
+	default:
+		del.NextAttemptAt = now.Add(notify.Backoff(del.Attempts, d.opts.BackoffBase, d.opts.BackoffMax))
+		del.LastError = err.Error()
+		result = "retry"
+		log.WithError(err).WithFields(logrus.Fields{
+			"attempts":        del.Attempts,
+			"next_attempt_at": del.NextAttemptAt,
+		}).Warn("Webhook delivery failed, will retry")
+	}
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+
+	err = d.db.WithContext(context.WithoutCancel(ctx)).
+		Model(del).
+		Select("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at").
+		Updates(del).Error
+	if err != nil {
+		log.WithError(err).Error("Failed to save webhook delivery")
+	}
+}
+
+// post отправляет событие с подписью и возвращает HTTP-статус ответа
+func (d *Dispatcher) post(ctx context.Context, endpoint *models.WebhookEndpoint, event *models.WebhookEvent) (int, error) {
+	if endpoint == nil || event == nil {
+		return 0, fmt.Errorf("webhook endpoint or event no longer exists")
+	}
+
+	body := []byte(event.Payload)
+	ts := d.now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "subscription-service-webhooks/1")
+	req.Header.Set(HeaderID, event.ID)
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, ts, body))
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
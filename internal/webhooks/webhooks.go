@@ -0,0 +1,185 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+)
+
+// Типы событий жизненного цикла подписки и бюджетов
+const (
+	EventSubscriptionCreated = "subscription.created"
+	EventSubscriptionUpdated = "subscription.updated"
+	EventSubscriptionDeleted = "subscription.deleted"
+	EventSubscriptionExpired = "subscription.expired"
+	EventSubscriptionPaused  = "subscription.paused"
+	EventSubscriptionResumed = "subscription.resumed"
+
+	EventSubscriptionCancelled   = "subscription.cancelled"
+	EventSubscriptionUncancelled = "subscription.uncancelled"
+
+	EventBudgetExceeded = "budget.exceeded"
+)
+
+// EventTypes — все типы событий, на которые можно подписаться
+var EventTypes = []string{
+	EventSubscriptionCreated,
+	EventSubscriptionUpdated,
+	EventSubscriptionDeleted,
+	EventSubscriptionExpired,
+	EventSubscriptionPaused,
+	EventSubscriptionResumed,
+	EventSubscriptionCancelled,
+	EventSubscriptionUncancelled,
+	EventBudgetExceeded,
+}
+
+// Заголовки запроса с событием
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderEvent     = "Webhook-Event"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+)
+
+// signatureVersion — префикс подписи; изменится, если изменится схема
+const signatureVersion = "v1="
+
+// Payload — тело запроса с событием
+type Payload struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	TenantID  string    `json:"tenant_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Publish записывает событие и создает доставки на все активные адреса,
+// подписанные на его тип. Вызывается в транзакции изменения подписки,
+// чтобы событие появлялось только вместе с изменением. Арендатор события —
+// арендатор из контекста tx.
+func Publish(tx *gorm.DB, eventType string, data any) error {
+	var endpoints []models.WebhookEndpoint
+	if err := tx.Where("active").Find(&endpoints).Error; err != nil {
+		return err
+	}
+	endpoints = slices.DeleteFunc(endpoints, func(e models.WebhookEndpoint) bool {
+		return !Subscribed(e, eventType)
+	})
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	event := models.WebhookEvent{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+	}
+	tenantID, _ := tenancy.FromContext(tx.Statement.Context)
+	payload, err := json.Marshal(Payload{
+		ID:        event.ID,
+		Type:      event.Type,
+		TenantID:  tenantID,
+		CreatedAt: event.CreatedAt,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+	event.Payload = string(payload)
+	if err := tx.Create(&event).Error; err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, len(endpoints))
+	for i, e := range endpoints {
+		deliveries[i] = models.WebhookDelivery{
+			EndpointID:    e.ID,
+			EventID:       event.ID,
+			Status:        StatusPending,
+			NextAttemptAt: event.CreatedAt,
+		}
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// Subscribed сообщает, получает ли адрес события этого типа
+func Subscribed(e models.WebhookEndpoint, eventType string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, eventType)
+}
+
+// ValidateEndpoint проверяет URL и типы событий нового адреса
+func ValidateEndpoint(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	for _, e := range events {
+		if !slices.Contains(EventTypes, e) {
+			return fmt.Errorf(
+				"unknown event %q, expected one of %s",
+				e,
+				strings.Join(EventTypes, ", "),
+			)
+		}
+	}
+	return nil
+}
+
+// NewSecret создает секрет для подписи событий
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign возвращает значение заголовка Webhook-Signature:
+// "v1=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Ошибки проверки подписи
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside tolerance")
+)
+
+// Verify проверяет подпись события на стороне получателя. Запросы с
+// меткой времени, отличающейся от now больше чем на tolerance,
+// отклоняются, чтобы перехваченный запрос нельзя было повторить.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	ts := time.Unix(unix, 0)
+	if d := now.Sub(ts); d > tolerance || d < -tolerance {
+		return ErrStaleTimestamp
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
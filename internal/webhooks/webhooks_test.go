@@ -0,0 +1,84 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nemopss/subscription-service/internal/models"
+	"github.com/nemopss/subscription-service/internal/notify/notifytest"
+)
+
+// Тест для подписи и ее проверки на стороне получателя
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	body := []byte(`{"id":"1"}`)
+	sig := Sign("whsec_test", now, body)
+	ts := "1760000000"
+
+	assert.NoError(t, Verify("whsec_test", ts, sig, body, 5*time.Minute, now.Add(time.Minute)))
+	assert.ErrorIs(t, Verify("whsec_other", ts, sig, body, 5*time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("whsec_test", ts, sig, []byte(`{"id":"2"}`), 5*time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("whsec_test", ts, sig, body, 5*time.Minute, now.Add(10*time.Minute)), ErrStaleTimestamp)
+	assert.ErrorIs(t, Verify("whsec_test", "soon", sig, body, 5*time.Minute, now), ErrInvalidSignature)
+}
+
+// Тест для фильтрации событий адреса
+func TestSubscribed(t *testing.T) {
+	all := models.WebhookEndpoint{}
+	some := models.WebhookEndpoint{Events: []string{EventSubscriptionDeleted}}
+
+	assert.True(t, Subscribed(all, EventSubscriptionCreated))
+	assert.True(t, Subscribed(some, EventSubscriptionDeleted))
+	assert.False(t, Subscribed(some, EventSubscriptionCreated))
+}
+
+// Тест для проверки нового адреса
+func TestValidateEndpoint(t *testing.T) {
+	assert.NoError(t, ValidateEndpoint("https://example.com/hook", []string{EventSubscriptionExpired}))
+	assert.Error(t, ValidateEndpoint("example.com/hook", nil))
+	assert.Error(t, ValidateEndpoint("https://example.com/hook", []string{"subscription.renamed"}))
+}
+
+// Тест для заголовков и подписи отправляемого события
+func TestDispatcherPost(t *testing.T) {
+	sink := notifytest.NewHTTPSink()
+	defer sink.Close()
+
+	now := time.Unix(1760000000, 0)
+	d := NewDispatcher(nil, Options{})
+	d.now = func() time.Time { return now }
+	endpoint := &models.WebhookEndpoint{URL: sink.URL + "/events", Secret: "whsec_test"}
+	event := &models.WebhookEvent{
+		ID:      "5d4e0c52-3f2b-4c55-9a4a-1f0f6c2b3a10",
+		Type:    EventSubscriptionCreated,
+		Payload: `{"type":"subscription.created"}`,
+	}
+
+	status, err := d.post(context.Background(), endpoint, event)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	reqs := sink.Requests()
+	require.Len(t, reqs, 1)
+	h := reqs[0].Header
+	assert.Equal(t, event.ID, h.Get(HeaderID))
+	assert.Equal(t, EventSubscriptionCreated, h.Get(HeaderEvent))
+	assert.NoError(t, Verify(
+		"whsec_test",
+		h.Get(HeaderTimestamp),
+		h.Get(HeaderSignature),
+		reqs[0].Body,
+		time.Minute,
+		now,
+	))
+
+	sink.SetResponse(http.StatusGone, "")
+	status, err = d.post(context.Background(), endpoint, event)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusGone, status)
+}
@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nemopss/subscription-service/pkg/logger"
+)
+
+// Worker — фоновая задача, работающая до отмены контекста
+type Worker interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// Manager запускает фоновые задачи и дожидается их завершения при остановке
+type Manager struct {
+	workers []Worker
+
+	mu      sync.Mutex
+	running map[string]bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewManager(workers ...Worker) *Manager {
+	return &Manager{
+		workers: workers,
+		running: make(map[string]bool),
+	}
+}
+
+// Add регистрирует задачу. Вызывается до Start.
+func (m *Manager) Add(w Worker) {
+	m.workers = append(m.workers, w)
+}
+
+// Start запускает все зарегистрированные задачи в отдельных горутинах
+func (m *Manager) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+
+	for _, w := range m.workers {
+		m.setRunning(w.Name(), true)
+		m.wg.Add(1)
+		go func(w Worker) {
+			defer m.wg.Done()
+			defer m.setRunning(w.Name(), false)
+
+			logger.Log.WithField("worker", w.Name()).Info("Worker started")
+			if err := w.Run(ctx); err != nil && ctx.Err() == nil {
+				logger.Log.WithError(err).
+					WithField("worker", w.Name()).
+					Error("Worker stopped with error")
+				return
+			}
+			logger.Log.WithField("worker", w.Name()).Info("Worker stopped")
+		}(w)
+	}
+}
+
+// Stop отменяет контекст задач и ждет их завершения, но не дольше,
+// чем позволяет ctx
+func (m *Manager) Stop(ctx context.Context) error {
+	if m.cancel != nil {
+		m.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Running сообщает, работает ли задача с указанным именем
+func (m *Manager) Running(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.running[name]
+}
+
+func (m *Manager) setRunning(name string, running bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running[name] = running
+}
+
+// Check возвращает ошибку, если какая-либо из запущенных задач остановилась
+func (m *Manager) Check(ctx context.Context) error {
+	var stopped []string
+	for _, w := range m.workers {
+		if !m.Running(w.Name()) {
+			stopped = append(stopped, w.Name())
+		}
+	}
+	if len(stopped) > 0 {
+		return fmt.Errorf("workers not running: %s", strings.Join(stopped, ", "))
+	}
+	return nil
+}
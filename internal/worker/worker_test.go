@@ -0,0 +1,54 @@
+package worker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nemopss/subscription-service/internal/worker"
+)
+
+type blockingWorker struct {
+	started chan struct{}
+	delay   time.Duration
+}
+
+func (w *blockingWorker) Name() string { return "blocking" }
+
+func (w *blockingWorker) Run(ctx context.Context) error {
+	close(w.started)
+	<-ctx.Done()
+	time.Sleep(w.delay)
+	return ctx.Err()
+}
+
+// Тест для остановки задач с ожиданием завершения
+func TestManagerStopWaitsForWorkers(t *testing.T) {
+	w := &blockingWorker{started: make(chan struct{})}
+	m := worker.NewManager(w)
+	m.Start(context.Background())
+	<-w.started
+	assert.True(t, m.Running("blocking"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, m.Stop(ctx))
+	assert.False(t, m.Running("blocking"))
+}
+
+// Тест для остановки задач по истечении срока
+func TestManagerStopDeadline(t *testing.T) {
+	w := &blockingWorker{started: make(chan struct{}), delay: time.Second}
+	m := worker.NewManager(w)
+	m.Start(context.Background())
+	<-w.started
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		10*time.Millisecond,
+	)
+	defer cancel()
+	assert.ErrorIs(t, m.Stop(ctx), context.DeadlineExceeded)
+}
@@ -1,34 +1,236 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
-	"github.com/swaggo/files"
-	"github.com/swaggo/gin-swagger"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/nemopss/subscription-service/docs"
+	"github.com/nemopss/subscription-service/internal/broker"
 	"github.com/nemopss/subscription-service/internal/config"
 	"github.com/nemopss/subscription-service/internal/db"
-	"github.com/nemopss/subscription-service/internal/handlers"
+	"github.com/nemopss/subscription-service/internal/events"
+	"github.com/nemopss/subscription-service/internal/expiry"
+	"github.com/nemopss/subscription-service/internal/health"
+	"github.com/nemopss/subscription-service/internal/notify"
+	"github.com/nemopss/subscription-service/internal/outbox"
+	"github.com/nemopss/subscription-service/internal/ratelimit"
+	"github.com/nemopss/subscription-service/internal/reminders"
+	"github.com/nemopss/subscription-service/internal/router"
+	"github.com/nemopss/subscription-service/internal/tenancy"
+	"github.com/nemopss/subscription-service/internal/tracing"
+	"github.com/nemopss/subscription-service/internal/webhooks"
+	"github.com/nemopss/subscription-service/internal/worker"
 	"github.com/nemopss/subscription-service/pkg/logger"
 )
 
+// @title     Subscription Service API
+// @version   1.0
+// @BasePath  /api/v1
 func main() {
 	logger.InitLogger()
-	config.LoadConfig("./.env")
-	r := gin.Default()
-	err := db.InitDB()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := logger.Configure(cfg.Log.Level, cfg.Log.Format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	serverCfg := cfg.Server
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	err = db.InitDB(cfg.Database)
 	if err != nil {
 		panic("db failed to init")
 	}
 
-	r.POST("/subscriptions", handlers.CreateSubscription)
-	r.GET("/subscriptions/:id", handlers.GetSubscription)
-	r.PUT("/subscriptions/:id", handlers.UpdateSubscription)
-	r.DELETE("/subscriptions/:id", handlers.DeleteSubscription)
-	r.GET("/subscriptions", handlers.ListSubscriptions)
-	r.GET("/subscriptions/total", handlers.GetTotalCostByPeriod)
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		syscall.SIGINT,
+		syscall.SIGTERM,
+	)
+	defer stop()
 
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	workers := worker.NewManager()
+	deps := router.Deps{}
+	if cfg.RateLimit.Enabled {
+		store := ratelimit.NewMemoryStore()
+		workers.Add(store)
+		deps.RateLimitStore = store
+	}
+	var sender reminders.Sender = reminders.LogSender{}
+	if cfg.Notifications.Enabled {
+		dispatcher, templates, err := newNotifications(cfg.Notifications)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		workers.Add(dispatcher)
+		sender = &reminders.NotifySender{
+			Dispatcher: dispatcher,
+			Templates:  templates,
+			Fallback:   reminders.LogSender{},
+		}
+	}
+	if cfg.Reminders.Enabled {
+		workers.Add(reminders.NewScheduler(
+			db.DB,
+			sender,
+			cfg.Reminders.Interval,
+			cfg.Reminders.DaysBefore,
+		))
+	}
+	if cfg.Webhooks.Enabled {
+		workers.Add(webhooks.NewDispatcher(db.DB, webhooks.Options{
+			PollInterval: cfg.Webhooks.PollInterval,
+			MaxAttempts:  cfg.Webhooks.MaxAttempts,
+			BackoffBase:  cfg.Webhooks.BackoffBase,
+			BackoffMax:   cfg.Webhooks.BackoffMax,
+			Client:       &http.Client{Timeout: cfg.Webhooks.Timeout},
+		}))
+	}
+	if cfg.Outbox.Enabled {
+		publisher, err := newPublisher(cfg.Outbox)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		events.EnableOutbox(true)
+		workers.Add(outbox.NewRelay(db.DB, publisher, outbox.Options{
+			PollInterval: cfg.Outbox.PollInterval,
+			BatchSize:    cfg.Outbox.BatchSize,
+			Retention:    cfg.Outbox.Retention,
+		}))
+	}
+	if cfg.Expiry.Enabled {
+		workers.Add(expiry.NewJob(db.DB, cfg.Expiry.Interval))
+	}
+	// Фоновые задачи обрабатывают данные всех арендаторов. Их контекст не
+	// зависит от сигнала: задачи останавливает workers.Stop уже после того,
+	// как HTTP-сервер дождется текущих запросов.
+	workers.Start(tenancy.System(context.Background()))
+
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.Add("database", db.Ping)
+	checker.Add("migrations", db.MigrationsApplied)
+	checker.Add("workers", workers.Check)
+	deps.Health = checker
 
-	r.Run()
+	srv := &http.Server{
+		Addr:              serverCfg.Addr,
+		Handler:           router.New(cfg, deps),
+		ReadTimeout:       serverCfg.ReadTimeout,
+		ReadHeaderTimeout: serverCfg.ReadHeaderTimeout,
+		WriteTimeout:      serverCfg.WriteTimeout,
+		IdleTimeout:       serverCfg.IdleTimeout,
+		MaxHeaderBytes:    serverCfg.MaxHeaderBytes,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Log.WithField("addr", srv.Addr).Info("HTTP server started")
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	failed := false
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.WithError(err).Error("HTTP server failed")
+			failed = true
+		}
+	case <-ctx.Done():
+		logger.Log.Info("Shutdown signal received")
+	}
+	stop()
+
+	// Завершаем работу в порядке: HTTP-сервер (дожидаемся текущих
+	// запросов), фоновые задачи, пул соединений с базой данных, экспорт
+	// оставшихся спанов
+	shutdownCtx, cancel := context.WithTimeout(
+		context.Background(),
+		serverCfg.ShutdownTimeout,
+	)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Log.WithError(err).Error("HTTP server shutdown failed")
+	}
+	if err := workers.Stop(shutdownCtx); err != nil {
+		logger.Log.WithError(err).Error("Background workers did not stop in time")
+	}
+	if err := db.Close(); err != nil {
+		logger.Log.WithError(err).Error("Failed to close database")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Log.WithError(err).Error("Failed to flush traces")
+	}
+	logger.Log.Info("Server stopped")
+	if failed {
+		cancel()
+		os.Exit(1)
+	}
+}
+
+// newNotifications создает очередь уведомлений с каналами, для которых
+// задана конфигурация
+func newNotifications(cfg config.NotificationsConfig) (*notify.Dispatcher, *notify.Templates, error) {
+	templates, err := notify.LoadTemplates(cfg.DefaultLocale)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := &http.Client{Timeout: cfg.Webhook.Timeout}
+	notifiers := []notify.Notifier{&notify.WebhookNotifier{Client: client}}
+	if cfg.SMTP.Host != "" {
+		notifiers = append(notifiers, &notify.SMTPNotifier{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		})
+	}
+	if cfg.Telegram.BotToken != "" {
+		notifiers = append(notifiers, &notify.TelegramNotifier{
+			Token:  cfg.Telegram.BotToken,
+			APIURL: cfg.Telegram.APIURL,
+			Client: client,
+		})
+	}
+
+	dispatcher := notify.NewDispatcher(db.DB, notify.Options{
+		PollInterval: cfg.PollInterval,
+		MaxAttempts:  cfg.MaxAttempts,
+		BackoffBase:  cfg.BackoffBase,
+		BackoffMax:   cfg.BackoffMax,
+	}, notifiers...)
+	return dispatcher, templates, nil
+}
+
+// newPublisher подключается к брокеру сообщений, выбранному в конфигурации
+func newPublisher(cfg config.OutboxConfig) (broker.Publisher, error) {
+	switch cfg.Broker {
+	case "kafka":
+		return broker.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Topic), nil
+	case "nats":
+		return broker.NewNATSPublisher(cfg.NATS.URL, cfg.Topic)
+	default:
+		return broker.NewMemoryBroker(), nil
+	}
 }
@@ -1,9 +1,12 @@
 package migrations
 
 import (
+	"fmt"
+
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
 
+	"github.com/nemopss/subscription-service/internal/catalog"
 	"github.com/nemopss/subscription-service/internal/models"
 )
 
@@ -18,3 +21,437 @@ func Migrate20250721(db *gorm.DB) *gormigrate.Migration {
 		},
 	}
 }
+
+// Migrate20261019 добавляет периодичность списаний подписок, настройки
+// напоминаний о продлении и журнал отправленных напоминаний
+func Migrate20261019(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019100000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&models.Subscription{},
+				&models.ReminderSettings{},
+				&models.Reminder{},
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable("reminders", "reminder_settings"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.Subscription{}, "billing_cycle")
+		},
+	}
+}
+
+// Migrate20261019Notifications добавляет очередь доставки уведомлений и
+// канал доставки в настройки напоминаний
+func Migrate20261019Notifications(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019120000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&models.ReminderSettings{},
+				&models.NotificationDelivery{},
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable("notification_deliveries"); err != nil {
+				return err
+			}
+			for _, column := range []string{"channel", "recipient", "locale"} {
+				if err := tx.Migrator().DropColumn(&models.ReminderSettings{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Migrate20261019Webhooks добавляет вебхуки, события и доставки, а также
+// отметку об истечении подписки
+func Migrate20261019Webhooks(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019140000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&models.Subscription{},
+				&models.WebhookEndpoint{},
+				&models.WebhookEvent{},
+				&models.WebhookDelivery{},
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			err := tx.Migrator().DropTable(
+				"webhook_deliveries",
+				"webhook_events",
+				"webhook_endpoints",
+			)
+			if err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.Subscription{}, "expired_at")
+		},
+	}
+}
+
+// Migrate20261019Outbox добавляет таблицу outbox для публикации событий
+// в брокер сообщений
+func Migrate20261019Outbox(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019160000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.OutboxEvent{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("outbox_events")
+		},
+	}
+}
+
+// Migrate20261019Status добавляет состояние подписки. Подписки, уже
+// отмеченные как истекшие, получают состояние expired.
+func Migrate20261019Status(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019180000",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.Subscription{}); err != nil {
+				return err
+			}
+			return tx.Model(&models.Subscription{}).
+				Where("expired_at IS NOT NULL").
+				Update("status", "expired").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&models.Subscription{}, "status")
+		},
+	}
+}
+
+// Migrate20261019Pauses добавляет приостановки подписок
+func Migrate20261019Pauses(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019200000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Subscription{}, &models.Pause{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("pauses")
+		},
+	}
+}
+
+// Migrate20261019Cancellation добавляет время, причину и способ отмены
+// подписки
+func Migrate20261019Cancellation(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019220000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Subscription{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, column := range []string{
+				"cancelled_at",
+				"cancel_reason",
+				"cancel_at_period_end",
+				"end_date_before_cancel",
+			} {
+				if err := tx.Migrator().DropColumn(&models.Subscription{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Migrate20261019Catalog добавляет каталог сервисов, заполняет его по
+// названиям существующих подписок и связывает подписки с сервисами
+func Migrate20261019Catalog(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019230000",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.Service{}, &models.Subscription{}); err != nil {
+				return err
+			}
+			return catalog.Seed(tx)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&models.Subscription{}, "service_id"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("services")
+		},
+	}
+}
+
+// Migrate20261019Taxonomy добавляет иерархию категорий, категорию и теги
+// подписок
+func Migrate20261019Taxonomy(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019233000",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.Category{}, &models.Subscription{}); err != nil {
+				return err
+			}
+			return tx.Exec(
+				"CREATE INDEX IF NOT EXISTS idx_subscriptions_tags ON subscriptions USING GIN (tags)",
+			).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, column := range []string{"category_id", "tags"} {
+				if err := tx.Migrator().DropColumn(&models.Subscription{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable("categories")
+		},
+	}
+}
+
+// Migrate20261019Budgets добавляет бюджеты пользователей и журнал
+// превышений
+func Migrate20261019Budgets(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019234000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Budget{}, &models.BudgetAlert{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("budget_alerts", "budgets")
+		},
+	}
+}
+
+// Migrate20261019PriceChanges добавляет запланированные изменения цены
+func Migrate20261019PriceChanges(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019235000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.PriceChange{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("price_changes")
+		},
+	}
+}
+
+// Migrate20261019Sharing добавляет совместные подписки
+func Migrate20261019Sharing(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019236000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Subscription{}, &models.Share{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable("shares"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&models.Subscription{}, "split_rule")
+		},
+	}
+}
+
+// Migrate20261019Organizations добавляет организации и домохозяйства
+func Migrate20261019Organizations(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019237000",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Organization{}, &models.Member{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("members", "organizations")
+		},
+	}
+}
+
+// tenantModels — модели с данными арендаторов
+var tenantModels = []any{
+	&models.Subscription{},
+	&models.Pause{},
+	&models.PriceChange{},
+	&models.Share{},
+	&models.Service{},
+	&models.Category{},
+	&models.Budget{},
+	&models.BudgetAlert{},
+	&models.Organization{},
+	&models.Member{},
+	&models.ReminderSettings{},
+	&models.Reminder{},
+	&models.NotificationDelivery{},
+	&models.WebhookEndpoint{},
+	&models.WebhookEvent{},
+	&models.WebhookDelivery{},
+	&models.OutboxEvent{},
+}
+
+// Migrate20261019Tenants добавляет арендатора во все таблицы. Существующие
+// данные переходят к арендатору по умолчанию; названия сервисов и
+// настройки напоминаний становятся уникальными в пределах арендатора.
+func Migrate20261019Tenants(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019238000",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(tenantModels...); err != nil {
+				return err
+			}
+			if tx.Migrator().HasIndex(&models.Service{}, "idx_services_name") {
+				if err := tx.Migrator().DropIndex(&models.Service{}, "idx_services_name"); err != nil {
+					return err
+				}
+			}
+			return tx.Exec(
+				"ALTER TABLE reminder_settings DROP CONSTRAINT reminder_settings_pkey, " +
+					"ADD PRIMARY KEY (tenant_id, user_id)",
+			).Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			err := tx.Exec(
+				"ALTER TABLE reminder_settings DROP CONSTRAINT reminder_settings_pkey, " +
+					"ADD PRIMARY KEY (user_id)",
+			).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex(&models.Service{}, "idx_services_tenant_name"); err != nil {
+				return err
+			}
+			err = tx.Exec("CREATE UNIQUE INDEX idx_services_name ON services (name)").Error
+			if err != nil {
+				return err
+			}
+			for _, model := range tenantModels {
+				if err := tx.Migrator().DropColumn(model, "tenant_id"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// RowLevelSecurity включает в таблицах арендаторов политики Postgres RLS:
+// строки видны и изменяемы, только если tenant_id совпадает с параметром
+// сеанса app.tenant_id. На владельца таблиц политики распространяет
+// ForceRowLevelSecurity. Миграция не входит в All и применяется, только
+// если RLS включен в конфигурации.
+func RowLevelSecurity(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019238500",
+		Migrate: func(tx *gorm.DB) error {
+			for _, model := range tenantModels {
+				table, err := tableName(tx, model)
+				if err != nil {
+					return err
+				}
+				for _, stmt := range []string{
+					"ALTER TABLE %[1]s ENABLE ROW LEVEL SECURITY",
+					"CREATE POLICY tenant_isolation ON %[1]s " +
+						"USING (tenant_id = current_setting('app.tenant_id', true)) " +
+						"WITH CHECK (tenant_id = current_setting('app.tenant_id', true))",
+				} {
+					if err := tx.Exec(fmt.Sprintf(stmt, table)).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, model := range tenantModels {
+				table, err := tableName(tx, model)
+				if err != nil {
+					return err
+				}
+				for _, stmt := range []string{
+					"DROP POLICY IF EXISTS tenant_isolation ON %[1]s",
+					"ALTER TABLE %[1]s DISABLE ROW LEVEL SECURITY",
+				} {
+					if err := tx.Exec(fmt.Sprintf(stmt, table)).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// ForceRowLevelSecurity распространяет политики RLS на владельца таблиц, от
+// имени которого работает сервис. Сервис задает app.tenant_id для каждого
+// запроса (см. tenancy.Plugin), а служебные запросы — миграции и фоновые
+// задачи — задают значение '*', при котором видны строки всех арендаторов.
+// Как и RowLevelSecurity, применяется, только если RLS включен.
+func ForceRowLevelSecurity(db *gorm.DB) *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20261019238600",
+		Migrate: func(tx *gorm.DB) error {
+			return alterPolicies(tx, "FORCE",
+				"tenant_id = current_setting('app.tenant_id', true) "+
+					"OR current_setting('app.tenant_id', true) = '*'",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return alterPolicies(tx, "NO FORCE",
+				"tenant_id = current_setting('app.tenant_id', true)",
+			)
+		},
+	}
+}
+
+// alterPolicies задает в таблицах арендаторов режим RLS для владельца и
+// условие политики tenant_isolation
+func alterPolicies(tx *gorm.DB, force, check string) error {
+	for _, model := range tenantModels {
+		table, err := tableName(tx, model)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range []string{
+			"ALTER TABLE %[1]s %[2]s ROW LEVEL SECURITY",
+			"ALTER POLICY tenant_isolation ON %[1]s USING (%[3]s) WITH CHECK (%[3]s)",
+		} {
+			if err := tx.Exec(fmt.Sprintf(stmt, table, force, check)).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tableName возвращает имя таблицы модели
+func tableName(tx *gorm.DB, model any) (string, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
+
+// All возвращает все миграции в порядке применения
+func All(db *gorm.DB) []*gormigrate.Migration {
+	return []*gormigrate.Migration{
+		Migrate20250721(db),
+		Migrate20261019(db),
+		Migrate20261019Notifications(db),
+		Migrate20261019Webhooks(db),
+		Migrate20261019Outbox(db),
+		Migrate20261019Status(db),
+		Migrate20261019Pauses(db),
+		Migrate20261019Cancellation(db),
+		Migrate20261019Catalog(db),
+		Migrate20261019Taxonomy(db),
+		Migrate20261019Budgets(db),
+		Migrate20261019PriceChanges(db),
+		Migrate20261019Sharing(db),
+		Migrate20261019Organizations(db),
+		Migrate20261019Tenants(db),
+	}
+}
+
+// LatestID возвращает идентификатор последней миграции
+func LatestID(db *gorm.DB) string {
+	all := All(db)
+	return all[len(all)-1].ID
+}
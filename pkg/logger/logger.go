@@ -1,10 +1,50 @@
 package logger
 
-import "github.com/sirupsen/logrus"
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
 
 var Log = logrus.New()
 
+type ctxKey struct{}
+
 func InitLogger() {
 	Log.SetFormatter(&logrus.JSONFormatter{})
 	Log.SetLevel(logrus.InfoLevel)
 }
+
+// Configure задает уровень и формат (json или text) логов
+func Configure(level, format string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		Log.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		Log.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	Log.SetLevel(lvl)
+	return nil
+}
+
+// WithContext сохраняет логгер запроса в контексте
+func WithContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, ctxKey{}, entry)
+}
+
+// FromContext возвращает логгер запроса из контекста или глобальный
+// логгер, если контекст его не содержит
+func FromContext(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(ctxKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(Log)
+}